@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ut-code/Raxcel/server/types"
+)
+
+type ChangePasswordResult struct {
+	Error string `json:"error"`
+}
+
+// ChangePassword keeps this device signed in and signs out the others
+func (a *App) ChangePassword(currentPassword, newPassword string) ChangePasswordResult {
+	jsonData, err := json.Marshal(types.ChangePasswordRequest{
+		CurrentPassword: currentPassword,
+		NewPassword:     newPassword,
+	})
+	if err != nil {
+		return ChangePasswordResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("POST", fmt.Sprintf("%s/users/me/password", apiUrl), jsonData)
+	if err != nil {
+		return ChangePasswordResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ChangePasswordResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.ChangePasswordResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ChangePasswordResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return ChangePasswordResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return ChangePasswordResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return ChangePasswordResult{
+		Error: "",
+	}
+}
+
+type ChangeEmailResult struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+// ChangeEmail sends a confirmation link to the new address, which takes effect once opened.
+// Accounts without a password give their two-factor code instead, when 2FA is enabled.
+func (a *App) ChangeEmail(newEmail, password, code string) ChangeEmailResult {
+	jsonData, err := json.Marshal(types.ChangeEmailRequest{
+		NewEmail: newEmail,
+		Password: password,
+		Code:     code,
+	})
+	if err != nil {
+		return ChangeEmailResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("POST", fmt.Sprintf("%s/users/me/email", apiUrl), jsonData)
+	if err != nil {
+		return ChangeEmailResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ChangeEmailResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.ChangeEmailResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ChangeEmailResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return ChangeEmailResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return ChangeEmailResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return ChangeEmailResult{
+		Message: serverResponse.Message,
+		Error:   "",
+	}
+}
+
+type DeleteAccountResult struct {
+	Error string `json:"error"`
+}
+
+// DeleteAccount deletes the account and everything in it, then forgets the stored tokens.
+// code is only needed when two-factor authentication is enabled.
+func (a *App) DeleteAccount(password, code string) DeleteAccountResult {
+	jsonData, err := json.Marshal(types.DeleteAccountRequest{
+		Password: password,
+		Code:     code,
+	})
+	if err != nil {
+		return DeleteAccountResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("DELETE", fmt.Sprintf("%s/users/me", apiUrl), jsonData)
+	if err != nil {
+		return DeleteAccountResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return DeleteAccountResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.DeleteAccountResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return DeleteAccountResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return DeleteAccountResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return DeleteAccountResult{
+			Error: serverResponse.Error,
+		}
+	}
+	if err := clearTokens(); err != nil {
+		return DeleteAccountResult{
+			Error: fmt.Sprintf("Failed to sign out: %v", err),
+		}
+	}
+	return DeleteAccountResult{
+		Error: "",
+	}
+}
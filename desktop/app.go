@@ -4,24 +4,31 @@ import (
 	"context"
 	"fmt"
 	"os"
-
-	"github.com/joho/godotenv"
+	"sync"
 )
 
-var apiURL string
-
+// getAPIURL is the server of the active profile
 func getAPIURL() string {
-	if apiURL == "" {
-		godotenv.Load()
-		apiUrl := os.Getenv("PUBLIC_API_URL")
+	if apiUrl := activeProfile().ApiUrl; apiUrl != "" {
 		return apiUrl
 	}
-	return apiURL
+	return defaultAPIURL()
 }
 
 // App struct
 type App struct {
-	ctx context.Context
+	ctx       context.Context
+	refreshMu sync.Mutex
+
+	deepLinkMu      sync.Mutex
+	pendingDeepLink *DeepLink
+
+	magicLinkMu sync.Mutex
+	magicLink   *magicLinkWait
+
+	// chatCancel stops the answer being streamed
+	chatMu     sync.Mutex
+	chatCancel context.CancelFunc
 }
 
 // NewApp creates a new App application struct
@@ -33,6 +40,7 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.handleArgs(os.Args[1:])
 }
 
 // Greet returns a greeting for the given name
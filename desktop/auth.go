@@ -6,14 +6,17 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/ut-code/Raxcel/server/types"
-	"github.com/zalando/go-keyring"
 )
 
 type SignupResult struct {
 	UserId string `json:"userId"`
 	Error  string `json:"error"`
+	// Code tells why the server rejected the signup, such as "invite_required"
+	Code string `json:"code"`
 }
 
 func (a *App) Signup(email, password string) SignupResult {
@@ -53,10 +56,16 @@ func (a *App) Signup(email, password string) SignupResult {
 			Error:  fmt.Sprintf("Failed to parse response: %v", err),
 		}
 	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return SignupResult{
+			Error: rateLimitError(resp, serverResponse.Error),
+		}
+	}
 	if resp.StatusCode != http.StatusCreated {
 		return SignupResult{
 			UserId: "",
 			Error:  serverResponse.Error,
+			Code:   serverResponse.Code,
 		}
 	}
 	return SignupResult{
@@ -68,6 +77,9 @@ func (a *App) Signup(email, password string) SignupResult {
 type SigninResult struct {
 	Token string `json:"token"`
 	Error string `json:"error"`
+	// When set, finish with CompleteTwoFactorSignin using the challenge token
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
 }
 
 func (a *App) Signin(email, password string) SigninResult {
@@ -84,7 +96,7 @@ func (a *App) Signin(email, password string) SigninResult {
 	}
 	apiUrl := getAPIURL()
 
-	resp, err := http.Post(fmt.Sprintf("%s/auth/signin", apiUrl), "application/json", bytes.NewReader(jsonData))
+	resp, err := postSignin(fmt.Sprintf("%s/auth/signin", apiUrl), jsonData)
 	if err != nil {
 		return SigninResult{
 			Token: "",
@@ -92,7 +104,36 @@ func (a *App) Signin(email, password string) SigninResult {
 		}
 	}
 	defer resp.Body.Close()
+	return finishSignin(resp)
+}
+
+func (a *App) CompleteTwoFactorSignin(challengeToken, code string) SigninResult {
+	postData := types.VerifyTwoFactorRequest{
+		ChallengeToken: challengeToken,
+		Code:           code,
+	}
+	jsonData, err := json.Marshal(postData)
+	if err != nil {
+		return SigninResult{
+			Token: "",
+			Error: fmt.Sprintf("Failed to marshal request: %v", err),
+		}
+	}
+	apiUrl := getAPIURL()
 
+	resp, err := postSignin(fmt.Sprintf("%s/auth/2fa/verify", apiUrl), jsonData)
+	if err != nil {
+		return SigninResult{
+			Token: "",
+			Error: fmt.Sprintf("Failed to send request: %v", err),
+		}
+	}
+	defer resp.Body.Close()
+	return finishSignin(resp)
+}
+
+// finishSignin stores the tokens from a signin response in the credential store
+func finishSignin(resp *http.Response) SigninResult {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return SigninResult{
@@ -109,14 +150,25 @@ func (a *App) Signin(email, password string) SigninResult {
 			Error: fmt.Sprintf("Failed to parse response %v", err),
 		}
 	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return SigninResult{
+			Error: rateLimitError(resp, serverResponse.Error),
+		}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return SigninResult{
 			Token: "",
 			Error: serverResponse.Error,
 		}
 	}
+	if serverResponse.TwoFactorRequired {
+		return SigninResult{
+			TwoFactorRequired: true,
+			ChallengeToken:    serverResponse.ChallengeToken,
+		}
+	}
 	token := serverResponse.Token
-	err = keyring.Set("Raxcel", "raxcel-user", token)
+	err = storeTokens(token, serverResponse.RefreshToken)
 	if err != nil {
 		return SigninResult{
 			Token: "",
@@ -129,18 +181,71 @@ func (a *App) Signin(email, password string) SigninResult {
 	}
 }
 
+type ResendVerificationEmailResult struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+func (a *App) ResendVerificationEmail(email string) ResendVerificationEmailResult {
+	postData := types.ResendVerificationRequest{
+		Email: email,
+	}
+	jsonData, err := json.Marshal(postData)
+	if err != nil {
+		return ResendVerificationEmailResult{
+			Error: fmt.Sprintf("Failed to marshal request: %v", err),
+		}
+	}
+	apiUrl := getAPIURL()
+
+	resp, err := http.Post(fmt.Sprintf("%s/auth/resend-verification", apiUrl), "application/json", bytes.NewReader(jsonData))
+	if err != nil {
+		return ResendVerificationEmailResult{
+			Error: fmt.Sprintf("Failed to send request: %v", err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ResendVerificationEmailResult{
+			Error: fmt.Sprintf("Failed to read response: %v", err),
+		}
+	}
+	var serverResponse types.ResendVerificationResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ResendVerificationEmailResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return ResendVerificationEmailResult{
+			Error: rateLimitError(resp, serverResponse.Error),
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ResendVerificationEmailResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return ResendVerificationEmailResult{
+		Message: serverResponse.Message,
+		Error:   "",
+	}
+}
+
 type GetCurrentUserResult struct {
-	UserId string `json:"userId"`
-	Error  string `json:"error"`
+	UserId      string    `json:"userId"`
+	Email       string    `json:"email"`
+	IsVerified  bool      `json:"isVerified"`
+	TotpEnabled bool      `json:"totpEnabled"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Error       string    `json:"error"`
 }
 
 func (a *App) GetCurrentUser() GetCurrentUserResult {
 	apiUrl := getAPIURL()
-	token, _ := keyring.Get("Raxcel", "raxcel-user")
-	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/users/me", apiUrl), nil)
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := a.sendAuthorized("GET", fmt.Sprintf("%s/users/me", apiUrl), nil)
 	if err != nil {
 		return GetCurrentUserResult{
 			UserId: "",
@@ -175,9 +280,15 @@ func (a *App) GetCurrentUser() GetCurrentUserResult {
 			Error:  serverResponse.Error,
 		}
 	}
+	rememberProfileEmail(serverResponse.Email)
 	return GetCurrentUserResult{
-		UserId: serverResponse.UserId,
-		Error:  "",
+		UserId:      serverResponse.UserId,
+		Email:       serverResponse.Email,
+		IsVerified:  serverResponse.IsVerified,
+		TotpEnabled: serverResponse.TotpEnabled,
+		Role:        serverResponse.Role,
+		CreatedAt:   serverResponse.CreatedAt,
+		Error:       "",
 	}
 }
 
@@ -186,7 +297,12 @@ type SignOutResult struct {
 }
 
 func (a *App) SignOut() SignOutResult {
-	err := keyring.Delete("Raxcel", "raxcel-user")
+	// Revoke the session on the server so a copied token stops working.
+	// Signing out locally still succeeds when the server cannot be reached.
+	if resp, err := a.sendAuthorized("DELETE", fmt.Sprintf("%s/users/me/sessions/current", getAPIURL()), nil); err == nil {
+		resp.Body.Close()
+	}
+	err := clearTokens()
 	if err != nil {
 		return SignOutResult{
 			Error: fmt.Sprintf("Failed to sign out: %v", err),
@@ -196,3 +312,122 @@ func (a *App) SignOut() SignOutResult {
 		Error: "",
 	}
 }
+
+type RequestPasswordResetResult struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+func (a *App) RequestPasswordReset(email string) RequestPasswordResetResult {
+	postData := types.ForgotPasswordRequest{
+		Email: email,
+	}
+	jsonData, err := json.Marshal(postData)
+	if err != nil {
+		return RequestPasswordResetResult{
+			Error: fmt.Sprintf("Failed to marshal request: %v", err),
+		}
+	}
+	apiUrl := getAPIURL()
+
+	resp, err := http.Post(fmt.Sprintf("%s/auth/forgot-password", apiUrl), "application/json", bytes.NewReader(jsonData))
+	if err != nil {
+		return RequestPasswordResetResult{
+			Error: fmt.Sprintf("Failed to send request: %v", err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return RequestPasswordResetResult{
+			Error: fmt.Sprintf("Failed to read response: %v", err),
+		}
+	}
+	var serverResponse types.ForgotPasswordResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return RequestPasswordResetResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return RequestPasswordResetResult{
+			Error: rateLimitError(resp, serverResponse.Error),
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return RequestPasswordResetResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return RequestPasswordResetResult{
+		Message: serverResponse.Message,
+		Error:   "",
+	}
+}
+
+type ResetPasswordResult struct {
+	Error string `json:"error"`
+}
+
+// ResetPassword sets a new password using the code from the reset email.
+// Every existing session is signed out, including this one.
+func (a *App) ResetPassword(token, password string) ResetPasswordResult {
+	postData := types.ResetPasswordRequest{
+		Token:    token,
+		Password: password,
+	}
+	jsonData, err := json.Marshal(postData)
+	if err != nil {
+		return ResetPasswordResult{
+			Error: fmt.Sprintf("Failed to marshal request: %v", err),
+		}
+	}
+	apiUrl := getAPIURL()
+
+	resp, err := http.Post(fmt.Sprintf("%s/auth/reset-password", apiUrl), "application/json", bytes.NewReader(jsonData))
+	if err != nil {
+		return ResetPasswordResult{
+			Error: fmt.Sprintf("Failed to send request: %v", err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ResetPasswordResult{
+			Error: fmt.Sprintf("Failed to read response: %v", err),
+		}
+	}
+	var serverResponse types.ResetPasswordResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ResetPasswordResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return ResetPasswordResult{
+			Error: rateLimitError(resp, serverResponse.Error),
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ResetPasswordResult{
+			Error: serverResponse.Error,
+		}
+	}
+	// The stored tokens were revoked by the reset
+	clearTokens()
+	return ResetPasswordResult{
+		Error: "",
+	}
+}
+
+// rateLimitError turns a 429 response into a message that tells the user how long to wait
+func rateLimitError(resp *http.Response, serverError string) string {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil {
+		return serverError
+	}
+	if seconds < 60 {
+		return fmt.Sprintf("Too many attempts. Please try again in %d seconds.", seconds)
+	}
+	return fmt.Sprintf("Too many attempts. Please try again in %d minutes.", (seconds+59)/60)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ut-code/Raxcel/server/types"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// chatChunkEvent carries each piece of a streamed answer to the frontend as it arrives
+const chatChunkEvent = "chat:chunk"
+
+type StreamChatWithAIResult struct {
+	// Message is the whole answer, or what had arrived when the chat was cancelled
+	Message        string `json:"message"`
+	ConversationId string `json:"conversationId"`
+	Title          string `json:"title"`
+	// Edits are spreadsheet edits the model proposes, for the user to apply or reject
+	Edits     []types.SpreadsheetEdit `json:"edits"`
+	Cancelled bool                    `json:"cancelled"`
+	Error     string                  `json:"error"`
+}
+
+// StreamChatWithAI sends a message like ChatWithAI, but emits the answer in
+// "chat:chunk" events while it is generated. It returns once the answer is complete
+// or CancelChat is called.
+func (a *App) StreamChatWithAI(message string, spreadsheet types.SpreadsheetContext, organizationId string, conversationId string, workbook string) StreamChatWithAIResult {
+	jsonData, err := json.Marshal(types.ChatWithAIRequest{
+		Message:        message,
+		Spreadsheet:    includedSpreadsheet(spreadsheet),
+		OrganizationId: organizationId,
+		ConversationId: conversationId,
+		Workbook:       workbook,
+		ProposeEdits:   true,
+	})
+	if err != nil {
+		return StreamChatWithAIResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.chatMu.Lock()
+	if a.chatCancel != nil {
+		a.chatCancel()
+	}
+	a.chatCancel = cancel
+	a.chatMu.Unlock()
+
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorizedContext(ctx, "POST", fmt.Sprintf("%s/messages/stream", apiUrl), jsonData)
+	if err != nil {
+		if ctx.Err() != nil {
+			return StreamChatWithAIResult{
+				Cancelled: true,
+			}
+		}
+		return StreamChatWithAIResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Failures before the answer starts are JSON responses like ChatWithAI
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return StreamChatWithAIResult{
+				Error: fmt.Sprint(err),
+			}
+		}
+		var serverResponse types.ChatWithAIResponse
+		if err := json.Unmarshal(body, &serverResponse); err != nil {
+			return StreamChatWithAIResult{
+				Error: fmt.Sprintf("Failed to parse response: %v", err),
+			}
+		}
+		if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+			return StreamChatWithAIResult{
+				Error: serverResponse.MiddlewareError,
+			}
+		}
+		return StreamChatWithAIResult{
+			Error: serverResponse.Error,
+		}
+	}
+
+	var answer strings.Builder
+	done := types.ChatDoneEvent{
+		ConversationId: resp.Header.Get(types.ConversationIdHeader),
+	}
+	err = readServerSentEvents(resp.Body, func(event string, data []byte) (bool, error) {
+		switch event {
+		case types.ChatEventChunk:
+			var chunk types.ChatChunkEvent
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return false, err
+			}
+			answer.WriteString(chunk.Text)
+			runtime.EventsEmit(a.ctx, chatChunkEvent, chunk.Text)
+		case types.ChatEventDone:
+			if err := json.Unmarshal(data, &done); err != nil {
+				return false, err
+			}
+			answer.Reset()
+			answer.WriteString(done.AiMessage)
+			return true, nil
+		case types.ChatEventError:
+			var failed types.ChatErrorEvent
+			if err := json.Unmarshal(data, &failed); err != nil {
+				return false, err
+			}
+			return false, errors.New(failed.Error)
+		}
+		return false, nil
+	})
+	if ctx.Err() != nil {
+		return StreamChatWithAIResult{
+			Message:        answer.String(),
+			ConversationId: done.ConversationId,
+			Cancelled:      true,
+		}
+	}
+	if err != nil {
+		return StreamChatWithAIResult{
+			Message:        answer.String(),
+			ConversationId: done.ConversationId,
+			Error:          fmt.Sprint(err),
+		}
+	}
+	return StreamChatWithAIResult{
+		Message:        answer.String(),
+		ConversationId: done.ConversationId,
+		Title:          done.Title,
+		Edits:          done.Edits,
+		Error:          "",
+	}
+}
+
+// CancelChat stops the answer StreamChatWithAI is waiting for. The server keeps
+// what was generated so far in the history.
+func (a *App) CancelChat() {
+	a.chatMu.Lock()
+	defer a.chatMu.Unlock()
+	if a.chatCancel != nil {
+		a.chatCancel()
+		a.chatCancel = nil
+	}
+}
+
+// readServerSentEvents calls handle for each event of a text/event-stream body
+// until handle reports that it is done or the body ends
+func readServerSentEvents(body io.Reader, handle func(event string, data []byte) (bool, error)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	event := "message"
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				done, err := handle(event, []byte(strings.Join(data, "\n")))
+				if done || err != nil {
+					return err
+				}
+			}
+			event, data = "message", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment, used as a keep-alive
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("the answer ended unexpectedly")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/types"
+)
+
+type Conversation struct {
+	Id        string    `json:"id"`
+	Title     string    `json:"title"`
+	Workbook  string    `json:"workbook"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type ListConversationsResult struct {
+	Conversations []Conversation `json:"conversations"`
+	Error         string         `json:"error"`
+}
+
+// ListConversations returns the chat threads, most recently active first
+func (a *App) ListConversations() ListConversationsResult {
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("GET", fmt.Sprintf("%s/conversations", apiUrl), nil)
+	if err != nil {
+		return ListConversationsResult{
+			Conversations: []Conversation{},
+			Error:         fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ListConversationsResult{
+			Conversations: []Conversation{},
+			Error:         fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.ListConversationsResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ListConversationsResult{
+			Conversations: []Conversation{},
+			Error:         fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return ListConversationsResult{
+			Conversations: []Conversation{},
+			Error:         serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return ListConversationsResult{
+			Conversations: []Conversation{},
+			Error:         serverResponse.Error,
+		}
+	}
+	conversations := make([]Conversation, len(serverResponse.Conversations))
+	for i, conversation := range serverResponse.Conversations {
+		conversations[i] = Conversation{
+			Id:        conversation.Id,
+			Title:     conversation.Title,
+			Workbook:  conversation.Workbook,
+			UpdatedAt: conversation.UpdatedAt,
+		}
+	}
+	return ListConversationsResult{
+		Conversations: conversations,
+		Error:         "",
+	}
+}
+
+type ConversationResult struct {
+	Conversation Conversation `json:"conversation"`
+	Messages     []Mesaage    `json:"messages"`
+	// PrevCursor loads earlier messages with LoadChatHistory
+	PrevCursor string `json:"prevCursor"`
+	Error      string `json:"error"`
+}
+
+// OpenConversation returns the thread with its latest messages
+func (a *App) OpenConversation(conversationId string) ConversationResult {
+	apiUrl := getAPIURL()
+	return a.sendConversationRequest("GET", fmt.Sprintf("%s/conversations/%s", apiUrl, url.PathEscape(conversationId)), nil)
+}
+
+func (a *App) RenameConversation(conversationId, title string) ConversationResult {
+	jsonData, err := json.Marshal(types.UpdateConversationRequest{
+		Title: &title,
+	})
+	if err != nil {
+		return ConversationResult{
+			Messages: []Mesaage{},
+			Error:    fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	return a.sendConversationRequest("PATCH", fmt.Sprintf("%s/conversations/%s", apiUrl, url.PathEscape(conversationId)), jsonData)
+}
+
+func (a *App) sendConversationRequest(method, endpoint string, jsonData []byte) ConversationResult {
+	resp, err := a.sendAuthorized(method, endpoint, jsonData)
+	if err != nil {
+		return ConversationResult{
+			Messages: []Mesaage{},
+			Error:    fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ConversationResult{
+			Messages: []Mesaage{},
+			Error:    fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.ConversationResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ConversationResult{
+			Messages: []Mesaage{},
+			Error:    fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return ConversationResult{
+			Messages: []Mesaage{},
+			Error:    serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return ConversationResult{
+			Messages: []Mesaage{},
+			Error:    serverResponse.Error,
+		}
+	}
+	if serverResponse.Conversation == nil {
+		return ConversationResult{
+			Messages: []Mesaage{},
+			Error:    "Failed to parse response: missing conversation",
+		}
+	}
+	messages := make([]Mesaage, len(serverResponse.Messages))
+	for i, msg := range serverResponse.Messages {
+		messages[i] = Mesaage{
+			Id:        msg.Id,
+			UserId:    msg.UserId,
+			Content:   msg.Content,
+			Role:      msg.Role,
+			CreatedAt: msg.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+	}
+	return ConversationResult{
+		Conversation: Conversation{
+			Id:        serverResponse.Conversation.Id,
+			Title:     serverResponse.Conversation.Title,
+			Workbook:  serverResponse.Conversation.Workbook,
+			UpdatedAt: serverResponse.Conversation.UpdatedAt,
+		},
+		Messages:   messages,
+		PrevCursor: serverResponse.PrevCursor,
+		Error:      "",
+	}
+}
+
+type DeleteConversationResult struct {
+	Error string `json:"error"`
+}
+
+// DeleteConversation removes the thread with all of its messages
+func (a *App) DeleteConversation(conversationId string) DeleteConversationResult {
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("DELETE", fmt.Sprintf("%s/conversations/%s", apiUrl, url.PathEscape(conversationId)), nil)
+	if err != nil {
+		return DeleteConversationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return DeleteConversationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.DeleteConversationResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return DeleteConversationResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return DeleteConversationResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	return DeleteConversationResult{
+		Error: serverResponse.Error,
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Values of the credentialStore setting. When it is not set, the OS keyring is used
+// if it works and the encrypted file otherwise.
+const (
+	CredentialStoreKeyring = "keyring"
+	CredentialStoreFile    = "file"
+)
+
+var errCredentialNotFound = errors.New("credential not found")
+
+// CredentialStore keeps the tokens of the signed-in accounts
+type CredentialStore interface {
+	// Get returns errCredentialNotFound when nothing is stored under key
+	Get(key string) (string, error)
+	Set(key, value string) error
+	// Delete does nothing when nothing is stored under key
+	Delete(key string) error
+}
+
+// keyringStore uses the Keychain on macOS, the Credential Manager on Windows
+// and the Secret Service on Linux
+type keyringStore struct{}
+
+func (keyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(keyringService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", errCredentialNotFound
+	}
+	return value, err
+}
+
+func (keyringStore) Set(key, value string) error {
+	return keyring.Set(keyringService, key, value)
+}
+
+func (keyringStore) Delete(key string) error {
+	if err := keyring.Delete(keyringService, key); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// keyringAvailable reports whether the OS keyring can be reached, which it cannot
+// on systems without a Secret Service daemon
+func keyringAvailable() bool {
+	_, err := keyring.Get(keyringService, "raxcel-probe")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+// encryptedCredentials is the content of credentials.json
+type encryptedCredentials struct {
+	// Passphrase tells whether the key comes from RAXCEL_CREDENTIAL_PASSPHRASE or from the machine
+	Passphrase bool   `json:"passphrase"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// fileStore keeps the credentials in a file encrypted with AES-256-GCM. The key is
+// derived with scrypt from RAXCEL_CREDENTIAL_PASSPHRASE, or else from the machine id,
+// which only stops the file from being read on another machine.
+type fileStore struct {
+	path       string
+	secret     []byte
+	passphrase bool
+
+	mu sync.Mutex
+	// key is derived once for salt, since scrypt is slow on purpose
+	salt []byte
+	key  []byte
+}
+
+func newFileStore() (*fileStore, error) {
+	path, err := settingsPath()
+	if err != nil {
+		return nil, err
+	}
+	store := &fileStore{path: filepath.Join(filepath.Dir(path), "credentials.json")}
+	if passphrase := os.Getenv("RAXCEL_CREDENTIAL_PASSPHRASE"); passphrase != "" {
+		store.secret = []byte(passphrase)
+		store.passphrase = true
+	} else {
+		store.secret = machineSecret()
+	}
+	return store, nil
+}
+
+// machineSecret identifies this machine, falling back to the host name where there is no machine id
+func machineSecret() []byte {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if id, err := os.ReadFile(path); err == nil && len(strings.TrimSpace(string(id))) > 0 {
+			return []byte(strings.TrimSpace(string(id)))
+		}
+	}
+	hostname, _ := os.Hostname()
+	home, _ := os.UserHomeDir()
+	return []byte(hostname + "\x00" + home)
+}
+
+func (s *fileStore) deriveKey(salt []byte) ([]byte, error) {
+	if s.key != nil && string(s.salt) == string(salt) {
+		return s.key, nil
+	}
+	key, err := scrypt.Key(s.secret, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	s.salt, s.key = salt, key
+	return key, nil
+}
+
+// load decrypts the file; a missing file is an empty store. The caller holds s.mu.
+func (s *fileStore) load() (map[string]string, []byte, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt := make([]byte, 16)
+		rand.Read(salt)
+		return map[string]string{}, salt, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var file encryptedCredentials
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %v", s.path, err)
+	}
+	if file.Passphrase != s.passphrase {
+		if file.Passphrase {
+			return nil, nil, fmt.Errorf("the credentials are encrypted with a passphrase, set RAXCEL_CREDENTIAL_PASSPHRASE")
+		}
+		return nil, nil, fmt.Errorf("the credentials are not encrypted with a passphrase, unset RAXCEL_CREDENTIAL_PASSPHRASE")
+	}
+	key, err := s.deriveKey(file.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt the credentials, the passphrase may be wrong")
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, nil, err
+	}
+	return values, file.Salt, nil
+}
+
+// save encrypts values with a new nonce. The caller holds s.mu.
+func (s *fileStore) save(values map[string]string, salt []byte) error {
+	key, err := s.deriveKey(salt)
+	if err != nil {
+		return err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	data, err := json.Marshal(encryptedCredentials{
+		Passphrase: s.passphrase,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, nil),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	// Written next to the file and renamed, so a crash cannot leave it half written
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *fileStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, _, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", errCredentialNotFound
+	}
+	return value, nil
+}
+
+func (s *fileStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, salt, err := s.load()
+	if err != nil {
+		return err
+	}
+	values[key] = value
+	return s.save(values, salt)
+}
+
+func (s *fileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, salt, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+	delete(values, key)
+	return s.save(values, salt)
+}
+
+var (
+	credentialsMu    sync.Mutex
+	credentialsStore CredentialStore
+	credentialsKind  string
+)
+
+// newCredentialStore opens the store of a credentialStore setting, and returns which one
+// it is. GetCredentialStore tells when "" fell back to the file for want of a keyring.
+func newCredentialStore(kind string) (CredentialStore, string, error) {
+	if kind == "" {
+		kind = CredentialStoreKeyring
+		if !keyringAvailable() {
+			kind = CredentialStoreFile
+		}
+	}
+	switch kind {
+	case CredentialStoreKeyring:
+		return keyringStore{}, kind, nil
+	case CredentialStoreFile:
+		store, err := newFileStore()
+		return store, kind, err
+	}
+	return nil, "", fmt.Errorf("unknown credential store %q", kind)
+}
+
+// credentials returns the store chosen in the settings. It must not be called with settingsMu held.
+func credentials() (CredentialStore, error) {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	if credentialsStore != nil {
+		return credentialsStore, nil
+	}
+	settingsMu.Lock()
+	kind := loadSettings().CredentialStore
+	settingsMu.Unlock()
+	store, kind, err := newCredentialStore(kind)
+	if err != nil {
+		return nil, err
+	}
+	credentialsStore, credentialsKind = store, kind
+	return store, nil
+}
+
+type GetCredentialStoreResult struct {
+	// Store is where credentials are kept now, "keyring" or "file"
+	Store string `json:"store"`
+	// Setting is the store chosen in the settings, or "" to pick one automatically
+	Setting string `json:"setting"`
+	// KeyringUnavailable is set when the automatic choice is the file because no OS keyring could be reached
+	KeyringUnavailable bool   `json:"keyringUnavailable"`
+	Error              string `json:"error"`
+}
+
+func (a *App) GetCredentialStore() GetCredentialStoreResult {
+	if _, err := credentials(); err != nil {
+		return GetCredentialStoreResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	credentialsMu.Lock()
+	kind := credentialsKind
+	credentialsMu.Unlock()
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	setting := loadSettings().CredentialStore
+	return GetCredentialStoreResult{
+		Store:              kind,
+		Setting:            setting,
+		KeyringUnavailable: setting == "" && kind == CredentialStoreFile,
+		Error:              "",
+	}
+}
+
+type SetCredentialStoreResult struct {
+	Error string `json:"error"`
+}
+
+// SetCredentialStore chooses where credentials are kept: "keyring", "file",
+// or "" to pick automatically. The tokens of every profile move to the new store.
+func (a *App) SetCredentialStore(kind string) SetCredentialStoreResult {
+	// No token is read or written while they move
+	a.refreshMu.Lock()
+	defer a.refreshMu.Unlock()
+	from, err := credentials()
+	if err != nil {
+		return SetCredentialStoreResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	to, resolved, err := newCredentialStore(kind)
+	if err != nil {
+		return SetCredentialStoreResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+
+	settingsMu.Lock()
+	s := loadSettings()
+	var keys []string
+	for _, profile := range s.Profiles {
+		keys = append(keys, profileKey(profile.Name, keyringAccessToken), profileKey(profile.Name, keyringRefreshToken))
+	}
+	settingsMu.Unlock()
+
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	if resolved != credentialsKind {
+		for _, key := range keys {
+			// Tokens that cannot be read, as when the keyring is gone, are left behind
+			value, err := from.Get(key)
+			if err != nil {
+				continue
+			}
+			if err := to.Set(key, value); err != nil {
+				return SetCredentialStoreResult{
+					Error: fmt.Sprintf("Failed to move credentials: %v", err),
+				}
+			}
+		}
+		for _, key := range keys {
+			from.Delete(key)
+		}
+	}
+	credentialsStore, credentialsKind = to, resolved
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	s.CredentialStore = kind
+	if err := s.save(); err != nil {
+		return SetCredentialStoreResult{
+			Error: fmt.Sprintf("Failed to save settings: %v", err),
+		}
+	}
+	return SetCredentialStoreResult{
+		Error: "",
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestFileStore(path, passphrase string) *fileStore {
+	if passphrase == "" {
+		return &fileStore{path: path, secret: []byte("machine-id")}
+	}
+	return &fileStore{path: path, secret: []byte(passphrase), passphrase: true}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	store := newTestFileStore(path, "correct horse")
+
+	if _, err := store.Get("access"); !errors.Is(err, errCredentialNotFound) {
+		t.Fatalf("Get on a missing file: got %v, want errCredentialNotFound", err)
+	}
+	if err := store.Set("access", "token-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("refresh", "token-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("access"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("missing"); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+
+	// A new store reads what the first one wrote
+	reopened := newTestFileStore(path, "correct horse")
+	if _, err := reopened.Get("access"); !errors.Is(err, errCredentialNotFound) {
+		t.Errorf("Get after Delete: got %v, want errCredentialNotFound", err)
+	}
+	value, err := reopened.Get("refresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "token-2" {
+		t.Errorf("Get = %q, want %q", value, "token-2")
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := newTestFileStore(path, "correct horse").Set("access", "token"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := newTestFileStore(path, "wrong horse").Get("access")
+	if err == nil || !strings.Contains(err.Error(), "passphrase may be wrong") {
+		t.Errorf("Get with a wrong passphrase: got %v", err)
+	}
+}
+
+func TestFileStoreKeyMismatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		written     string
+		read        string
+		wantMessage string
+	}{
+		{"passphrase file read with the machine key", "correct horse", "", "set RAXCEL_CREDENTIAL_PASSPHRASE"},
+		{"machine key file read with a passphrase", "", "correct horse", "unset RAXCEL_CREDENTIAL_PASSPHRASE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "credentials.json")
+			if err := newTestFileStore(path, tt.written).Set("access", "token"); err != nil {
+				t.Fatal(err)
+			}
+			_, err := newTestFileStore(path, tt.read).Get("access")
+			if err == nil || !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("Get: got %v, want an error saying %q", err, tt.wantMessage)
+			}
+			// Nothing is written over credentials that cannot be read
+			if err := newTestFileStore(path, tt.read).Set("access", "other"); err == nil {
+				t.Error("Set over credentials of the other key succeeded")
+			}
+		})
+	}
+}
+
+// memoryStore is a CredentialStore for tests
+type memoryStore map[string]string
+
+func (m memoryStore) Get(key string) (string, error) {
+	value, ok := m[key]
+	if !ok {
+		return "", errCredentialNotFound
+	}
+	return value, nil
+}
+
+func (m memoryStore) Set(key, value string) error {
+	m[key] = value
+	return nil
+}
+
+func (m memoryStore) Delete(key string) error {
+	delete(m, key)
+	return nil
+}
+
+func TestSetCredentialStoreMovesTokens(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("RAXCEL_CREDENTIAL_PASSPHRASE", "correct horse")
+	t.Cleanup(func() {
+		credentialsStore, credentialsKind, loadedSettings = nil, "", nil
+	})
+	loadedSettings = &settings{
+		ActiveProfile: defaultProfileName,
+		Profiles:      []Profile{{Name: defaultProfileName}, {Name: "work", ApiUrl: "https://raxcel.example.com"}},
+	}
+	from := memoryStore{
+		keyringAccessToken:                      "default-access",
+		keyringRefreshToken:                     "default-refresh",
+		profileKey("work", keyringRefreshToken): "work-refresh",
+	}
+	credentialsStore, credentialsKind = from, CredentialStoreKeyring
+
+	if result := (&App{}).SetCredentialStore(CredentialStoreFile); result.Error != "" {
+		t.Fatalf("SetCredentialStore: %s", result.Error)
+	}
+
+	if len(from) != 0 {
+		t.Errorf("tokens left in the old store: %v", from)
+	}
+	if credentialsKind != CredentialStoreFile {
+		t.Errorf("credentialsKind = %q, want %q", credentialsKind, CredentialStoreFile)
+	}
+	to, err := newFileStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		keyringAccessToken:                      "default-access",
+		keyringRefreshToken:                     "default-refresh",
+		profileKey("work", keyringRefreshToken): "work-refresh",
+	} {
+		if got, err := to.Get(key); err != nil || got != want {
+			t.Errorf("Get(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+	if _, err := to.Get(profileKey("work", keyringAccessToken)); !errors.Is(err, errCredentialNotFound) {
+		t.Errorf("a token that was not stored was moved: %v", err)
+	}
+
+	// The choice is saved with the settings
+	loadedSettings = nil
+	settingsMu.Lock()
+	setting := loadSettings().CredentialStore
+	settingsMu.Unlock()
+	if setting != CredentialStoreFile {
+		t.Errorf("saved credentialStore = %q, want %q", setting, CredentialStoreFile)
+	}
+}
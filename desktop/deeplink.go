@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/options"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// appScheme is the URL scheme registered for the app, as in raxcel://verified?email=...
+const appScheme = "raxcel"
+
+// deepLinkEvent tells the frontend that TakeDeepLink has a link to handle
+const deepLinkEvent = "deeplink"
+
+type DeepLink struct {
+	Action string            `json:"action"`
+	Params map[string]string `json:"params"`
+}
+
+func parseDeepLink(rawURL string) (DeepLink, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Scheme, appScheme) {
+		return DeepLink{}, false
+	}
+	// raxcel://verified and raxcel:verified are both accepted
+	action := u.Host
+	if action == "" {
+		action = strings.Trim(u.Opaque, "/")
+	}
+	params := make(map[string]string)
+	for key, values := range u.Query() {
+		params[key] = values[0]
+	}
+	return DeepLink{Action: action, Params: params}, true
+}
+
+// handleDeepLink keeps a link the operating system opened the app with and
+// brings the window to the front. Links are kept until the frontend takes them,
+// because one can arrive before the frontend has loaded.
+func (a *App) handleDeepLink(rawURL string) {
+	link, ok := parseDeepLink(rawURL)
+	if !ok {
+		return
+	}
+	if a.ctx != nil {
+		runtime.WindowUnminimise(a.ctx)
+		runtime.WindowShow(a.ctx)
+	}
+	if link.Action == "magic-link" {
+		// Handled by the signin that is waiting for the link
+		a.checkMagicLink()
+		return
+	}
+	a.deepLinkMu.Lock()
+	a.pendingDeepLink = &link
+	a.deepLinkMu.Unlock()
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, deepLinkEvent)
+	}
+}
+
+// handleArgs looks for a link in command line arguments. Windows and Linux
+// open the link by starting the app with it as an argument.
+func (a *App) handleArgs(args []string) {
+	for _, arg := range args {
+		if strings.HasPrefix(strings.ToLower(arg), appScheme+":") {
+			a.handleDeepLink(arg)
+		}
+	}
+}
+
+// onSecondInstanceLaunch receives the arguments of an instance started while the app was already running
+func (a *App) onSecondInstanceLaunch(data options.SecondInstanceData) {
+	a.handleArgs(data.Args)
+}
+
+// TakeDeepLink returns the link the app was last opened with, or nil, and forgets it
+func (a *App) TakeDeepLink() *DeepLink {
+	a.deepLinkMu.Lock()
+	defer a.deepLinkMu.Unlock()
+	link := a.pendingDeepLink
+	a.pendingDeepLink = nil
+	return link
+}
@@ -0,0 +1,127 @@
+import type { routes } from "./wailsjs/go/models";
+import type { Cell } from "./types";
+import { parseA1Notation, resetFormulaState, updateCell } from "./formula";
+
+// Describes a proposed edit for the preview, with the value it replaces
+export function describeEdit(
+  edit: routes.SpreadsheetEdit,
+  grid: Record<string, Cell>,
+): string {
+  switch (edit.type) {
+    case "set_value":
+    case "set_formula": {
+      const next = edit.type === "set_value" ? edit.value : edit.formula;
+      const current = cellAt(edit.cell ?? "", grid)?.rawValue;
+      return current
+        ? `${edit.cell}: ${current} → ${next}`
+        : `${edit.cell}: ${next}`;
+    }
+    case "insert_rows":
+      return `Insert ${edit.count} row(s) before row ${edit.row}`;
+    case "create_chart":
+      return `Chart ${edit.range}`;
+    default:
+      return edit.type;
+  }
+}
+
+// Applies a proposed edit to the grid. Charts are drawn with drawChartOf, which
+// gets the keys of the charted cells. Returns false when the edit cannot be applied.
+export function applyEdit(
+  edit: routes.SpreadsheetEdit,
+  grid: Record<string, Cell>,
+  drawChartOf: (keys: string[]) => boolean,
+): boolean {
+  switch (edit.type) {
+    case "set_value":
+      return setCell(edit.cell ?? "", edit.value ?? "", grid);
+    case "set_formula":
+      return setCell(edit.cell ?? "", edit.formula ?? "", grid);
+    case "insert_rows":
+      insertRows(edit.row ?? 0, edit.count ?? 0, grid);
+      return true;
+    case "create_chart":
+      return drawChartOf(rangeKeys(edit.range ?? ""));
+    default:
+      return false;
+  }
+}
+
+function cellAt(ref: string, grid: Record<string, Cell>): Cell | undefined {
+  const position = parseA1Notation(ref);
+  return position ? grid[`${position.x}-${position.y}`] : undefined;
+}
+
+function setCell(
+  ref: string,
+  rawValue: string,
+  grid: Record<string, Cell>,
+): boolean {
+  const position = parseA1Notation(ref);
+  if (!position) return false;
+  const key = `${position.x}-${position.y}`;
+  grid[key] = {
+    x: position.x,
+    y: position.y,
+    rawValue,
+    displayValue: rawValue,
+    isSelected: false,
+    isEditing: false,
+  };
+  // Evaluates the formula, if any, and the cells that depend on this one
+  updateCell(key, grid);
+  return true;
+}
+
+// Moves the cells from the row down by count rows, with the references to them in
+// formulas, as spreadsheets do, then evaluates the formulas again
+function insertRows(row: number, count: number, grid: Record<string, Cell>) {
+  const moved = Object.values(grid)
+    .filter((cell) => cell.y >= row)
+    .sort((a, b) => b.y - a.y);
+  for (const cell of moved) {
+    delete grid[`${cell.x}-${cell.y}`];
+    grid[`${cell.x}-${cell.y + count}`] = { ...cell, y: cell.y + count };
+  }
+  for (const cell of Object.values(grid)) {
+    if (cell.rawValue.startsWith("=")) {
+      cell.rawValue = shiftRowReferences(cell.rawValue, row, count);
+    }
+  }
+  resetFormulaState();
+  for (const [key, cell] of Object.entries(grid)) {
+    if (cell.rawValue.startsWith("=")) {
+      updateCell(key, grid);
+    }
+  }
+}
+
+// Adds count to the rows of the A1 references in a formula that point at the row or
+// below. Quoted text and function names such as LOG10 are left as they are.
+function shiftRowReferences(
+  formula: string,
+  row: number,
+  count: number,
+): string {
+  return formula.replace(
+    /"[^"]*"|\b([A-Z]+)(\d+)\b(?!\()/g,
+    (match, column?: string, referencedRow?: string) => {
+      if (column === undefined || referencedRow === undefined) return match;
+      const y = Number(referencedRow);
+      return y >= row ? `${column}${y + count}` : match;
+    },
+  );
+}
+
+// Returns the keys of the cells in an A1 range such as "A1:B3", row by row
+function rangeKeys(range: string): string[] {
+  const [start, end] = range.split(":").map(parseA1Notation);
+  if (!start || !end) return [];
+  const keys = [];
+  for (let y = Math.min(start.y, end.y); y <= Math.max(start.y, end.y); y++) {
+    for (let x = Math.min(start.x, end.x); x <= Math.max(start.x, end.x); x++) {
+      keys.push(`${x}-${y}`);
+    }
+  }
+  return keys;
+}
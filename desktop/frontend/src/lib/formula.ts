@@ -18,7 +18,9 @@ function columnToNumber(column: string): number {
   return result;
 }
 
-function parseA1Notation(cellRef: string): { x: number; y: number } | null {
+export function parseA1Notation(
+  cellRef: string,
+): { x: number; y: number } | null {
   const match = cellRef.match(/^([A-Z]+)(\d+)$/);
   if (!match) return null;
   const [, column, row] = match;
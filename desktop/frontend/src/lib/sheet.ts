@@ -1,38 +1,54 @@
 import type { Cell } from "./types";
+import { routes } from "./wailsjs/go/models";
 
-export function gridToMarkdownTable(grid: Record<string, Cell>): string {
-  // グリッドの範囲を取得
-  const cells = Object.keys(grid);
-  if (cells.length === 0) return "";
-
-  const coords = cells.map((key) => {
-    const [x, y] = key.split("-").map(Number);
-    return { x, y };
-  });
-
-  const maxX = Math.max(...coords.map((c) => c.x));
-  const maxY = Math.max(...coords.map((c) => c.y));
+// A1 reference of a grid position, such as "AA10" for x = 27, y = 10
+export function cellReference(x: number, y: number): string {
+  let column = "";
+  let columnIndex = x;
+  while (columnIndex > 0) {
+    columnIndex--;
+    column = String.fromCharCode(65 + (columnIndex % 26)) + column;
+    columnIndex = Math.floor(columnIndex / 26);
+  }
+  return `${column}${y}`;
+}
 
-  // Markdownテーブルを構築
-  let markdown = "\nCurrent spreadsheet content:\n\n";
+// The sheet sent with a chat message. The server decides how much of it the
+// model sees, so every non-empty cell is sent.
+export function gridToSpreadsheetContext(
+  grid: Record<string, Cell>,
+  selectedCells: Iterable<string>,
+  sheet: string,
+): routes.SpreadsheetContext {
+  // Row 0 and column 0 hold the headers of the sheet, not cells
+  const cells = Object.values(grid)
+    .filter((cell) => cell.x > 0 && cell.y > 0)
+    .filter((cell) => cell.rawValue !== "" || cell.displayValue !== "")
+    .sort((a, b) => a.y - b.y || a.x - b.x);
 
-  // ヘッダー行（列名: A, B, C...）
-  const headers = [];
-  for (let x = 0; x <= maxX; x++) {
-    headers.push(String.fromCharCode(65 + x));
-  }
-  markdown += "| " + headers.join(" | ") + " |\n";
-  markdown += "|" + headers.map(() => "---").join("|") + "|\n";
+  const selection = [...selectedCells]
+    .map((key) => key.split("-").map(Number))
+    .filter(([x, y]) => x > 0 && y > 0)
+    .map(([x, y]) => cellReference(x, y));
 
-  // データ行
-  for (let y = 0; y <= maxY; y++) {
-    const row = [];
-    for (let x = 0; x <= maxX; x++) {
-      const cell = grid[`${x}-${y}`];
-      row.push(cell?.displayValue || "");
-    }
-    markdown += "| " + row.join(" | ") + " |\n";
-  }
+  // Row 1 names the columns when it only holds text and other rows follow
+  const firstRow = cells.filter((cell) => cell.y === 1);
+  const isHeaderRow =
+    firstRow.length > 0 &&
+    cells.length > firstRow.length &&
+    firstRow.every(
+      (cell) =>
+        !cell.rawValue.startsWith("=") && isNaN(Number(cell.displayValue)),
+    );
 
-  return markdown;
+  return routes.SpreadsheetContext.createFrom({
+    sheet,
+    cells: cells.map((cell) => ({
+      cell: cellReference(cell.x, cell.y),
+      value: cell.displayValue,
+      formula: cell.rawValue.startsWith("=") ? cell.rawValue : undefined,
+    })),
+    selection,
+    headerRow: isHeaderRow ? 1 : 0,
+  });
 }
@@ -0,0 +1,14 @@
+// Email of the account verified through a raxcel://verified link, shown on the signin page
+let verifiedEmail = $state("");
+
+export const deepLinkState = {
+  get verifiedEmail() {
+    return verifiedEmail;
+  },
+  setVerified(email: string) {
+    verifiedEmail = email;
+  },
+  clear() {
+    verifiedEmail = "";
+  },
+};
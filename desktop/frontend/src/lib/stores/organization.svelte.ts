@@ -0,0 +1,11 @@
+// The organization whose chat quota new messages are charged to; "" for personal use
+let activeOrganizationId = $state("");
+
+export const organizationState = {
+  get activeOrganizationId() {
+    return activeOrganizationId;
+  },
+  select(organizationId: string) {
+    activeOrganizationId = organizationId;
+  },
+};
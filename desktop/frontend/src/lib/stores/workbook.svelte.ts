@@ -0,0 +1,17 @@
+// The file name of the workbook opened in the sheet; "" until one is opened
+let name = $state("");
+// The name of the workbook's sheet shown in the grid
+let sheet = $state("");
+
+export const workbookState = {
+  get name() {
+    return name;
+  },
+  get sheet() {
+    return sheet;
+  },
+  open(fileName: string, sheetName: string) {
+    name = fileName;
+    sheet = sheetName;
+  },
+};
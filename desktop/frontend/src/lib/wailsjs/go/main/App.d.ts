@@ -1,17 +1,108 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
+import {routes} from '../models';
 
-export function ChatWithAI(arg1:string,arg2:string):Promise<main.ChatWithAIResult>;
+export function AcceptInvitation(arg1:string):Promise<main.AcceptInvitationResult>;
+
+export function CancelChat():Promise<void>;
+
+export function CancelMagicLinkSignin():Promise<void>;
+
+export function ChangeEmail(arg1:string,arg2:string,arg3:string):Promise<main.ChangeEmailResult>;
+
+export function ChangePassword(arg1:string,arg2:string):Promise<main.ChangePasswordResult>;
+
+export function ChatWithAI(arg1:string,arg2:routes.SpreadsheetContext,arg3:string,arg4:string,arg5:string):Promise<main.ChatWithAIResult>;
+
+export function CompleteTwoFactorSignin(arg1:string,arg2:string):Promise<main.SigninResult>;
+
+export function ConfirmTwoFactor(arg1:string):Promise<main.ConfirmTwoFactorResult>;
+
+export function CreateOrganization(arg1:string):Promise<main.OrganizationResult>;
+
+export function CreatePersonalAccessToken(arg1:string,arg2:Array<string>,arg3:number):Promise<main.CreatePersonalAccessTokenResult>;
+
+export function DeclineInvitation(arg1:string):Promise<main.OrganizationActionResult>;
+
+export function DeleteAccount(arg1:string,arg2:string):Promise<main.DeleteAccountResult>;
+
+export function DeleteConversation(arg1:string):Promise<main.DeleteConversationResult>;
+
+export function DeleteOrganization(arg1:string):Promise<main.OrganizationActionResult>;
+
+export function DisableTwoFactor(arg1:string,arg2:string):Promise<main.DisableTwoFactorResult>;
+
+export function GetCredentialStore():Promise<main.GetCredentialStoreResult>;
 
 export function GetCurrentUser():Promise<main.GetCurrentUserResult>;
 
+export function GetOrganization(arg1:string):Promise<main.OrganizationResult>;
+
+export function GetOrganizationUsage(arg1:string):Promise<main.OrganizationUsageResult>;
+
 export function Greet(arg1:string):Promise<string>;
 
-export function LoadChatHistory():Promise<main.LoadChatHistoryResult>;
+export function InviteToOrganization(arg1:string,arg2:string,arg3:string):Promise<main.InviteToOrganizationResult>;
+
+export function ListConversations():Promise<main.ListConversationsResult>;
+
+export function ListInvitations(arg1:string):Promise<main.ListInvitationsResult>;
+
+export function ListOrganizations():Promise<main.ListOrganizationsResult>;
+
+export function ListPersonalAccessTokens():Promise<main.ListPersonalAccessTokensResult>;
+
+export function ListProfiles():Promise<main.ListProfilesResult>;
+
+export function ListSessions():Promise<main.ListSessionsResult>;
+
+export function LoadChatHistory(arg1:string,arg2:string,arg3:string,arg4:number):Promise<main.LoadChatHistoryResult>;
+
+export function OpenConversation(arg1:string):Promise<main.ConversationResult>;
+
+export function RemoveMember(arg1:string,arg2:string):Promise<main.OrganizationActionResult>;
+
+export function RemoveProfile(arg1:string):Promise<main.RemoveProfileResult>;
+
+export function RenameConversation(arg1:string,arg2:string):Promise<main.ConversationResult>;
+
+export function RequestPasswordReset(arg1:string):Promise<main.RequestPasswordResetResult>;
+
+export function ResendVerificationEmail(arg1:string):Promise<main.ResendVerificationEmailResult>;
+
+export function ResetPassword(arg1:string,arg2:string):Promise<main.ResetPasswordResult>;
+
+export function RevokeInvitation(arg1:string,arg2:string):Promise<main.OrganizationActionResult>;
+
+export function RevokePersonalAccessToken(arg1:string):Promise<main.RevokePersonalAccessTokenResult>;
+
+export function RevokeSession(arg1:string):Promise<main.RevokeSessionResult>;
+
+export function SaveProfile(arg1:string,arg2:string):Promise<main.SaveProfileResult>;
+
+export function SetCredentialStore(arg1:string):Promise<main.SetCredentialStoreResult>;
+
+export function SetupTwoFactor(arg1:string):Promise<main.SetupTwoFactorResult>;
 
 export function SignOut():Promise<main.SignOutResult>;
 
+export function SignOutEverywhere():Promise<main.RevokeSessionResult>;
+
 export function Signin(arg1:string,arg2:string):Promise<main.SigninResult>;
 
+export function SigninWithMagicLink(arg1:string):Promise<main.SigninResult>;
+
+export function SigninWithOIDC():Promise<main.SigninResult>;
+
 export function Signup(arg1:string,arg2:string):Promise<main.SignupResult>;
+
+export function StreamChatWithAI(arg1:string,arg2:routes.SpreadsheetContext,arg3:string,arg4:string,arg5:string):Promise<main.StreamChatWithAIResult>;
+
+export function SwitchProfile(arg1:string):Promise<main.SwitchProfileResult>;
+
+export function TakeDeepLink():Promise<main.DeepLink>;
+
+export function UpdateMemberRole(arg1:string,arg2:string,arg3:string):Promise<main.OrganizationActionResult>;
+
+export function UpdateOrganization(arg1:string,arg2:string,arg3:number):Promise<main.OrganizationResult>;
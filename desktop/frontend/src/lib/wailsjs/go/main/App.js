@@ -2,30 +2,210 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function ChatWithAI(arg1, arg2) {
-  return window['go']['main']['App']['ChatWithAI'](arg1, arg2);
+export function AcceptInvitation(arg1) {
+  return window['go']['main']['App']['AcceptInvitation'](arg1);
+}
+
+export function CancelChat() {
+  return window['go']['main']['App']['CancelChat']();
+}
+
+export function CancelMagicLinkSignin() {
+  return window['go']['main']['App']['CancelMagicLinkSignin']();
+}
+
+export function ChangeEmail(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ChangeEmail'](arg1, arg2, arg3);
+}
+
+export function ChangePassword(arg1, arg2) {
+  return window['go']['main']['App']['ChangePassword'](arg1, arg2);
+}
+
+export function ChatWithAI(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ChatWithAI'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function CompleteTwoFactorSignin(arg1, arg2) {
+  return window['go']['main']['App']['CompleteTwoFactorSignin'](arg1, arg2);
+}
+
+export function ConfirmTwoFactor(arg1) {
+  return window['go']['main']['App']['ConfirmTwoFactor'](arg1);
+}
+
+export function CreateOrganization(arg1) {
+  return window['go']['main']['App']['CreateOrganization'](arg1);
+}
+
+export function CreatePersonalAccessToken(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CreatePersonalAccessToken'](arg1, arg2, arg3);
+}
+
+export function DeclineInvitation(arg1) {
+  return window['go']['main']['App']['DeclineInvitation'](arg1);
+}
+
+export function DeleteAccount(arg1, arg2) {
+  return window['go']['main']['App']['DeleteAccount'](arg1, arg2);
+}
+
+export function DeleteConversation(arg1) {
+  return window['go']['main']['App']['DeleteConversation'](arg1);
+}
+
+export function DeleteOrganization(arg1) {
+  return window['go']['main']['App']['DeleteOrganization'](arg1);
+}
+
+export function DisableTwoFactor(arg1, arg2) {
+  return window['go']['main']['App']['DisableTwoFactor'](arg1, arg2);
+}
+
+export function GetCredentialStore() {
+  return window['go']['main']['App']['GetCredentialStore']();
 }
 
 export function GetCurrentUser() {
   return window['go']['main']['App']['GetCurrentUser']();
 }
 
+export function GetOrganization(arg1) {
+  return window['go']['main']['App']['GetOrganization'](arg1);
+}
+
+export function GetOrganizationUsage(arg1) {
+  return window['go']['main']['App']['GetOrganizationUsage'](arg1);
+}
+
 export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
 
-export function LoadChatHistory() {
-  return window['go']['main']['App']['LoadChatHistory']();
+export function InviteToOrganization(arg1, arg2, arg3) {
+  return window['go']['main']['App']['InviteToOrganization'](arg1, arg2, arg3);
+}
+
+export function ListConversations() {
+  return window['go']['main']['App']['ListConversations']();
+}
+
+export function ListInvitations(arg1) {
+  return window['go']['main']['App']['ListInvitations'](arg1);
+}
+
+export function ListOrganizations() {
+  return window['go']['main']['App']['ListOrganizations']();
+}
+
+export function ListPersonalAccessTokens() {
+  return window['go']['main']['App']['ListPersonalAccessTokens']();
+}
+
+export function ListProfiles() {
+  return window['go']['main']['App']['ListProfiles']();
+}
+
+export function ListSessions() {
+  return window['go']['main']['App']['ListSessions']();
+}
+
+export function LoadChatHistory(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['LoadChatHistory'](arg1, arg2, arg3, arg4);
+}
+
+export function OpenConversation(arg1) {
+  return window['go']['main']['App']['OpenConversation'](arg1);
+}
+
+export function RemoveMember(arg1, arg2) {
+  return window['go']['main']['App']['RemoveMember'](arg1, arg2);
+}
+
+export function RemoveProfile(arg1) {
+  return window['go']['main']['App']['RemoveProfile'](arg1);
+}
+
+export function RenameConversation(arg1, arg2) {
+  return window['go']['main']['App']['RenameConversation'](arg1, arg2);
+}
+
+export function RequestPasswordReset(arg1) {
+  return window['go']['main']['App']['RequestPasswordReset'](arg1);
+}
+
+export function ResendVerificationEmail(arg1) {
+  return window['go']['main']['App']['ResendVerificationEmail'](arg1);
+}
+
+export function ResetPassword(arg1, arg2) {
+  return window['go']['main']['App']['ResetPassword'](arg1, arg2);
+}
+
+export function RevokeInvitation(arg1, arg2) {
+  return window['go']['main']['App']['RevokeInvitation'](arg1, arg2);
+}
+
+export function RevokePersonalAccessToken(arg1) {
+  return window['go']['main']['App']['RevokePersonalAccessToken'](arg1);
+}
+
+export function RevokeSession(arg1) {
+  return window['go']['main']['App']['RevokeSession'](arg1);
+}
+
+export function SaveProfile(arg1, arg2) {
+  return window['go']['main']['App']['SaveProfile'](arg1, arg2);
+}
+
+export function SetCredentialStore(arg1) {
+  return window['go']['main']['App']['SetCredentialStore'](arg1);
+}
+
+export function SetupTwoFactor(arg1) {
+  return window['go']['main']['App']['SetupTwoFactor'](arg1);
 }
 
 export function SignOut() {
   return window['go']['main']['App']['SignOut']();
 }
 
+export function SignOutEverywhere() {
+  return window['go']['main']['App']['SignOutEverywhere']();
+}
+
 export function Signin(arg1, arg2) {
   return window['go']['main']['App']['Signin'](arg1, arg2);
 }
 
+export function SigninWithMagicLink(arg1) {
+  return window['go']['main']['App']['SigninWithMagicLink'](arg1);
+}
+
+export function SigninWithOIDC() {
+  return window['go']['main']['App']['SigninWithOIDC']();
+}
+
 export function Signup(arg1, arg2) {
   return window['go']['main']['App']['Signup'](arg1, arg2);
 }
+
+export function StreamChatWithAI(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['StreamChatWithAI'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function SwitchProfile(arg1) {
+  return window['go']['main']['App']['SwitchProfile'](arg1);
+}
+
+export function TakeDeepLink() {
+  return window['go']['main']['App']['TakeDeepLink']();
+}
+
+export function UpdateMemberRole(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UpdateMemberRole'](arg1, arg2, arg3);
+}
+
+export function UpdateOrganization(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UpdateOrganization'](arg1, arg2, arg3);
+}
@@ -1,7 +1,50 @@
 export namespace main {
 	
+	export class AcceptInvitationResult {
+	    organizationId: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AcceptInvitationResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.organizationId = source["organizationId"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ChangeEmailResult {
+	    message: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChangeEmailResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.message = source["message"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ChangePasswordResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChangePasswordResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
 	export class ChatWithAIResult {
 	    message: string;
+	    conversationId: string;
+	    title: string;
+	    edits: routes.SpreadsheetEdit[];
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
@@ -11,23 +54,81 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.message = source["message"];
+	        this.conversationId = source["conversationId"];
+	        this.title = source["title"];
+	        this.edits = this.convertValues(source["edits"], routes.SpreadsheetEdit);
 	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class GetCurrentUserResult {
-	    userId: string;
+	export class ConfirmTwoFactorResult {
+	    recoveryCodes: string[];
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new GetCurrentUserResult(source);
+	        return new ConfirmTwoFactorResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.userId = source["userId"];
+	        this.recoveryCodes = source["recoveryCodes"];
 	        this.error = source["error"];
 	    }
 	}
+	export class Conversation {
+	    id: string;
+	    title: string;
+	    workbook: string;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Conversation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.workbook = source["workbook"];
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class Mesaage {
 	    id: string;
 	    userId: string;
@@ -48,17 +149,21 @@ export namespace main {
 	        this.createdAt = source["createdAt"];
 	    }
 	}
-	export class LoadChatHistoryResult {
+	export class ConversationResult {
+	    conversation: Conversation;
 	    messages: Mesaage[];
+	    prevCursor: string;
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new LoadChatHistoryResult(source);
+	        return new ConversationResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.conversation = this.convertValues(source["conversation"], Conversation);
 	        this.messages = this.convertValues(source["messages"], Mesaage);
+	        this.prevCursor = source["prevCursor"];
 	        this.error = source["error"];
 	    }
 	
@@ -80,12 +185,104 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class PersonalAccessToken {
+	    id: string;
+	    name: string;
+	    prefix: string;
+	    scopes: string;
+	    // Go type: time
+	    expiresAt?: any;
+	    // Go type: time
+	    lastUsedAt?: any;
+	    // Go type: time
+	    createdAt: any;
 	
-	export class SignOutResult {
+	    static createFrom(source: any = {}) {
+	        return new PersonalAccessToken(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.prefix = source["prefix"];
+	        this.scopes = source["scopes"];
+	        this.expiresAt = this.convertValues(source["expiresAt"], null);
+	        this.lastUsedAt = this.convertValues(source["lastUsedAt"], null);
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CreatePersonalAccessTokenResult {
+	    token: string;
+	    personalAccessToken: PersonalAccessToken;
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new SignOutResult(source);
+	        return new CreatePersonalAccessTokenResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.token = source["token"];
+	        this.personalAccessToken = this.convertValues(source["personalAccessToken"], PersonalAccessToken);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DeepLink {
+	    action: string;
+	    params: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeepLink(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action = source["action"];
+	        this.params = source["params"];
+	    }
+	}
+	export class DeleteAccountResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeleteAccountResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
@@ -93,33 +290,881 @@ export namespace main {
 	        this.error = source["error"];
 	    }
 	}
-	export class SigninResult {
-	    token: string;
+	export class DeleteConversationResult {
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new SigninResult(source);
+	        return new DeleteConversationResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.token = source["token"];
 	        this.error = source["error"];
 	    }
 	}
-	export class SignupResult {
+	export class DisableTwoFactorResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DisableTwoFactorResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+	export class GetCredentialStoreResult {
+	    store: string;
+	    setting: string;
+	    keyringUnavailable: boolean;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GetCredentialStoreResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.store = source["store"];
+	        this.setting = source["setting"];
+	        this.keyringUnavailable = source["keyringUnavailable"];
+	        this.error = source["error"];
+	    }
+	}
+	export class GetCurrentUserResult {
 	    userId: string;
+	    email: string;
+	    isVerified: boolean;
+	    totpEnabled: boolean;
+	    role: string;
+	    // Go type: time
+	    createdAt: any;
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new SignupResult(source);
+	        return new GetCurrentUserResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.userId = source["userId"];
+	        this.email = source["email"];
+	        this.isVerified = source["isVerified"];
+	        this.totpEnabled = source["totpEnabled"];
+	        this.role = source["role"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OrganizationInvitation {
+	    id: string;
+	    email: string;
+	    role: string;
+	    // Go type: time
+	    expiresAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new OrganizationInvitation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.email = source["email"];
+	        this.role = source["role"];
+	        this.expiresAt = this.convertValues(source["expiresAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class InviteToOrganizationResult {
+	    invitation: OrganizationInvitation;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new InviteToOrganizationResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.invitation = this.convertValues(source["invitation"], OrganizationInvitation);
 	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ListConversationsResult {
+	    conversations: Conversation[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ListConversationsResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.conversations = this.convertValues(source["conversations"], Conversation);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ListInvitationsResult {
+	    invitations: OrganizationInvitation[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ListInvitationsResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.invitations = this.convertValues(source["invitations"], OrganizationInvitation);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ListOrganizationsResult {
+	    organizations: routes.OrganizationSummary[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ListOrganizationsResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.organizations = this.convertValues(source["organizations"], routes.OrganizationSummary);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ListPersonalAccessTokensResult {
+	    personalAccessTokens: PersonalAccessToken[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ListPersonalAccessTokensResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.personalAccessTokens = this.convertValues(source["personalAccessTokens"], PersonalAccessToken);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProfileInfo {
+	    name: string;
+	    apiUrl: string;
+	    email: string;
+	    active: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.apiUrl = source["apiUrl"];
+	        this.email = source["email"];
+	        this.active = source["active"];
+	    }
+	}
+	export class ListProfilesResult {
+	    profiles: ProfileInfo[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ListProfilesResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profiles = this.convertValues(source["profiles"], ProfileInfo);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ListSessionsResult {
+	    sessions: routes.SessionInfo[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ListSessionsResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sessions = this.convertValues(source["sessions"], routes.SessionInfo);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LoadChatHistoryResult {
+	    messages: Mesaage[];
+	    prevCursor: string;
+	    nextCursor: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoadChatHistoryResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.messages = this.convertValues(source["messages"], Mesaage);
+	        this.prevCursor = source["prevCursor"];
+	        this.nextCursor = source["nextCursor"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Organization {
+	    id: string;
+	    name: string;
+	    chatQuota: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Organization(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.chatQuota = source["chatQuota"];
+	    }
+	}
+	export class OrganizationActionResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OrganizationActionResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+	
+	export class OrganizationResult {
+	    organization: Organization;
+	    members: routes.OrganizationMember[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OrganizationResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.organization = this.convertValues(source["organization"], Organization);
+	        this.members = this.convertValues(source["members"], routes.OrganizationMember);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OrganizationUsageResult {
+	    chatQuota: number;
+	    messagesThisMonth: number;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OrganizationUsageResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.chatQuota = source["chatQuota"];
+	        this.messagesThisMonth = source["messagesThisMonth"];
+	        this.error = source["error"];
+	    }
+	}
+	
+	
+	export class RemoveProfileResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RemoveProfileResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+	export class RequestPasswordResetResult {
+	    message: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RequestPasswordResetResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.message = source["message"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ResendVerificationEmailResult {
+	    message: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ResendVerificationEmailResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.message = source["message"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ResetPasswordResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ResetPasswordResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+	export class RevokePersonalAccessTokenResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RevokePersonalAccessTokenResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+	export class RevokeSessionResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RevokeSessionResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+	export class SaveProfileResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SaveProfileResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+	export class SetCredentialStoreResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SetCredentialStoreResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+	export class SetupTwoFactorResult {
+	    secret: string;
+	    otpauthUrl: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SetupTwoFactorResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.secret = source["secret"];
+	        this.otpauthUrl = source["otpauthUrl"];
+	        this.error = source["error"];
+	    }
+	}
+	export class SignOutResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SignOutResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+	export class SigninResult {
+	    token: string;
+	    error: string;
+	    twoFactorRequired: boolean;
+	    challengeToken: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SigninResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.token = source["token"];
+	        this.error = source["error"];
+	        this.twoFactorRequired = source["twoFactorRequired"];
+	        this.challengeToken = source["challengeToken"];
+	    }
+	}
+	export class SignupResult {
+	    userId: string;
+	    error: string;
+	    code: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SignupResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.userId = source["userId"];
+	        this.error = source["error"];
+	        this.code = source["code"];
+	    }
+	}
+	export class StreamChatWithAIResult {
+	    message: string;
+	    conversationId: string;
+	    title: string;
+	    edits: routes.SpreadsheetEdit[];
+	    cancelled: boolean;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new StreamChatWithAIResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.message = source["message"];
+	        this.conversationId = source["conversationId"];
+	        this.title = source["title"];
+	        this.edits = this.convertValues(source["edits"], routes.SpreadsheetEdit);
+	        this.cancelled = source["cancelled"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SwitchProfileResult {
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SwitchProfileResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	    }
+	}
+
+}
+
+export namespace routes {
+	
+	export class OrganizationMember {
+	    userId: string;
+	    email: string;
+	    role: string;
+	    // Go type: time
+	    joinedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new OrganizationMember(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.userId = source["userId"];
+	        this.email = source["email"];
+	        this.role = source["role"];
+	        this.joinedAt = this.convertValues(source["joinedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OrganizationSummary {
+	    id: string;
+	    name: string;
+	    role: string;
+	    chatQuota: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new OrganizationSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.role = source["role"];
+	        this.chatQuota = source["chatQuota"];
+	    }
+	}
+	export class SessionInfo {
+	    id: string;
+	    deviceName: string;
+	    ipAddress: string;
+	    userAgent: string;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    lastSeenAt: any;
+	    current: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.deviceName = source["deviceName"];
+	        this.ipAddress = source["ipAddress"];
+	        this.userAgent = source["userAgent"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.lastSeenAt = this.convertValues(source["lastSeenAt"], null);
+	        this.current = source["current"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SpreadsheetCell {
+	    cell: string;
+	    value: string;
+	    formula?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SpreadsheetCell(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.cell = source["cell"];
+	        this.value = source["value"];
+	        this.formula = source["formula"];
+	    }
+	}
+	export class SpreadsheetContext {
+	    sheet?: string;
+	    cells: SpreadsheetCell[];
+	    selection?: string[];
+	    headerRow?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SpreadsheetContext(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sheet = source["sheet"];
+	        this.cells = this.convertValues(source["cells"], SpreadsheetCell);
+	        this.selection = source["selection"];
+	        this.headerRow = source["headerRow"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SpreadsheetEdit {
+	    type: string;
+	    cell?: string;
+	    value?: string;
+	    formula?: string;
+	    row?: number;
+	    count?: number;
+	    range?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SpreadsheetEdit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.cell = source["cell"];
+	        this.value = source["value"];
+	        this.formula = source["formula"];
+	        this.row = source["row"];
+	        this.count = source["count"];
+	        this.range = source["range"];
+	    }
 	}
 
 }
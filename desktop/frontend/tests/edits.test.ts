@@ -0,0 +1,71 @@
+import { describe, test, expect, beforeEach } from "vitest";
+import { applyEdit } from "../src/lib/edits";
+import { resetFormulaState } from "../src/lib/formula";
+import type { Cell } from "../src/lib/types";
+import type { routes } from "../src/lib/wailsjs/go/models";
+
+function cell(x: number, y: number, rawValue: string): Cell {
+  return {
+    x,
+    y,
+    rawValue,
+    displayValue: rawValue,
+    isSelected: false,
+    isEditing: false,
+  };
+}
+
+describe("edits.ts", () => {
+  let grid: Record<string, Cell>;
+
+  beforeEach(() => {
+    grid = {};
+    resetFormulaState();
+  });
+
+  describe("insert_rows", () => {
+    const insertRows = (row: number, count: number) =>
+      applyEdit(
+        { type: "insert_rows", row, count } as routes.SpreadsheetEdit,
+        grid,
+        () => false,
+      );
+
+    test("moves the references to moved cells", () => {
+      grid["1-1"] = cell(1, 1, "1");
+      grid["1-2"] = cell(1, 2, "2");
+      grid["1-3"] = cell(1, 3, "3");
+      grid["2-1"] = cell(2, 1, "=SUM(A1:A3)");
+      grid["2-3"] = cell(2, 3, "=A3*10");
+
+      expect(insertRows(2, 2)).toBe(true);
+
+      expect(grid["1-4"].rawValue).toBe("2");
+      expect(grid["1-5"].rawValue).toBe("3");
+      expect(grid["1-2"]).toBeUndefined();
+      expect(grid["2-1"].rawValue).toBe("=SUM(A1:A5)");
+      expect(grid["2-1"].displayValue).toBe("6");
+      expect(grid["2-5"].rawValue).toBe("=A5*10");
+      expect(grid["2-5"].displayValue).toBe("30");
+    });
+
+    test("leaves references above the inserted rows", () => {
+      grid["1-1"] = cell(1, 1, "4");
+      grid["1-3"] = cell(1, 3, "=A1+1");
+
+      insertRows(2, 1);
+
+      expect(grid["1-4"].rawValue).toBe("=A1+1");
+      expect(grid["1-4"].displayValue).toBe("5");
+    });
+
+    test("leaves function names and quoted text", () => {
+      grid["1-2"] = cell(1, 2, "100");
+      grid["2-1"] = cell(2, 1, '=LOG10(A2)&"A2"');
+
+      insertRows(1, 1);
+
+      expect(grid["2-2"].rawValue).toBe('=LOG10(A3)&"A2"');
+    });
+  });
+});
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/types"
+)
+
+// magicLinkPollInterval is how often the app asks whether the emailed link has been opened
+const magicLinkPollInterval = 3 * time.Second
+
+// magicLinkWait is the magic link signin in progress
+type magicLinkWait struct {
+	cancel context.CancelFunc
+	// check is signalled by the raxcel://magic-link callback to poll right away
+	check chan struct{}
+}
+
+// SigninWithMagicLink emails a signin link and waits until it is opened, in a browser
+// on any device. The signin completes like Signin, including the second factor.
+func (a *App) SigninWithMagicLink(email string) SigninResult {
+	jsonData, err := json.Marshal(types.RequestMagicLinkRequest{
+		Email: email,
+	})
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to marshal request: %v", err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := http.Post(fmt.Sprintf("%s/auth/magic-link", apiUrl), "application/json", bytes.NewReader(jsonData))
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to send request: %v", err),
+		}
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to read response: %v", err),
+		}
+	}
+	var requestResponse types.RequestMagicLinkResponse
+	if err := json.Unmarshal(body, &requestResponse); err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return SigninResult{
+			Error: rateLimitError(resp, requestResponse.Error),
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return SigninResult{
+			Error: requestResponse.Error,
+		}
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), requestResponse.ExpiresAt)
+	defer cancel()
+	wait := &magicLinkWait{cancel: cancel, check: make(chan struct{}, 1)}
+	a.magicLinkMu.Lock()
+	if a.magicLink != nil {
+		a.magicLink.cancel()
+	}
+	a.magicLink = wait
+	a.magicLinkMu.Unlock()
+	defer func() {
+		a.magicLinkMu.Lock()
+		if a.magicLink == wait {
+			a.magicLink = nil
+		}
+		a.magicLinkMu.Unlock()
+	}()
+
+	jsonData, err = json.Marshal(types.CompleteMagicLinkRequest{
+		PendingId: requestResponse.PendingId,
+	})
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to marshal request: %v", err),
+		}
+	}
+	ticker := time.NewTicker(magicLinkPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-wait.check:
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return SigninResult{
+					Error: "The sign-in link has expired",
+				}
+			}
+			return SigninResult{
+				Error: "Sign-in was cancelled",
+			}
+		}
+		resp, err := postSignin(fmt.Sprintf("%s/auth/magic-link/complete", apiUrl), jsonData)
+		if err != nil {
+			// The connection may come back before the link expires
+			continue
+		}
+		// Polls over the rate limit are tried again at the next tick
+		if resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+			continue
+		}
+		result := finishSignin(resp)
+		resp.Body.Close()
+		return result
+	}
+}
+
+// CancelMagicLinkSignin stops waiting for the link of SigninWithMagicLink
+func (a *App) CancelMagicLinkSignin() {
+	a.magicLinkMu.Lock()
+	defer a.magicLinkMu.Unlock()
+	if a.magicLink != nil {
+		a.magicLink.cancel()
+	}
+}
+
+// checkMagicLink makes SigninWithMagicLink poll right away
+func (a *App) checkMagicLink() {
+	a.magicLinkMu.Lock()
+	defer a.magicLinkMu.Unlock()
+	if a.magicLink == nil {
+		return
+	}
+	select {
+	case a.magicLink.check <- struct{}{}:
+	default:
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/options/mac"
 )
 
 //go:embed all:frontend/build
@@ -25,6 +26,14 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		// raxcel:// links start a second instance on Windows and Linux, which hands them over to this one
+		SingleInstanceLock: &options.SingleInstanceLock{
+			UniqueId:               "net.utcode.raxcel",
+			OnSecondInstanceLaunch: app.onSecondInstanceLaunch,
+		},
+		Mac: &mac.Options{
+			OnUrlOpen: app.handleDeepLink,
+		},
 		Bind: []interface{}{
 			app,
 		},
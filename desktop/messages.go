@@ -1,14 +1,13 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/ut-code/Raxcel/server/types"
-	"github.com/zalando/go-keyring"
 )
 
 type Mesaage struct {
@@ -21,33 +20,38 @@ type Mesaage struct {
 
 type LoadChatHistoryResult struct {
 	Messages []Mesaage `json:"messages"`
-	Error    string    `json:"error"`
+	// PrevCursor loads the page before this one and is empty at the start of the history
+	PrevCursor string `json:"prevCursor"`
+	// NextCursor loads the messages sent after this page
+	NextCursor string `json:"nextCursor"`
+	Error      string `json:"error"`
 }
 
-func (a *App) LoadChatHistory() LoadChatHistoryResult {
+// LoadChatHistory returns the latest messages of a conversation, or of every
+// conversation when conversationId is empty. before is the PrevCursor of a page
+// to get the one before it, after is the NextCursor of a page to get the messages
+// sent since, and limit is the page size (0 for the server default).
+func (a *App) LoadChatHistory(conversationId string, before string, after string, limit int) LoadChatHistoryResult {
 	apiUrl := getAPIURL()
-
-	jwt, err := keyring.Get("Raxcel", "raxcel-user")
-	if err != nil {
-		return LoadChatHistoryResult{
-			Messages: []Mesaage{},
-			Error:    fmt.Sprint(err),
-		}
+	query := url.Values{}
+	if conversationId != "" {
+		query.Set("conversationId", conversationId)
 	}
-	fmt.Println("JWT retrieved successfully")
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/messages", apiUrl), nil)
-	if err != nil {
-		return LoadChatHistoryResult{
-			Messages: []Mesaage{},
-			Error:    fmt.Sprint(err),
-		}
+	if before != "" {
+		query.Set("before", before)
+	}
+	if after != "" {
+		query.Set("after", after)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	endpoint := fmt.Sprintf("%s/messages", apiUrl)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 
-	fmt.Println("Sending request to:", req.URL.String())
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := a.sendAuthorized("GET", endpoint, nil)
 	if err != nil {
 		return LoadChatHistoryResult{
 			Messages: []Mesaage{},
@@ -56,7 +60,6 @@ func (a *App) LoadChatHistory() LoadChatHistoryResult {
 	}
 	defer resp.Body.Close()
 
-	fmt.Println("Response status:", resp.StatusCode)
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return LoadChatHistoryResult{
@@ -103,20 +106,44 @@ func (a *App) LoadChatHistory() LoadChatHistoryResult {
 	}
 
 	return LoadChatHistoryResult{
-		Messages: messages,
-		Error:    "",
+		Messages:   messages,
+		PrevCursor: serverResponse.PrevCursor,
+		NextCursor: serverResponse.NextCursor,
+		Error:      "",
+	}
+}
+
+// includedSpreadsheet leaves out a spreadsheet without cells, which the frontend
+// sends when the sheet is empty or not included
+func includedSpreadsheet(spreadsheet types.SpreadsheetContext) *types.SpreadsheetContext {
+	if len(spreadsheet.Cells) == 0 {
+		return nil
 	}
+	return &spreadsheet
 }
 
 type ChatWithAIResult struct {
 	Message string `json:"message"`
-	Error   string `json:"error"`
+	// ConversationId is the thread the message went to, new when none was given
+	ConversationId string `json:"conversationId"`
+	Title          string `json:"title"`
+	// Edits are spreadsheet edits the model proposes, for the user to apply or reject
+	Edits []types.SpreadsheetEdit `json:"edits"`
+	Error string                  `json:"error"`
 }
 
-func (a *App) ChatWithAI(message string, spreadsheetContext string) ChatWithAIResult {
+// ChatWithAI sends a message. organizationId charges it to an organization's chat quota
+// and is empty for personal use. An empty conversationId starts a new conversation
+// linked to workbook, the file name of the open workbook if any. spreadsheet is the
+// sheet the message is about, left out when it has no cells.
+func (a *App) ChatWithAI(message string, spreadsheet types.SpreadsheetContext, organizationId string, conversationId string, workbook string) ChatWithAIResult {
 	postData := types.ChatWithAIRequest{
-		Message:            message,
-		SpreadsheetContext: spreadsheetContext,
+		Message:        message,
+		Spreadsheet:    includedSpreadsheet(spreadsheet),
+		OrganizationId: organizationId,
+		ConversationId: conversationId,
+		Workbook:       workbook,
+		ProposeEdits:   true,
 	}
 	jsonData, err := json.Marshal(postData)
 	if err != nil {
@@ -127,26 +154,7 @@ func (a *App) ChatWithAI(message string, spreadsheetContext string) ChatWithAIRe
 	}
 	apiUrl := getAPIURL()
 
-	jwt, err := keyring.Get("Raxcel", "raxcel-user")
-	if err != nil {
-		return ChatWithAIResult{
-			Message: "",
-			Error:   fmt.Sprint(err),
-		}
-	}
-
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/messages", apiUrl), bytes.NewReader(jsonData))
-	if err != nil {
-		return ChatWithAIResult{
-			Message: "",
-			Error:   fmt.Sprint(err),
-		}
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := a.sendAuthorized("POST", fmt.Sprintf("%s/messages", apiUrl), jsonData)
 	if err != nil {
 		return ChatWithAIResult{
 			Message: "",
@@ -189,7 +197,10 @@ func (a *App) ChatWithAI(message string, spreadsheetContext string) ChatWithAIRe
 	}
 
 	return ChatWithAIResult{
-		Message: serverResponse.AiMessage,
-		Error:   "",
+		Message:        serverResponse.AiMessage,
+		ConversationId: serverResponse.ConversationId,
+		Title:          serverResponse.Title,
+		Edits:          serverResponse.Edits,
+		Error:          "",
 	}
 }
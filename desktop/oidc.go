@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/types"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// oidcTimeout is how long to wait for the user to finish signing in in the browser
+const oidcTimeout = 5 * time.Minute
+
+const oidcCallbackPage = `<!doctype html>
+<html><body style="font-family: sans-serif; text-align: center; padding-top: 64px">
+<h1>%s</h1><p>You can close this window and return to Raxcel.</p>
+</body></html>`
+
+func randomURLString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+type oidcCallback struct {
+	code string
+	err  error
+}
+
+// SigninWithOIDC signs in through the organization's identity provider. The browser
+// is sent to the provider, which redirects back to a one-off loopback listener
+// (RFC 8252) with an authorization code protected by PKCE.
+func (a *App) SigninWithOIDC() SigninResult {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to listen for the sign-in callback: %v", err),
+		}
+	}
+	defer listener.Close()
+	redirectUri := fmt.Sprintf("http://%s/callback", listener.Addr().String())
+
+	state := randomURLString()
+	codeVerifier := randomURLString()
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	postData := types.StartOIDCRequest{
+		RedirectUri:   redirectUri,
+		State:         state,
+		CodeChallenge: base64.RawURLEncoding.EncodeToString(challenge[:]),
+	}
+	jsonData, err := json.Marshal(postData)
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to marshal request: %v", err),
+		}
+	}
+	apiUrl := getAPIURL()
+
+	resp, err := http.Post(fmt.Sprintf("%s/auth/oidc/start", apiUrl), "application/json", bytes.NewReader(jsonData))
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to send request: %v", err),
+		}
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to read response: %v", err),
+		}
+	}
+	var startResponse types.StartOIDCResponse
+	if err := json.Unmarshal(body, &startResponse); err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return SigninResult{
+			Error: startResponse.Error,
+		}
+	}
+
+	callbacks := make(chan oidcCallback, 1)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/callback" {
+				http.NotFound(w, r)
+				return
+			}
+			query := r.URL.Query()
+			var result oidcCallback
+			switch {
+			case query.Get("state") != state:
+				result.err = fmt.Errorf("sign-in response did not match this request")
+			case query.Get("error") != "":
+				result.err = fmt.Errorf("identity provider returned %s: %s", query.Get("error"), query.Get("error_description"))
+			default:
+				result.code = query.Get("code")
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if result.err != nil {
+				fmt.Fprintf(w, oidcCallbackPage, "Sign-in failed")
+			} else {
+				fmt.Fprintf(w, oidcCallbackPage, "Signed in")
+			}
+			select {
+			case callbacks <- result:
+			default:
+			}
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	runtime.BrowserOpenURL(a.ctx, startResponse.AuthorizationUrl)
+
+	var callback oidcCallback
+	select {
+	case callback = <-callbacks:
+	case <-time.After(oidcTimeout):
+		return SigninResult{
+			Error: "Timed out waiting for the identity provider",
+		}
+	}
+	if callback.err != nil {
+		return SigninResult{
+			Error: fmt.Sprint(callback.err),
+		}
+	}
+
+	jsonData, err = json.Marshal(types.OIDCCallbackRequest{
+		Code:         callback.code,
+		CodeVerifier: codeVerifier,
+		RedirectUri:  redirectUri,
+	})
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to marshal request: %v", err),
+		}
+	}
+	resp, err = postSignin(fmt.Sprintf("%s/auth/oidc/callback", apiUrl), jsonData)
+	if err != nil {
+		return SigninResult{
+			Error: fmt.Sprintf("Failed to send request: %v", err),
+		}
+	}
+	defer resp.Body.Close()
+	return finishSignin(resp)
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/types"
+)
+
+type Organization struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	ChatQuota int    `json:"chatQuota"`
+}
+
+type OrganizationInvitation struct {
+	Id        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type ListOrganizationsResult struct {
+	Organizations []types.OrganizationSummary `json:"organizations"`
+	Error         string                      `json:"error"`
+}
+
+// ListOrganizations returns the organizations the user belongs to with their role in each
+func (a *App) ListOrganizations() ListOrganizationsResult {
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("GET", fmt.Sprintf("%s/orgs", apiUrl), nil)
+	if err != nil {
+		return ListOrganizationsResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ListOrganizationsResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.ListOrganizationsResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ListOrganizationsResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return ListOrganizationsResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return ListOrganizationsResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return ListOrganizationsResult{
+		Organizations: serverResponse.Organizations,
+		Error:         "",
+	}
+}
+
+type OrganizationResult struct {
+	Organization Organization               `json:"organization"`
+	Members      []types.OrganizationMember `json:"members"`
+	Error        string                     `json:"error"`
+}
+
+func (a *App) CreateOrganization(name string) OrganizationResult {
+	jsonData, err := json.Marshal(types.CreateOrganizationRequest{
+		Name: name,
+	})
+	if err != nil {
+		return OrganizationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	return a.sendOrganizationRequest("POST", fmt.Sprintf("%s/orgs", apiUrl), jsonData)
+}
+
+// GetOrganization returns the organization with its members
+func (a *App) GetOrganization(organizationId string) OrganizationResult {
+	apiUrl := getAPIURL()
+	return a.sendOrganizationRequest("GET", fmt.Sprintf("%s/orgs/%s", apiUrl, url.PathEscape(organizationId)), nil)
+}
+
+// UpdateOrganization renames the organization and sets its monthly chat quota (0 for unlimited)
+func (a *App) UpdateOrganization(organizationId, name string, chatQuota int) OrganizationResult {
+	jsonData, err := json.Marshal(types.UpdateOrganizationRequest{
+		Name:      &name,
+		ChatQuota: &chatQuota,
+	})
+	if err != nil {
+		return OrganizationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	return a.sendOrganizationRequest("PATCH", fmt.Sprintf("%s/orgs/%s", apiUrl, url.PathEscape(organizationId)), jsonData)
+}
+
+func (a *App) sendOrganizationRequest(method, endpoint string, jsonData []byte) OrganizationResult {
+	resp, err := a.sendAuthorized(method, endpoint, jsonData)
+	if err != nil {
+		return OrganizationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return OrganizationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.OrganizationResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return OrganizationResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return OrganizationResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return OrganizationResult{
+			Error: serverResponse.Error,
+		}
+	}
+	if serverResponse.Organization == nil {
+		return OrganizationResult{
+			Error: "Failed to parse response: missing organization",
+		}
+	}
+	return OrganizationResult{
+		Organization: Organization{
+			Id:        serverResponse.Organization.Id,
+			Name:      serverResponse.Organization.Name,
+			ChatQuota: serverResponse.Organization.ChatQuota,
+		},
+		Members: serverResponse.Members,
+		Error:   "",
+	}
+}
+
+type OrganizationActionResult struct {
+	Error string `json:"error"`
+}
+
+func (a *App) DeleteOrganization(organizationId string) OrganizationActionResult {
+	apiUrl := getAPIURL()
+	return a.sendOrganizationAction("DELETE", fmt.Sprintf("%s/orgs/%s", apiUrl, url.PathEscape(organizationId)), nil)
+}
+
+// UpdateMemberRole sets the role of a member to owner, admin or member
+func (a *App) UpdateMemberRole(organizationId, userId, role string) OrganizationActionResult {
+	jsonData, err := json.Marshal(types.UpdateMemberRequest{
+		Role: role,
+	})
+	if err != nil {
+		return OrganizationActionResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	return a.sendOrganizationAction("PATCH", fmt.Sprintf("%s/orgs/%s/members/%s", apiUrl, url.PathEscape(organizationId), url.PathEscape(userId)), jsonData)
+}
+
+// RemoveMember removes a member; passing your own user id leaves the organization
+func (a *App) RemoveMember(organizationId, userId string) OrganizationActionResult {
+	apiUrl := getAPIURL()
+	return a.sendOrganizationAction("DELETE", fmt.Sprintf("%s/orgs/%s/members/%s", apiUrl, url.PathEscape(organizationId), url.PathEscape(userId)), nil)
+}
+
+func (a *App) RevokeInvitation(organizationId, invitationId string) OrganizationActionResult {
+	apiUrl := getAPIURL()
+	return a.sendOrganizationAction("DELETE", fmt.Sprintf("%s/orgs/%s/invitations/%s", apiUrl, url.PathEscape(organizationId), url.PathEscape(invitationId)), nil)
+}
+
+// DeclineInvitation discards an invitation using the code from the invitation email
+func (a *App) DeclineInvitation(code string) OrganizationActionResult {
+	jsonData, err := json.Marshal(types.RespondToInvitationRequest{
+		Token: code,
+	})
+	if err != nil {
+		return OrganizationActionResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	return a.sendOrganizationAction("POST", fmt.Sprintf("%s/orgs/invitations/decline", apiUrl), jsonData)
+}
+
+func (a *App) sendOrganizationAction(method, endpoint string, jsonData []byte) OrganizationActionResult {
+	resp, err := a.sendAuthorized(method, endpoint, jsonData)
+	if err != nil {
+		return OrganizationActionResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return OrganizationActionResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.OrganizationActionResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return OrganizationActionResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return OrganizationActionResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return OrganizationActionResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return OrganizationActionResult{
+		Error: "",
+	}
+}
+
+type AcceptInvitationResult struct {
+	OrganizationId string `json:"organizationId"`
+	Error          string `json:"error"`
+}
+
+// AcceptInvitation joins the organization using the code from the invitation email
+func (a *App) AcceptInvitation(code string) AcceptInvitationResult {
+	jsonData, err := json.Marshal(types.RespondToInvitationRequest{
+		Token: code,
+	})
+	if err != nil {
+		return AcceptInvitationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("POST", fmt.Sprintf("%s/orgs/invitations/accept", apiUrl), jsonData)
+	if err != nil {
+		return AcceptInvitationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return AcceptInvitationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.AcceptInvitationResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return AcceptInvitationResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return AcceptInvitationResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return AcceptInvitationResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return AcceptInvitationResult{
+		OrganizationId: serverResponse.OrganizationId,
+		Error:          "",
+	}
+}
+
+type InviteToOrganizationResult struct {
+	Invitation OrganizationInvitation `json:"invitation"`
+	Error      string                 `json:"error"`
+}
+
+// InviteToOrganization emails an invitation code to the address
+func (a *App) InviteToOrganization(organizationId, email, role string) InviteToOrganizationResult {
+	jsonData, err := json.Marshal(types.CreateInvitationRequest{
+		Email: email,
+		Role:  role,
+	})
+	if err != nil {
+		return InviteToOrganizationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("POST", fmt.Sprintf("%s/orgs/%s/invitations", apiUrl, url.PathEscape(organizationId)), jsonData)
+	if err != nil {
+		return InviteToOrganizationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return InviteToOrganizationResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.InvitationResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return InviteToOrganizationResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return InviteToOrganizationResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return InviteToOrganizationResult{
+			Error: serverResponse.Error,
+		}
+	}
+	if serverResponse.Invitation == nil {
+		return InviteToOrganizationResult{
+			Error: "Failed to parse response: missing invitation",
+		}
+	}
+	return InviteToOrganizationResult{
+		Invitation: OrganizationInvitation{
+			Id:        serverResponse.Invitation.Id,
+			Email:     serverResponse.Invitation.Email,
+			Role:      serverResponse.Invitation.Role,
+			ExpiresAt: serverResponse.Invitation.ExpiresAt,
+		},
+		Error: "",
+	}
+}
+
+type ListInvitationsResult struct {
+	Invitations []OrganizationInvitation `json:"invitations"`
+	Error       string                   `json:"error"`
+}
+
+// ListInvitations returns the pending invitations of an organization
+func (a *App) ListInvitations(organizationId string) ListInvitationsResult {
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("GET", fmt.Sprintf("%s/orgs/%s/invitations", apiUrl, url.PathEscape(organizationId)), nil)
+	if err != nil {
+		return ListInvitationsResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ListInvitationsResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.ListInvitationsResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ListInvitationsResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return ListInvitationsResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return ListInvitationsResult{
+			Error: serverResponse.Error,
+		}
+	}
+	invitations := make([]OrganizationInvitation, len(serverResponse.Invitations))
+	for i, invitation := range serverResponse.Invitations {
+		invitations[i] = OrganizationInvitation{
+			Id:        invitation.Id,
+			Email:     invitation.Email,
+			Role:      invitation.Role,
+			ExpiresAt: invitation.ExpiresAt,
+		}
+	}
+	return ListInvitationsResult{
+		Invitations: invitations,
+		Error:       "",
+	}
+}
+
+type OrganizationUsageResult struct {
+	ChatQuota         int    `json:"chatQuota"`
+	MessagesThisMonth int64  `json:"messagesThisMonth"`
+	Error             string `json:"error"`
+}
+
+func (a *App) GetOrganizationUsage(organizationId string) OrganizationUsageResult {
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("GET", fmt.Sprintf("%s/orgs/%s/usage", apiUrl, url.PathEscape(organizationId)), nil)
+	if err != nil {
+		return OrganizationUsageResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return OrganizationUsageResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.OrganizationUsageResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return OrganizationUsageResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return OrganizationUsageResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return OrganizationUsageResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return OrganizationUsageResult{
+		ChatQuota:         serverResponse.ChatQuota,
+		MessagesThisMonth: serverResponse.MessagesThisMonth,
+		Error:             "",
+	}
+}
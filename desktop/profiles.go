@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// defaultProfileName is the profile created on first run, pointing at PUBLIC_API_URL
+const defaultProfileName = "default"
+
+// Profile is a server and the account signed in to it. Each profile keeps its
+// own tokens, so switching does not sign out of the others.
+type Profile struct {
+	Name string `json:"name"`
+	// ApiUrl is the server of the profile; empty means PUBLIC_API_URL
+	ApiUrl string `json:"apiUrl"`
+	// Email is the account last signed in with the profile, to tell profiles apart
+	Email string `json:"email"`
+}
+
+// settings is stored as settings.json in the Raxcel directory of the user config directory
+type settings struct {
+	ActiveProfile string    `json:"activeProfile"`
+	Profiles      []Profile `json:"profiles"`
+	// CredentialStore is "keyring", "file" or empty to choose automatically
+	CredentialStore string `json:"credentialStore,omitempty"`
+
+	// problem is reported by ListProfiles until the settings are saved again
+	problem string
+	// saveError keeps save from overwriting a settings file that could not be read nor backed up
+	saveError error
+}
+
+var (
+	settingsMu     sync.Mutex
+	loadedSettings *settings
+)
+
+func defaultAPIURL() string {
+	godotenv.Load()
+	return os.Getenv("PUBLIC_API_URL")
+}
+
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "Raxcel", "settings.json"), nil
+}
+
+// loadSettings reads the settings file once. Without one there is only the default
+// profile, which is how the app behaved before profiles. A file that cannot be read is
+// moved aside, so that saving does not lose the profiles in it. The caller holds settingsMu.
+func loadSettings() *settings {
+	if loadedSettings != nil {
+		return loadedSettings
+	}
+	s := &settings{}
+	if path, err := settingsPath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			if err := json.Unmarshal(data, s); err != nil {
+				s = &settings{}
+				backup := path + ".bak"
+				if renameErr := os.Rename(path, backup); renameErr != nil {
+					s.saveError = fmt.Errorf("%s could not be read (%v) nor moved aside: %w", path, err, renameErr)
+					s.problem = s.saveError.Error()
+				} else {
+					s.problem = fmt.Sprintf("%s could not be read and was moved to %s: %v", path, backup, err)
+				}
+			}
+		}
+	}
+	if len(s.Profiles) == 0 {
+		s.Profiles = []Profile{{Name: defaultProfileName}}
+	}
+	if s.find(s.ActiveProfile) < 0 {
+		s.ActiveProfile = s.Profiles[0].Name
+	}
+	loadedSettings = s
+	return s
+}
+
+func (s *settings) save() error {
+	if s.saveError != nil {
+		return s.saveError
+	}
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	s.problem = ""
+	return nil
+}
+
+func (s *settings) find(name string) int {
+	for i, profile := range s.Profiles {
+		if profile.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func activeProfile() Profile {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	s := loadSettings()
+	return s.Profiles[s.find(s.ActiveProfile)]
+}
+
+// profileKey is the credential store key of a token of a profile. The default profile keeps
+// the keys used before profiles existed, so its signin survives the upgrade.
+func profileKey(profileName, key string) string {
+	if profileName == defaultProfileName {
+		return key
+	}
+	return key + ":" + profileName
+}
+
+func clearProfileTokens(store CredentialStore, profileName string) error {
+	if err := store.Delete(profileKey(profileName, keyringRefreshToken)); err != nil {
+		return err
+	}
+	return store.Delete(profileKey(profileName, keyringAccessToken))
+}
+
+// rememberProfileEmail records the account signed in with the active profile.
+// It is only shown in the profile menu, so failing to save it is reported there.
+func rememberProfileEmail(email string) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	s := loadSettings()
+	i := s.find(s.ActiveProfile)
+	if s.Profiles[i].Email == email {
+		return
+	}
+	s.Profiles[i].Email = email
+	if err := s.save(); err != nil {
+		s.problem = fmt.Sprintf("Failed to save settings: %v", err)
+	}
+}
+
+type ProfileInfo struct {
+	Name   string `json:"name"`
+	ApiUrl string `json:"apiUrl"`
+	Email  string `json:"email"`
+	Active bool   `json:"active"`
+}
+
+// ListProfilesResult has the profiles even when there is an error, such as a settings file that could not be read
+type ListProfilesResult struct {
+	Profiles []ProfileInfo `json:"profiles"`
+	Error    string        `json:"error"`
+}
+
+func (a *App) ListProfiles() ListProfilesResult {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	s := loadSettings()
+	profiles := make([]ProfileInfo, len(s.Profiles))
+	for i, profile := range s.Profiles {
+		apiUrl := profile.ApiUrl
+		if apiUrl == "" {
+			apiUrl = defaultAPIURL()
+		}
+		profiles[i] = ProfileInfo{
+			Name:   profile.Name,
+			ApiUrl: apiUrl,
+			Email:  profile.Email,
+			Active: profile.Name == s.ActiveProfile,
+		}
+	}
+	return ListProfilesResult{
+		Profiles: profiles,
+		Error:    s.problem,
+	}
+}
+
+type SaveProfileResult struct {
+	Error string `json:"error"`
+}
+
+// SaveProfile adds a profile or changes the server of an existing one.
+// Changing the server signs the profile out, as its tokens belong to the old server.
+func (a *App) SaveProfile(name, apiUrl string) SaveProfileResult {
+	name = strings.TrimSpace(name)
+	apiUrl = strings.TrimRight(strings.TrimSpace(apiUrl), "/")
+	if name == "" {
+		return SaveProfileResult{
+			Error: "Profile name is required",
+		}
+	}
+	if apiUrl != "" {
+		u, err := url.Parse(apiUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return SaveProfileResult{
+				Error: "Server URL must start with http:// or https://",
+			}
+		}
+	} else if name != defaultProfileName {
+		return SaveProfileResult{
+			Error: "Server URL is required",
+		}
+	}
+	store, err := credentials()
+	if err != nil {
+		return SaveProfileResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	a.refreshMu.Lock()
+	defer a.refreshMu.Unlock()
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	s := loadSettings()
+	if i := s.find(name); i >= 0 {
+		if s.Profiles[i].ApiUrl == apiUrl {
+			return SaveProfileResult{}
+		}
+		if err := clearProfileTokens(store, name); err != nil {
+			return SaveProfileResult{
+				Error: fmt.Sprintf("Failed to sign out of the profile: %v", err),
+			}
+		}
+		s.Profiles[i] = Profile{Name: name, ApiUrl: apiUrl}
+	} else {
+		s.Profiles = append(s.Profiles, Profile{Name: name, ApiUrl: apiUrl})
+	}
+	if err := s.save(); err != nil {
+		return SaveProfileResult{
+			Error: fmt.Sprintf("Failed to save settings: %v", err),
+		}
+	}
+	return SaveProfileResult{
+		Error: "",
+	}
+}
+
+type SwitchProfileResult struct {
+	Error string `json:"error"`
+}
+
+// SwitchProfile makes requests go to the server and account of another profile
+func (a *App) SwitchProfile(name string) SwitchProfileResult {
+	// Wait for a refresh in progress, which would otherwise store its tokens in the new profile
+	a.refreshMu.Lock()
+	defer a.refreshMu.Unlock()
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	s := loadSettings()
+	if s.find(name) < 0 {
+		return SwitchProfileResult{
+			Error: fmt.Sprintf("Profile %q not found", name),
+		}
+	}
+	s.ActiveProfile = name
+	if err := s.save(); err != nil {
+		return SwitchProfileResult{
+			Error: fmt.Sprintf("Failed to save settings: %v", err),
+		}
+	}
+	return SwitchProfileResult{
+		Error: "",
+	}
+}
+
+type RemoveProfileResult struct {
+	Error string `json:"error"`
+}
+
+// RemoveProfile deletes a profile that is not in use together with its tokens
+func (a *App) RemoveProfile(name string) RemoveProfileResult {
+	store, err := credentials()
+	if err != nil {
+		return RemoveProfileResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	s := loadSettings()
+	i := s.find(name)
+	if i < 0 {
+		return RemoveProfileResult{
+			Error: fmt.Sprintf("Profile %q not found", name),
+		}
+	}
+	if name == s.ActiveProfile {
+		return RemoveProfileResult{
+			Error: "Switch to another profile before removing this one",
+		}
+	}
+	if err := clearProfileTokens(store, name); err != nil {
+		return RemoveProfileResult{
+			Error: fmt.Sprintf("Failed to sign out of the profile: %v", err),
+		}
+	}
+	s.Profiles = append(s.Profiles[:i], s.Profiles[i+1:]...)
+	if err := s.save(); err != nil {
+		return RemoveProfileResult{
+			Error: fmt.Sprintf("Failed to save settings: %v", err),
+		}
+	}
+	return RemoveProfileResult{
+		Error: "",
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/types"
+)
+
+// Names under which tokens are kept in the credential store
+const (
+	keyringService      = "Raxcel"
+	keyringAccessToken  = "raxcel-user"
+	keyringRefreshToken = "raxcel-refresh"
+)
+
+// refreshMargin is how long before expiry the access token is renewed
+const refreshMargin = time.Minute
+
+// storeTokens keeps the tokens of the active profile
+func storeTokens(accessToken, refreshToken string) error {
+	store, err := credentials()
+	if err != nil {
+		return err
+	}
+	profileName := activeProfile().Name
+	if err := store.Set(profileKey(profileName, keyringAccessToken), accessToken); err != nil {
+		return err
+	}
+	return store.Set(profileKey(profileName, keyringRefreshToken), refreshToken)
+}
+
+func loadToken(key string) (string, error) {
+	store, err := credentials()
+	if err != nil {
+		return "", err
+	}
+	return store.Get(profileKey(activeProfile().Name, key))
+}
+
+func clearTokens() error {
+	store, err := credentials()
+	if err != nil {
+		return err
+	}
+	return clearProfileTokens(store, activeProfile().Name)
+}
+
+// accessTokenExpiry reads the exp claim without verifying the signature,
+// which only the server can do
+func accessTokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}
+	}
+	return time.Unix(claims.ExpiresAt, 0)
+}
+
+// refreshAccessToken exchanges the stored refresh token for a new token pair.
+// staleToken is the access token the caller gave up on; if another call has
+// already replaced it, the newer token is returned without refreshing again,
+// because presenting the same refresh token twice revokes the session.
+func (a *App) refreshAccessToken(staleToken string) (string, error) {
+	a.refreshMu.Lock()
+	defer a.refreshMu.Unlock()
+
+	current, err := loadToken(keyringAccessToken)
+	if err != nil {
+		return "", err
+	}
+	if current != staleToken && time.Until(accessTokenExpiry(current)) > refreshMargin {
+		return current, nil
+	}
+	refreshToken, err := loadToken(keyringRefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("session expired, please sign in again")
+	}
+
+	jsonData, err := json.Marshal(types.RefreshRequest{
+		RefreshToken: refreshToken,
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.Post(fmt.Sprintf("%s/auth/refresh", getAPIURL()), "application/json", bytes.NewReader(jsonData))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var serverResponse types.RefreshResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return "", fmt.Errorf("Failed to parse response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusUnauthorized {
+			clearTokens()
+			return "", fmt.Errorf("session expired, please sign in again")
+		}
+		return "", fmt.Errorf("%s", serverResponse.Error)
+	}
+	if err := storeTokens(serverResponse.Token, serverResponse.RefreshToken); err != nil {
+		return "", fmt.Errorf("Failed to store token: %v", err)
+	}
+	return serverResponse.Token, nil
+}
+
+// accessToken returns a stored access token that is not about to expire
+func (a *App) accessToken() (string, error) {
+	token, err := loadToken(keyringAccessToken)
+	if err != nil {
+		return "", err
+	}
+	if time.Until(accessTokenExpiry(token)) > refreshMargin {
+		return token, nil
+	}
+	return a.refreshAccessToken(token)
+}
+
+// sendAuthorized sends a request with the stored access token, refreshing it
+// beforehand when it is about to expire and retrying once after a 401
+func (a *App) sendAuthorized(method, url string, body []byte) (*http.Response, error) {
+	return a.sendAuthorizedContext(context.Background(), method, url, body)
+}
+
+// sendAuthorizedContext is sendAuthorized for requests that can be cancelled
+func (a *App) sendAuthorizedContext(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	token, err := a.accessToken()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sendWithToken(ctx, method, url, body, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+	token, err = a.refreshAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return sendWithToken(ctx, method, url, body, token)
+}
+
+// postSignin posts to an endpoint that starts a session, naming this device
+// so it can be recognized in the session list
+func postSignin(url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hostname, err := os.Hostname(); err == nil {
+		req.Header.Set("X-Device-Name", hostname)
+	}
+	client := &http.Client{}
+	return client.Do(req)
+}
+
+func sendWithToken(ctx context.Context, method, url string, body []byte, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	client := &http.Client{}
+	return client.Do(req)
+}
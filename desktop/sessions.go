@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/ut-code/Raxcel/server/types"
+)
+
+type ListSessionsResult struct {
+	Sessions []types.SessionInfo `json:"sessions"`
+	Error    string              `json:"error"`
+}
+
+// ListSessions returns the devices currently signed in to the account
+func (a *App) ListSessions() ListSessionsResult {
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("GET", fmt.Sprintf("%s/users/me/sessions", apiUrl), nil)
+	if err != nil {
+		return ListSessionsResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ListSessionsResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.ListSessionsResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ListSessionsResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return ListSessionsResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return ListSessionsResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return ListSessionsResult{
+		Sessions: serverResponse.Sessions,
+		Error:    "",
+	}
+}
+
+type RevokeSessionResult struct {
+	Error string `json:"error"`
+}
+
+// RevokeSession signs out another device
+func (a *App) RevokeSession(sessionId string) RevokeSessionResult {
+	apiUrl := getAPIURL()
+	return a.revokeSessions(fmt.Sprintf("%s/users/me/sessions/%s", apiUrl, url.PathEscape(sessionId)))
+}
+
+// SignOutEverywhere revokes every session, including this one, and forgets the stored tokens
+func (a *App) SignOutEverywhere() RevokeSessionResult {
+	apiUrl := getAPIURL()
+	result := a.revokeSessions(fmt.Sprintf("%s/users/me/sessions", apiUrl))
+	if result.Error != "" {
+		return result
+	}
+	if err := clearTokens(); err != nil {
+		return RevokeSessionResult{
+			Error: fmt.Sprintf("Failed to sign out: %v", err),
+		}
+	}
+	return result
+}
+
+func (a *App) revokeSessions(endpoint string) RevokeSessionResult {
+	resp, err := a.sendAuthorized("DELETE", endpoint, nil)
+	if err != nil {
+		return RevokeSessionResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return RevokeSessionResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.RevokeSessionResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return RevokeSessionResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return RevokeSessionResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return RevokeSessionResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return RevokeSessionResult{
+		Error: "",
+	}
+}
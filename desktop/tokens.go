@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/types"
+)
+
+type PersonalAccessToken struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     string     `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func toPersonalAccessToken(token db.PersonalAccessToken) PersonalAccessToken {
+	return PersonalAccessToken{
+		Id:         token.Id,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.Scopes,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+type CreatePersonalAccessTokenResult struct {
+	// Token is shown to the user once and never stored by the app
+	Token               string              `json:"token"`
+	PersonalAccessToken PersonalAccessToken `json:"personalAccessToken"`
+	Error               string              `json:"error"`
+}
+
+// CreatePersonalAccessToken creates a token for scripts. expiresInDays of 0 means no expiry.
+func (a *App) CreatePersonalAccessToken(name string, scopes []string, expiresInDays int) CreatePersonalAccessTokenResult {
+	jsonData, err := json.Marshal(types.CreatePersonalAccessTokenRequest{
+		Name:          name,
+		Scopes:        scopes,
+		ExpiresInDays: expiresInDays,
+	})
+	if err != nil {
+		return CreatePersonalAccessTokenResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("POST", fmt.Sprintf("%s/users/me/tokens", apiUrl), jsonData)
+	if err != nil {
+		return CreatePersonalAccessTokenResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return CreatePersonalAccessTokenResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.CreatePersonalAccessTokenResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return CreatePersonalAccessTokenResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return CreatePersonalAccessTokenResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return CreatePersonalAccessTokenResult{
+			Error: serverResponse.Error,
+		}
+	}
+	if serverResponse.PersonalAccessToken == nil {
+		return CreatePersonalAccessTokenResult{
+			Error: "Failed to parse response: missing token",
+		}
+	}
+	return CreatePersonalAccessTokenResult{
+		Token:               serverResponse.Token,
+		PersonalAccessToken: toPersonalAccessToken(*serverResponse.PersonalAccessToken),
+		Error:               "",
+	}
+}
+
+type ListPersonalAccessTokensResult struct {
+	PersonalAccessTokens []PersonalAccessToken `json:"personalAccessTokens"`
+	Error                string                `json:"error"`
+}
+
+func (a *App) ListPersonalAccessTokens() ListPersonalAccessTokensResult {
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("GET", fmt.Sprintf("%s/users/me/tokens", apiUrl), nil)
+	if err != nil {
+		return ListPersonalAccessTokensResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ListPersonalAccessTokensResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.ListPersonalAccessTokensResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ListPersonalAccessTokensResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return ListPersonalAccessTokensResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return ListPersonalAccessTokensResult{
+			Error: serverResponse.Error,
+		}
+	}
+	tokens := make([]PersonalAccessToken, len(serverResponse.PersonalAccessTokens))
+	for i, token := range serverResponse.PersonalAccessTokens {
+		tokens[i] = toPersonalAccessToken(token)
+	}
+	return ListPersonalAccessTokensResult{
+		PersonalAccessTokens: tokens,
+		Error:                "",
+	}
+}
+
+type RevokePersonalAccessTokenResult struct {
+	Error string `json:"error"`
+}
+
+func (a *App) RevokePersonalAccessToken(tokenId string) RevokePersonalAccessTokenResult {
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("DELETE", fmt.Sprintf("%s/users/me/tokens/%s", apiUrl, url.PathEscape(tokenId)), nil)
+	if err != nil {
+		return RevokePersonalAccessTokenResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return RevokePersonalAccessTokenResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.RevokePersonalAccessTokenResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return RevokePersonalAccessTokenResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return RevokePersonalAccessTokenResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return RevokePersonalAccessTokenResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return RevokePersonalAccessTokenResult{
+		Error: "",
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ut-code/Raxcel/server/types"
+)
+
+type SetupTwoFactorResult struct {
+	Secret     string `json:"secret"`
+	OtpauthUrl string `json:"otpauthUrl"`
+	Error      string `json:"error"`
+}
+
+// SetupTwoFactor starts enrollment. The secret is added to an authenticator app,
+// then ConfirmTwoFactor is called with a code from it. The password is empty for
+// accounts without one.
+func (a *App) SetupTwoFactor(password string) SetupTwoFactorResult {
+	jsonData, err := json.Marshal(types.SetupTwoFactorRequest{
+		Password: password,
+	})
+	if err != nil {
+		return SetupTwoFactorResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("POST", fmt.Sprintf("%s/users/me/2fa/setup", apiUrl), jsonData)
+	if err != nil {
+		return SetupTwoFactorResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return SetupTwoFactorResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.SetupTwoFactorResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return SetupTwoFactorResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return SetupTwoFactorResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return SetupTwoFactorResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return SetupTwoFactorResult{
+		Secret:     serverResponse.Secret,
+		OtpauthUrl: serverResponse.OtpauthUrl,
+		Error:      "",
+	}
+}
+
+type ConfirmTwoFactorResult struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+	Error         string   `json:"error"`
+}
+
+// ConfirmTwoFactor enables 2FA and returns the recovery codes, which are shown only once
+func (a *App) ConfirmTwoFactor(code string) ConfirmTwoFactorResult {
+	jsonData, err := json.Marshal(types.ConfirmTwoFactorRequest{
+		Code: code,
+	})
+	if err != nil {
+		return ConfirmTwoFactorResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("POST", fmt.Sprintf("%s/users/me/2fa/confirm", apiUrl), jsonData)
+	if err != nil {
+		return ConfirmTwoFactorResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ConfirmTwoFactorResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.ConfirmTwoFactorResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return ConfirmTwoFactorResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return ConfirmTwoFactorResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return ConfirmTwoFactorResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return ConfirmTwoFactorResult{
+		RecoveryCodes: serverResponse.RecoveryCodes,
+		Error:         "",
+	}
+}
+
+type DisableTwoFactorResult struct {
+	Error string `json:"error"`
+}
+
+func (a *App) DisableTwoFactor(password, code string) DisableTwoFactorResult {
+	jsonData, err := json.Marshal(types.DisableTwoFactorRequest{
+		Password: password,
+		Code:     code,
+	})
+	if err != nil {
+		return DisableTwoFactorResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	apiUrl := getAPIURL()
+	resp, err := a.sendAuthorized("POST", fmt.Sprintf("%s/users/me/2fa/disable", apiUrl), jsonData)
+	if err != nil {
+		return DisableTwoFactorResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return DisableTwoFactorResult{
+			Error: fmt.Sprint(err),
+		}
+	}
+	var serverResponse types.DisableTwoFactorResponse
+	if err := json.Unmarshal(body, &serverResponse); err != nil {
+		return DisableTwoFactorResult{
+			Error: fmt.Sprintf("Failed to parse response: %v", err),
+		}
+	}
+	if serverResponse.AuthMiddlewareReturn != nil && serverResponse.MiddlewareError != "" {
+		return DisableTwoFactorResult{
+			Error: serverResponse.MiddlewareError,
+		}
+	}
+	if serverResponse.Error != "" {
+		return DisableTwoFactorResult{
+			Error: serverResponse.Error,
+		}
+	}
+	return DisableTwoFactorResult{
+		Error: "",
+	}
+}
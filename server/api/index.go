@@ -2,35 +2,115 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
 	middleware "github.com/ut-code/Raxcel/server/middlewares"
 	"github.com/ut-code/Raxcel/server/routes"
 )
 
 func SetupRouter() *echo.Echo {
 	router := echo.New()
+	router.IPExtractor = middleware.ClientIPExtractor()
 
 	router.GET("/", routes.Greet)
+	router.GET("/.well-known/jwks.json", routes.JWKS)
 
 	messageGroup := router.Group("/messages")
 	{
 		messageGroup.Use(middleware.AuthMiddleware)
-		messageGroup.POST("", routes.ChatWithAI)
-		messageGroup.GET("", routes.LoadChatHistory)
+		messageGroup.POST("", routes.ChatWithAI, middleware.RequireScope(middleware.ScopeMessagesWrite))
+		messageGroup.POST("/stream", routes.StreamChatWithAI, middleware.RequireScope(middleware.ScopeMessagesWrite))
+		messageGroup.GET("", routes.LoadChatHistory, middleware.RequireScope(middleware.ScopeMessagesRead))
 	}
 
+	conversationGroup := router.Group("/conversations")
+	{
+		conversationGroup.Use(middleware.AuthMiddleware)
+		conversationGroup.GET("", routes.ListConversations, middleware.RequireScope(middleware.ScopeMessagesRead))
+		conversationGroup.POST("", routes.CreateConversation, middleware.RequireScope(middleware.ScopeMessagesWrite))
+		conversationGroup.GET("/:id", routes.GetConversation, middleware.RequireScope(middleware.ScopeMessagesRead))
+		conversationGroup.PATCH("/:id", routes.UpdateConversation, middleware.RequireScope(middleware.ScopeMessagesWrite))
+		conversationGroup.DELETE("/:id", routes.DeleteConversation, middleware.RequireScope(middleware.ScopeMessagesWrite))
+	}
+
+	// 10 requests per IP in a burst, then one every 6 seconds
+	ipLimit := middleware.RateLimit(middleware.NewLimiter("auth-ip", 10, 6*time.Second), middleware.KeyByIP)
+	// 5 signins per account in a burst, then one per minute
+	emailLimit := middleware.RateLimit(middleware.NewLimiter("signin-email", 5, time.Minute), middleware.KeyByEmail)
+	// The app polls for a magic link every 3 seconds, so polls get their own bucket per IP
+	pollLimit := middleware.RateLimit(middleware.NewLimiter("magic-link-poll", 10, 2*time.Second), middleware.KeyByIP)
+
 	authGroup := router.Group("/auth")
 	{
-		authGroup.POST("/signup", routes.Signup)
-		authGroup.POST("/signin", routes.Signin)
+		authGroup.POST("/signup", routes.Signup, ipLimit)
+		authGroup.POST("/signin", routes.Signin, ipLimit, emailLimit)
+		authGroup.POST("/2fa/verify", routes.VerifyTwoFactor, ipLimit)
+		authGroup.POST("/oidc/start", routes.StartOIDC, ipLimit)
+		authGroup.POST("/oidc/callback", routes.OIDCCallback, ipLimit)
+		authGroup.POST("/magic-link", routes.RequestMagicLink, ipLimit, emailLimit)
+		authGroup.GET("/magic-link/verify", routes.ShowMagicLink)
+		authGroup.POST("/magic-link/verify", routes.ApproveMagicLink, ipLimit)
+		authGroup.POST("/magic-link/complete", routes.CompleteMagicLink, pollLimit)
+		authGroup.POST("/refresh", routes.RefreshToken)
 		authGroup.GET("/verify-email", routes.VerifyEmail)
+		authGroup.POST("/verify-email/resend", routes.ResendVerificationFromPage, ipLimit)
+		authGroup.GET("/confirm-email-change", routes.ConfirmEmailChange)
+		authGroup.POST("/resend-verification", routes.ResendVerification, ipLimit)
+		authGroup.POST("/forgot-password", routes.ForgotPassword, ipLimit)
+		authGroup.POST("/reset-password", routes.ResetPassword, ipLimit)
 	}
 
 	userGroup := router.Group("/users")
 	{
-		userGroup.Use(middleware.AuthMiddleware)
+		// Personal access tokens cannot manage the account
+		userGroup.Use(middleware.AuthMiddleware, middleware.RequireSession)
 		userGroup.GET("/me", routes.GetCurrentUser)
+		userGroup.DELETE("/me", routes.DeleteAccount)
+		userGroup.POST("/me/password", routes.ChangePassword)
+		userGroup.POST("/me/email", routes.ChangeEmail)
+		userGroup.POST("/me/2fa/setup", routes.SetupTwoFactor)
+		userGroup.POST("/me/2fa/confirm", routes.ConfirmTwoFactor)
+		userGroup.POST("/me/2fa/disable", routes.DisableTwoFactor)
+		userGroup.GET("/me/sessions", routes.ListSessions)
+		userGroup.DELETE("/me/sessions", routes.RevokeAllSessions)
+		userGroup.DELETE("/me/sessions/:id", routes.RevokeSession)
+		userGroup.GET("/me/tokens", routes.ListPersonalAccessTokens)
+		userGroup.POST("/me/tokens", routes.CreatePersonalAccessToken)
+		userGroup.DELETE("/me/tokens/:id", routes.RevokePersonalAccessToken)
+	}
+
+	orgGroup := router.Group("/orgs")
+	{
+		orgGroup.Use(middleware.AuthMiddleware, middleware.RequireSession)
+		orgGroup.GET("", routes.ListOrganizations)
+		orgGroup.POST("", routes.CreateOrganization)
+		orgGroup.POST("/invitations/accept", routes.AcceptInvitation)
+		orgGroup.POST("/invitations/decline", routes.DeclineInvitation)
+		orgGroup.GET("/:id", routes.GetOrganization)
+		orgGroup.PATCH("/:id", routes.UpdateOrganization)
+		orgGroup.DELETE("/:id", routes.DeleteOrganization)
+		orgGroup.GET("/:id/usage", routes.GetOrganizationUsage)
+		orgGroup.PATCH("/:id/members/:userId", routes.UpdateMember)
+		orgGroup.DELETE("/:id/members/:userId", routes.RemoveMember)
+		orgGroup.GET("/:id/invitations", routes.ListInvitations)
+		orgGroup.POST("/:id/invitations", routes.CreateInvitation)
+		orgGroup.DELETE("/:id/invitations/:invitationId", routes.RevokeInvitation)
+	}
+
+	adminGroup := router.Group("/admin")
+	{
+		adminGroup.Use(middleware.AuthMiddleware, middleware.RequireSession, middleware.RequireRole(db.RoleAdmin))
+		adminGroup.GET("/users", routes.AdminListUsers)
+		adminGroup.GET("/users/:id", routes.AdminGetUser)
+		adminGroup.DELETE("/users/:id", routes.AdminDeleteUser)
+		adminGroup.GET("/users/:id/usage", routes.AdminUserUsage)
+		adminGroup.POST("/users/:id/verify", routes.AdminVerifyUser)
+		adminGroup.POST("/users/:id/disable", routes.AdminDisableUser)
+		adminGroup.POST("/users/:id/enable", routes.AdminEnableUser)
+		adminGroup.POST("/users/:id/role", routes.AdminSetRole)
+		adminGroup.DELETE("/users/:id/messages", routes.AdminResetChatHistory)
 	}
 
 	return router
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/db/dbtest"
+	middleware "github.com/ut-code/Raxcel/server/middlewares"
+	"github.com/ut-code/Raxcel/server/utils"
+)
+
+// Routes under these prefixes manage the account, so personal access tokens are kept out
+var sessionOnlyPrefixes = []string{"/users", "/orgs", "/admin"}
+
+func TestPersonalAccessTokensCannotReachSessionRoutes(t *testing.T) {
+	t.Setenv("RATE_LIMIT_STORE", "")
+	t.Setenv("VERCEL", "")
+	database := dbtest.New(t)
+	user := db.User{Id: "user-1", Email: "alice@example.com", IsVerified: true, Role: db.RoleAdmin}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	token := middleware.PersonalAccessTokenPrefix + "all-scopes-0123456789"
+	if err := database.Create(&db.PersonalAccessToken{
+		Id:        "token-1",
+		UserId:    user.Id,
+		Name:      "all scopes",
+		TokenHash: utils.HashToken(token),
+		Prefix:    token[:8],
+		Scopes:    strings.Join(middleware.Scopes, " "),
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	router := SetupRouter()
+	request := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	checked := 0
+	for _, route := range router.Routes() {
+		for _, prefix := range sessionOnlyPrefixes {
+			if !strings.HasPrefix(route.Path, prefix) {
+				continue
+			}
+			checked++
+			path := strings.NewReplacer(":id", "id-1", ":userId", "user-1", ":invitationId", "invitation-1").Replace(route.Path)
+			if got := request(route.Method, path); got != http.StatusForbidden {
+				t.Errorf("%s %s: status = %d, want %d", route.Method, route.Path, got, http.StatusForbidden)
+			}
+		}
+	}
+	if checked == 0 {
+		t.Fatal("no session-only routes found")
+	}
+
+	// The token still reaches the routes its scopes allow
+	if got := request(http.MethodGet, "/conversations"); got != http.StatusOK {
+		t.Errorf("GET /conversations: status = %d, want %d", got, http.StatusOK)
+	}
+}
@@ -8,9 +8,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// Dialector opens DATABASE_URL with Postgres. Tests replace it to run against another database.
+var Dialector = func() gorm.Dialector {
+	return postgres.Open(os.Getenv("DATABASE_URL"))
+}
+
 func ConnectDB() (*gorm.DB, error) {
-	url := os.Getenv("DATABASE_URL")
-	db, err := gorm.Open(postgres.Open(url), &gorm.Config{
+	db, err := gorm.Open(Dialector(), &gorm.Config{
 		TranslateError: true,
 	})
 	if err != nil {
@@ -0,0 +1,39 @@
+// Package dbtest runs tests against SQLite in place of Postgres
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/ut-code/Raxcel/server/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// New opens a database with the tables of every model. db.ConnectDB opens it as
+// well until the test ends, so handlers and middlewares can be called directly.
+func New(t testing.TB) *gorm.DB {
+	t.Helper()
+	// Handlers open their own connections, which wait for each other's writes
+	path := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	database, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(db.Models()...); err != nil {
+		t.Fatal(err)
+	}
+	dialector := db.Dialector
+	db.Dialector = func() gorm.Dialector { return sqlite.Open(path) }
+	t.Cleanup(func() {
+		db.Dialector = dialector
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return database
+}
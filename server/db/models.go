@@ -12,30 +12,212 @@ type User struct {
 	CreatedAt    time.Time `json:"createdAt" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
 	IsVerified   bool      `json:"isVerified"`
-	Tokens       []Token   `json:"tokens,omitempty" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
-	Messages     []Message `json:"messages,omitempty" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+	Role         string    `json:"role" gorm:"not null;default:user"`
+	// DisabledAt is set by an administrator; disabled users cannot sign in
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+	// Consecutive wrong passwords, reset by a successful signin
+	FailedSigninCount int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil       *time.Time `json:"-"`
+	// TotpSecret is set during enrollment and only used once TotpEnabled
+	TotpSecret  string `json:"-"`
+	TotpEnabled bool   `json:"totpEnabled" gorm:"not null;default:false"`
+	// TotpLastStep is the time step of the last accepted code, which cannot be used again
+	TotpLastStep  int64                 `json:"-" gorm:"not null;default:0"`
+	RecoveryCodes []RecoveryCode        `json:"-" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+	Identities    []Identity            `json:"identities,omitempty" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+	Sessions      []Session             `json:"sessions,omitempty" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+	AccessTokens  []PersonalAccessToken `json:"-" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+	Tokens        []Token               `json:"tokens,omitempty" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+	Messages      []Message             `json:"messages,omitempty" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+	Conversations []Conversation        `json:"conversations,omitempty" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
 }
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	TokenTypeVerification  = "verification"
+	TokenTypeRefresh       = "refresh"
+	TokenTypePasswordReset = "password_reset"
+	// Issued after the password check when the account has 2FA enabled
+	TokenTypeTwoFactorChallenge = "two_factor_challenge"
+	// Sent to the new address of an email change, which is kept in Data
+	TokenTypeEmailChange = "email_change"
+	// Emailed for passwordless signin. PendingIdHash is the hash of the id the app
+	// polls with, Data holds where the link was requested from, and UsedAt is set
+	// once the link has been opened.
+	TokenTypeMagicLink = "magic_link"
+)
+
 type Token struct {
-	Id        string    `json:"id" gorm:"primaryKey"`
-	UserId    string    `json:"userId" gorm:"not null;index"`
-	Token     string    `json:"token" gorm:"unique;not null"`
+	Id     string `json:"id" gorm:"primaryKey"`
+	UserId string `json:"userId" gorm:"not null;index"`
+	// Refresh tokens are stored as a SHA-256 hash, never in plain text
+	Token string `json:"token" gorm:"unique;not null"`
+	Type  string `json:"type" gorm:"not null;default:verification;index"`
+	// FamilyId groups every refresh token rotated from the same signin; it is the session id
+	FamilyId string `json:"familyId,omitempty" gorm:"index"`
+	// PendingIdHash is the hash of the pending id a magic link is completed with
+	PendingIdHash string     `json:"-" gorm:"index"`
+	UsedAt        *time.Time `json:"usedAt,omitempty"`
+	// Data holds what the token type needs besides the user, such as a pending email
+	Data      string    `json:"-"`
 	ExpiresAt time.Time `json:"expiresAt" gorm:"not null"`
 	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
 }
 
+// Message is one chat message. The history is paged by created_at, so it is
+// indexed together with the user and with the conversation.
 type Message struct {
+	Id     string `json:"id" gorm:"primaryKey"`
+	UserId string `json:"userId" gorm:"not null;index;index:idx_messages_user_id_created_at,priority:1"`
+	// ConversationId is the thread the message belongs to
+	ConversationId *string `json:"conversationId,omitempty" gorm:"index;index:idx_messages_conversation_id_created_at,priority:1"`
+	// OrganizationId is set when the message counts towards an organization's chat quota
+	OrganizationId *string   `json:"organizationId,omitempty" gorm:"index"`
+	Content        string    `json:"content" gorm:"not null"`
+	Role           string    `json:"role" gorm:"not null"` // "user" or "assistant"
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime;index:idx_messages_user_id_created_at,priority:2;index:idx_messages_conversation_id_created_at,priority:2"`
+}
+
+// Conversation is a chat thread of a user. The model only sees the messages of the
+// thread being answered.
+type Conversation struct {
+	Id     string `json:"id" gorm:"primaryKey"`
+	UserId string `json:"userId" gorm:"not null;index"`
+	// Title is generated from the first exchange unless the user has named the thread
+	Title string `json:"title" gorm:"not null;default:''"`
+	// Workbook names the workbook file the thread is about, empty when there is none
+	Workbook  string    `json:"workbook,omitempty" gorm:"not null;default:''"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	// UpdatedAt moves forward with every message, so recent threads are listed first
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	// Summary condenses the messages sent up to SummarizedUntil, which no longer
+	// fit the history sent to the model
+	Summary         string     `json:"-" gorm:"not null;default:''"`
+	SummarizedUntil *time.Time `json:"-"`
+	Messages        []Message  `json:"-" gorm:"foreignKey:ConversationId;constraint:OnDelete:CASCADE"`
+}
+
+// Session is one signed-in device. Its id is the jti claim of the access tokens
+// and the family id of the refresh tokens issued to that device.
+type Session struct {
+	Id         string     `json:"id" gorm:"primaryKey"`
+	UserId     string     `json:"userId" gorm:"not null;index"`
+	DeviceName string     `json:"deviceName"`
+	IpAddress  string     `json:"ipAddress"`
+	UserAgent  string     `json:"userAgent"`
+	LastSeenAt time.Time  `json:"lastSeenAt" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expiresAt" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// PersonalAccessToken lets scripts call the API without signing in.
+// Only the hash is stored; the token is shown once when it is created.
+type PersonalAccessToken struct {
+	Id        string `json:"id" gorm:"primaryKey"`
+	UserId    string `json:"userId" gorm:"not null;index"`
+	Name      string `json:"name" gorm:"not null"`
+	TokenHash string `json:"-" gorm:"unique;not null"`
+	// Prefix is the start of the token, shown to tell tokens apart
+	Prefix string `json:"prefix" gorm:"not null"`
+	// Scopes is a space separated list such as "messages:read messages:write"
+	Scopes     string     `json:"scopes" gorm:"not null"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// Organization groups users who share resources such as a chat quota
+type Organization struct {
+	Id   string `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"not null"`
+	// ChatQuota is the number of chat messages the members may send per calendar month; 0 means unlimited
+	ChatQuota   int          `json:"chatQuota" gorm:"not null;default:0"`
+	CreatedAt   time.Time    `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time    `json:"updatedAt" gorm:"autoUpdateTime"`
+	Memberships []Membership `json:"-" gorm:"foreignKey:OrganizationId;constraint:OnDelete:CASCADE"`
+	Invitations []Invitation `json:"-" gorm:"foreignKey:OrganizationId;constraint:OnDelete:CASCADE"`
+	Messages    []Message    `json:"-" gorm:"foreignKey:OrganizationId;constraint:OnDelete:SET NULL"`
+	ChatUsages  []ChatUsage  `json:"-" gorm:"foreignKey:OrganizationId;constraint:OnDelete:CASCADE"`
+}
+
+// ChatUsage records a chat message sent on behalf of an organization, once it has been
+// answered. Rows are only ever added, so deleting messages or conversations does not
+// give the quota back.
+type ChatUsage struct {
+	Id             string    `json:"id" gorm:"primaryKey"`
+	OrganizationId string    `json:"organizationId" gorm:"not null;index:idx_chat_usages_organization_id_created_at,priority:1"`
+	UserId         string    `json:"userId" gorm:"not null"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime;index:idx_chat_usages_organization_id_created_at,priority:2"`
+}
+
+type Membership struct {
+	Id             string    `json:"id" gorm:"primaryKey"`
+	OrganizationId string    `json:"organizationId" gorm:"not null;uniqueIndex:idx_memberships_organization_user"`
+	UserId         string    `json:"userId" gorm:"not null;uniqueIndex:idx_memberships_organization_user;index"`
+	Role           string    `json:"role" gorm:"not null;default:member"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// Invitation is sent to an email address that may not have an account yet, so it
+// cannot be a Token, whose UserId is required and deleted with the user. The token
+// is hashed with utils.HashToken like the other tokens.
+type Invitation struct {
+	Id             string    `json:"id" gorm:"primaryKey"`
+	OrganizationId string    `json:"organizationId" gorm:"not null;index"`
+	Email          string    `json:"email" gorm:"not null;index"`
+	Role           string    `json:"role" gorm:"not null;default:member"`
+	TokenHash      string    `json:"-" gorm:"unique;not null"`
+	InvitedBy      string    `json:"invitedBy" gorm:"not null"`
+	ExpiresAt      time.Time `json:"expiresAt" gorm:"not null"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// RecoveryCode replaces a TOTP code once when the authenticator is lost
+type RecoveryCode struct {
+	Id        string     `json:"id" gorm:"primaryKey"`
+	UserId    string     `json:"userId" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"unique;not null"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// Identity links an account at an external OpenID Connect provider to a user
+type Identity struct {
 	Id        string    `json:"id" gorm:"primaryKey"`
 	UserId    string    `json:"userId" gorm:"not null;index"`
-	Content   string    `json:"content" gorm:"not null"`
-	Role      string    `json:"role" gorm:"not null"` // "user" or "assistant"
+	Issuer    string    `json:"issuer" gorm:"not null;uniqueIndex:idx_identities_issuer_subject"`
+	Subject   string    `json:"subject" gorm:"not null;uniqueIndex:idx_identities_issuer_subject"`
+	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
 }
 
+// RateLimitBucket is a token bucket shared between server instances
+type RateLimitBucket struct {
+	Id        string    `json:"id" gorm:"primaryKey"`
+	Tokens    float64   `json:"tokens" gorm:"not null"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"not null"`
+}
+
+// Models are the tables that Migrate creates
+func Models() []any {
+	return []any{&User{}, &Token{}, &Conversation{}, &Message{}, &RecoveryCode{}, &Identity{}, &Session{}, &PersonalAccessToken{}, &Organization{}, &Membership{}, &Invitation{}, &ChatUsage{}, &RateLimitBucket{}}
+}
+
 func Migrate() {
 	db, err := ConnectDB()
 	if err != nil {
 		log.Fatal("failed to connect db")
 	}
-	db.AutoMigrate(&User{}, &Token{}, &Message{})
+	db.AutoMigrate(Models()...)
 }
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"strings"
+)
+
+// FakeProvider answers without a model, for tests and offline development.
+// The answer only depends on the request, so the same request always gets the same answer:
+// "You said: " and the last message. When the request offers tools and the last message is
+// "call <tool> <json arguments>", it calls that tool instead.
+type FakeProvider struct{}
+
+func (FakeProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	return fakeAnswer(req), nil
+}
+
+// Stream yields the text one word at a time
+func (FakeProvider) Stream(ctx context.Context, req Request) iter.Seq2[Response, error] {
+	return func(yield func(Response, error) bool) {
+		answer := fakeAnswer(req)
+		if len(answer.ToolCalls) > 0 {
+			yield(answer, nil)
+			return
+		}
+		for _, word := range strings.SplitAfter(answer.Text, " ") {
+			if err := ctx.Err(); err != nil {
+				yield(Response{}, err)
+				return
+			}
+			if !yield(Response{Text: word}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func fakeAnswer(req Request) Response {
+	var last string
+	if len(req.Messages) > 0 {
+		last = req.Messages[len(req.Messages)-1].Content
+	}
+	if rest, ok := strings.CutPrefix(last, "call "); ok && len(req.Tools) > 0 {
+		name, arguments, _ := strings.Cut(rest, " ")
+		if json.Valid([]byte(arguments)) {
+			return Response{
+				ToolCalls: []ToolCall{{Name: name, Arguments: json.RawMessage(arguments)}},
+			}
+		}
+	}
+	return Response{Text: "You said: " + last}
+}
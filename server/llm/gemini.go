@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+
+	"google.golang.org/genai"
+)
+
+const defaultGeminiModel = "gemini-2.5-flash"
+
+type GeminiProvider struct {
+	client  *genai.Client
+	options Options
+}
+
+func NewGeminiProvider(ctx context.Context, apiKey string, options Options) (*GeminiProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY is not set")
+	}
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey: apiKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &GeminiProvider{client: client, options: options}, nil
+}
+
+func (p *GeminiProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	contents, config := p.content(req)
+	result, err := p.client.Models.GenerateContent(ctx, p.options.Model, contents, config)
+	if err != nil {
+		return Response{}, err
+	}
+	return geminiResponse(result)
+}
+
+func (p *GeminiProvider) Stream(ctx context.Context, req Request) iter.Seq2[Response, error] {
+	contents, config := p.content(req)
+	return func(yield func(Response, error) bool) {
+		for result, err := range p.client.Models.GenerateContentStream(ctx, p.options.Model, contents, config) {
+			if err != nil {
+				yield(Response{}, err)
+				return
+			}
+			piece, err := geminiResponse(result)
+			if err != nil {
+				yield(Response{}, err)
+				return
+			}
+			if piece.Text == "" && len(piece.ToolCalls) == 0 {
+				continue
+			}
+			if !yield(piece, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (p *GeminiProvider) content(req Request) ([]*genai.Content, *genai.GenerateContentConfig) {
+	var contents []*genai.Content
+	for _, m := range req.Messages {
+		role := genai.Role(genai.RoleUser)
+		if m.Role == RoleAssistant {
+			role = genai.RoleModel
+		}
+		contents = append(contents, genai.NewContentFromText(m.Content, role))
+	}
+	config := &genai.GenerateContentConfig{
+		MaxOutputTokens: int32(p.options.MaxTokens),
+	}
+	if req.System != "" {
+		config.SystemInstruction = genai.NewContentFromText(req.System, genai.RoleUser)
+	}
+	if p.options.Temperature != nil {
+		temperature := float32(*p.options.Temperature)
+		config.Temperature = &temperature
+	}
+	if p.options.TopP != nil {
+		topP := float32(*p.options.TopP)
+		config.TopP = &topP
+	}
+	if len(req.Tools) > 0 {
+		var declarations []*genai.FunctionDeclaration
+		for _, tool := range req.Tools {
+			declarations = append(declarations, &genai.FunctionDeclaration{
+				Name:                 tool.Name,
+				Description:          tool.Description,
+				ParametersJsonSchema: tool.Parameters,
+			})
+		}
+		config.Tools = []*genai.Tool{{FunctionDeclarations: declarations}}
+	}
+	return contents, config
+}
+
+// geminiResponse collects the text and function calls of the first candidate,
+// skipping thoughts
+func geminiResponse(result *genai.GenerateContentResponse) (Response, error) {
+	var response Response
+	if result == nil || len(result.Candidates) == 0 || result.Candidates[0].Content == nil {
+		return response, nil
+	}
+	for _, part := range result.Candidates[0].Content.Parts {
+		if part.Text != "" && !part.Thought {
+			response.Text += part.Text
+		}
+		if part.FunctionCall != nil {
+			arguments, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil {
+				return response, err
+			}
+			response.ToolCalls = append(response.ToolCalls, ToolCall{
+				Name:      part.FunctionCall.Name,
+				Arguments: arguments,
+			})
+		}
+	}
+	return response, nil
+}
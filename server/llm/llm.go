@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"os"
+	"strconv"
+)
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of the conversation sent to the model
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Tool is a function the model may call instead of, or besides, answering in text
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON Schema of the arguments, an object schema
+	Parameters map[string]any
+}
+
+// ToolCall is a call the model made. The caller decides what to do with it;
+// results are not sent back to the model.
+type ToolCall struct {
+	Name string
+	// Arguments is the JSON object of arguments
+	Arguments json.RawMessage
+}
+
+// Request is what the model answers: instructions and the conversation so far,
+// ending with the message to answer
+type Request struct {
+	System   string
+	Messages []Message
+	Tools    []Tool
+}
+
+// Response is an answer, or one piece of a streamed answer
+type Response struct {
+	Text      string
+	ToolCalls []ToolCall
+}
+
+// Options are the generation parameters shared by every provider.
+// Nil and zero values leave the provider's default.
+type Options struct {
+	Model       string
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+}
+
+// Provider generates answers with a language model
+type Provider interface {
+	// Generate returns the whole answer
+	Generate(ctx context.Context, req Request) (Response, error)
+	// Stream yields the answer in pieces as they are generated. Text arrives in
+	// order across pieces, while each tool call arrives whole.
+	Stream(ctx context.Context, req Request) iter.Seq2[Response, error]
+}
+
+// New returns the provider selected by LLM_PROVIDER (gemini, openai, ollama or fake)
+func New(ctx context.Context) (Provider, error) {
+	options, err := optionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	switch provider := os.Getenv("LLM_PROVIDER"); provider {
+	case "", "gemini":
+		options.Model = envOr("GEMINI_MODEL", defaultGeminiModel)
+		return NewGeminiProvider(ctx, os.Getenv("GEMINI_API_KEY"), options)
+	case "openai":
+		options.Model = envOr("OPENAI_MODEL", defaultOpenAIModel)
+		return NewOpenAIProvider(envOr("OPENAI_BASE_URL", defaultOpenAIBaseURL), os.Getenv("OPENAI_API_KEY"), options), nil
+	case "ollama":
+		options.Model = envOr("OLLAMA_MODEL", defaultOllamaModel)
+		return NewOllamaProvider(envOr("OLLAMA_URL", defaultOllamaURL), options), nil
+	case "fake":
+		return FakeProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown LLM_PROVIDER %q", provider)
+	}
+}
+
+func optionsFromEnv() (Options, error) {
+	var options Options
+	if value := os.Getenv("LLM_TEMPERATURE"); value != "" {
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return options, fmt.Errorf("invalid LLM_TEMPERATURE %q", value)
+		}
+		options.Temperature = &temperature
+	}
+	if value := os.Getenv("LLM_TOP_P"); value != "" {
+		topP, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return options, fmt.Errorf("invalid LLM_TOP_P %q", value)
+		}
+		options.TopP = &topP
+	}
+	if value := os.Getenv("LLM_MAX_TOKENS"); value != "" {
+		maxTokens, err := strconv.Atoi(value)
+		if err != nil || maxTokens < 0 {
+			return options, fmt.Errorf("invalid LLM_MAX_TOKENS %q", value)
+		}
+		options.MaxTokens = maxTokens
+	}
+	return options, nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// clearEnv unsets the variables New reads, so the tests do not depend on the shell
+func clearEnv(t *testing.T) {
+	for _, key := range []string{
+		"LLM_PROVIDER", "LLM_TEMPERATURE", "LLM_TOP_P", "LLM_MAX_TOKENS",
+		"GEMINI_API_KEY", "GEMINI_MODEL", "OPENAI_BASE_URL", "OPENAI_API_KEY", "OPENAI_MODEL", "OLLAMA_URL", "OLLAMA_MODEL",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestNewSelectsProvider(t *testing.T) {
+	tests := []struct {
+		provider string
+		env      map[string]string
+		check    func(t *testing.T, provider Provider)
+	}{
+		{
+			provider: "",
+			env:      map[string]string{"GEMINI_API_KEY": "test-key"},
+			check: func(t *testing.T, provider Provider) {
+				gemini, ok := provider.(*GeminiProvider)
+				if !ok || gemini.options.Model != defaultGeminiModel {
+					t.Errorf("New = %#v, want Gemini with %s", provider, defaultGeminiModel)
+				}
+			},
+		},
+		{
+			provider: "openai",
+			env:      map[string]string{"OPENAI_BASE_URL": "http://localhost:8000/v1/", "OPENAI_API_KEY": "key", "OPENAI_MODEL": "local-model"},
+			check: func(t *testing.T, provider Provider) {
+				openAI, ok := provider.(*OpenAIProvider)
+				if !ok || openAI.baseURL != "http://localhost:8000/v1" || openAI.apiKey != "key" || openAI.options.Model != "local-model" {
+					t.Errorf("New = %#v", provider)
+				}
+			},
+		},
+		{
+			provider: "ollama",
+			check: func(t *testing.T, provider Provider) {
+				ollama, ok := provider.(*OllamaProvider)
+				if !ok || ollama.baseURL != defaultOllamaURL || ollama.options.Model != defaultOllamaModel {
+					t.Errorf("New = %#v, want Ollama with the defaults", provider)
+				}
+			},
+		},
+		{
+			provider: "fake",
+			check: func(t *testing.T, provider Provider) {
+				if _, ok := provider.(FakeProvider); !ok {
+					t.Errorf("New = %#v, want FakeProvider", provider)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("LLM_PROVIDER", tt.provider)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			provider, err := New(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, provider)
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"unknown provider", map[string]string{"LLM_PROVIDER": "claude"}, `unknown LLM_PROVIDER "claude"`},
+		{"gemini without a key", map[string]string{}, "GEMINI_API_KEY is not set"},
+		{"temperature", map[string]string{"LLM_PROVIDER": "fake", "LLM_TEMPERATURE": "warm"}, `invalid LLM_TEMPERATURE "warm"`},
+		{"top p", map[string]string{"LLM_PROVIDER": "fake", "LLM_TOP_P": "high"}, `invalid LLM_TOP_P "high"`},
+		{"max tokens", map[string]string{"LLM_PROVIDER": "fake", "LLM_MAX_TOKENS": "many"}, `invalid LLM_MAX_TOKENS "many"`},
+		{"negative max tokens", map[string]string{"LLM_PROVIDER": "fake", "LLM_MAX_TOKENS": "-1"}, `invalid LLM_MAX_TOKENS "-1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			_, err := New(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New = %v, want an error containing %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewParsesOptions(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("LLM_PROVIDER", "ollama")
+	t.Setenv("LLM_TEMPERATURE", "0.2")
+	t.Setenv("LLM_TOP_P", "0.9")
+	t.Setenv("LLM_MAX_TOKENS", "512")
+	provider, err := New(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := provider.(*OllamaProvider).options
+	if options.Temperature == nil || *options.Temperature != 0.2 {
+		t.Errorf("Temperature = %v, want 0.2", options.Temperature)
+	}
+	if options.TopP == nil || *options.TopP != 0.9 {
+		t.Errorf("TopP = %v, want 0.9", options.TopP)
+	}
+	if options.MaxTokens != 512 {
+		t.Errorf("MaxTokens = %d, want 512", options.MaxTokens)
+	}
+}
@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOllamaURL   = "http://localhost:11434"
+	defaultOllamaModel = "llama3.2"
+)
+
+// OllamaProvider talks to a local Ollama server
+type OllamaProvider struct {
+	baseURL string
+	options Options
+}
+
+func NewOllamaProvider(baseURL string, options Options) *OllamaProvider {
+	return &OllamaProvider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		options: options,
+	}
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name string `json:"name"`
+		// Arguments is a JSON object, unlike the string OpenAI sends
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	// Ollama takes tools in the OpenAI format
+	Tools   []openAITool  `json:"tools,omitempty"`
+	Options ollamaOptions `json:"options"`
+	Stream  bool          `json:"stream"`
+}
+
+type ollamaResponse struct {
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+	Error   string        `json:"error"`
+}
+
+func (p *OllamaProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	res, err := p.post(ctx, req, false)
+	if err != nil {
+		return Response{}, err
+	}
+	defer res.Body.Close()
+	var body ollamaResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return Response{}, err
+	}
+	if body.Error != "" {
+		return Response{}, errors.New("ollama: " + body.Error)
+	}
+	return ollamaPiece(body.Message), nil
+}
+
+func ollamaPiece(message ollamaMessage) Response {
+	piece := Response{Text: message.Content}
+	for _, call := range message.ToolCalls {
+		piece.ToolCalls = append(piece.ToolCalls, ToolCall{
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return piece
+}
+
+// Stream reads the streamed answer, which Ollama sends as one JSON object per line
+func (p *OllamaProvider) Stream(ctx context.Context, req Request) iter.Seq2[Response, error] {
+	return func(yield func(Response, error) bool) {
+		res, err := p.post(ctx, req, true)
+		if err != nil {
+			yield(Response{}, err)
+			return
+		}
+		defer res.Body.Close()
+		scanner := bufio.NewScanner(res.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var chunk ollamaResponse
+			if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+				yield(Response{}, err)
+				return
+			}
+			if chunk.Error != "" {
+				yield(Response{}, errors.New("ollama: "+chunk.Error))
+				return
+			}
+			piece := ollamaPiece(chunk.Message)
+			if (piece.Text != "" || len(piece.ToolCalls) > 0) && !yield(piece, nil) {
+				return
+			}
+			if chunk.Done {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(Response{}, err)
+		}
+	}
+}
+
+func (p *OllamaProvider) post(ctx context.Context, req Request, stream bool) (*http.Response, error) {
+	var messages []ollamaMessage
+	if req.System != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		messages = append(messages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+	payload, err := json.Marshal(ollamaRequest{
+		Model:    p.options.Model,
+		Messages: messages,
+		Tools:    openAITools(req.Tools),
+		Options: ollamaOptions{
+			Temperature: p.options.Temperature,
+			TopP:        p.options.TopP,
+			NumPredict:  p.options.MaxTokens,
+		},
+		Stream: stream,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, statusError("ollama", res)
+	}
+	return res, nil
+}
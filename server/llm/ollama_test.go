@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllamaStream(t *testing.T) {
+	var received ollamaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		for _, line := range []string{
+			`{"message":{"role":"assistant","content":"Adding "},"done":false}`,
+			`{"message":{"role":"assistant","content":"rows."},"done":false}`,
+			"",
+			// Ollama sends each tool call whole, with arguments as an object
+			`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"insert_rows","arguments":{"row":2,"count":3}}}]},"done":false}`,
+			`{"message":{"role":"assistant","content":""},"done":true}`,
+			`{"message":{"role":"assistant","content":"after done"},"done":false}`,
+		} {
+			fmt.Fprintln(w, line)
+		}
+	}))
+	defer server.Close()
+
+	topP := 0.8
+	provider := NewOllamaProvider(server.URL+"/", Options{Model: "llama3.2", TopP: &topP, MaxTokens: 64})
+	got := collect(t, provider, Request{
+		System:   "Be brief.",
+		Messages: []Message{{Role: RoleUser, Content: "Insert rows"}},
+		Tools:    []Tool{{Name: "insert_rows", Parameters: map[string]any{"type": "object"}}},
+	})
+
+	if got.Text != "Adding rows." {
+		t.Errorf("text = %q, want %q", got.Text, "Adding rows.")
+	}
+	if len(got.ToolCalls) != 1 || got.ToolCalls[0].Name != "insert_rows" {
+		t.Fatalf("tool calls = %+v", got.ToolCalls)
+	}
+	var arguments struct{ Row, Count int }
+	if err := json.Unmarshal(got.ToolCalls[0].Arguments, &arguments); err != nil || arguments.Row != 2 || arguments.Count != 3 {
+		t.Errorf("arguments = %s", got.ToolCalls[0].Arguments)
+	}
+
+	if !received.Stream || received.Model != "llama3.2" || received.Options.NumPredict != 64 || received.Options.TopP == nil || *received.Options.TopP != 0.8 {
+		t.Errorf("request = %+v", received)
+	}
+	if len(received.Messages) != 2 || received.Messages[0].Role != "system" || len(received.Tools) != 1 {
+		t.Errorf("request = %+v", received)
+	}
+}
+
+func TestOllamaStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Hel"},"done":false}`)
+		fmt.Fprintln(w, `{"error":"model crashed"}`)
+	}))
+	defer server.Close()
+
+	var text string
+	var lastErr error
+	for piece, err := range NewOllamaProvider(server.URL, Options{}).Stream(context.Background(), Request{}) {
+		text += piece.Text
+		lastErr = err
+	}
+	if text != "Hel" || lastErr == nil || !strings.Contains(lastErr.Error(), "model crashed") {
+		t.Errorf("text = %q, err = %v", text, lastErr)
+	}
+}
+
+func TestOllamaGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":"Hello"},"done":true}`)
+	}))
+	defer server.Close()
+
+	got, err := NewOllamaProvider(server.URL, Options{}).Generate(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "Hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "Hello" || len(got.ToolCalls) != 0 {
+		t.Errorf("Generate = %+v", got)
+	}
+}
@@ -0,0 +1,233 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o-mini"
+)
+
+// OpenAIProvider talks to any server with the OpenAI chat completions API,
+// such as OpenAI itself, vLLM, LM Studio or OpenRouter
+type OpenAIProvider struct {
+	baseURL string
+	apiKey  string
+	options Options
+}
+
+func NewOpenAIProvider(baseURL, apiKey string, options Options) *OpenAIProvider {
+	return &OpenAIProvider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		options: options,
+	}
+}
+
+type openAIMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+type openAIToolCall struct {
+	// Index tells which call a streamed piece of arguments belongs to
+	Index    int    `json:"index"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name string `json:"name,omitempty"`
+		// Arguments is a JSON object encoded as a string
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Tools       []openAITool    `json:"tools,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	TopP        *float64        `json:"top_p,omitempty"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Stream      bool            `json:"stream,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+func (p *OpenAIProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	res, err := p.post(ctx, req, false)
+	if err != nil {
+		return Response{}, err
+	}
+	defer res.Body.Close()
+	var body openAIResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return Response{}, err
+	}
+	if len(body.Choices) == 0 {
+		return Response{}, nil
+	}
+	message := body.Choices[0].Message
+	return Response{
+		Text:      message.Content,
+		ToolCalls: openAIToolCalls(message.ToolCalls),
+	}, nil
+}
+
+// Stream reads the Server-Sent Events of a streamed completion, which end with "data: [DONE]".
+// Tool calls arrive in pieces and are yielded once the model has finished.
+func (p *OpenAIProvider) Stream(ctx context.Context, req Request) iter.Seq2[Response, error] {
+	return func(yield func(Response, error) bool) {
+		res, err := p.post(ctx, req, true)
+		if err != nil {
+			yield(Response{}, err)
+			return
+		}
+		defer res.Body.Close()
+		var calls []openAIToolCall
+		flushCalls := func() bool {
+			if len(calls) == 0 {
+				return true
+			}
+			piece := Response{ToolCalls: openAIToolCalls(calls)}
+			calls = nil
+			return yield(piece, nil)
+		}
+		scanner := bufio.NewScanner(res.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			data = strings.TrimSpace(data)
+			if data == "[DONE]" {
+				flushCalls()
+				return
+			}
+			var chunk openAIResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				yield(Response{}, err)
+				return
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			choice := chunk.Choices[0]
+			for _, delta := range choice.Delta.ToolCalls {
+				for len(calls) <= delta.Index {
+					calls = append(calls, openAIToolCall{Index: len(calls)})
+				}
+				calls[delta.Index].Function.Name += delta.Function.Name
+				calls[delta.Index].Function.Arguments += delta.Function.Arguments
+			}
+			if choice.Delta.Content != "" && !yield(Response{Text: choice.Delta.Content}, nil) {
+				return
+			}
+			if choice.FinishReason != "" && !flushCalls() {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(Response{}, err)
+			return
+		}
+		flushCalls()
+	}
+}
+
+func openAITools(tools []Tool) []openAITool {
+	var openAITools []openAITool
+	for _, tool := range tools {
+		t := openAITool{Type: "function"}
+		t.Function.Name = tool.Name
+		t.Function.Description = tool.Description
+		t.Function.Parameters = tool.Parameters
+		openAITools = append(openAITools, t)
+	}
+	return openAITools
+}
+
+func openAIToolCalls(calls []openAIToolCall) []ToolCall {
+	var toolCalls []ToolCall
+	for _, call := range calls {
+		arguments := call.Function.Arguments
+		if arguments == "" {
+			arguments = "{}"
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			Name:      call.Function.Name,
+			Arguments: json.RawMessage(arguments),
+		})
+	}
+	return toolCalls
+}
+
+func (p *OpenAIProvider) post(ctx context.Context, req Request, stream bool) (*http.Response, error) {
+	var messages []openAIMessage
+	if req.System != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		messages = append(messages, openAIMessage{Role: m.Role, Content: m.Content})
+	}
+	payload, err := json.Marshal(openAIRequest{
+		Model:       p.options.Model,
+		Messages:    messages,
+		Tools:       openAITools(req.Tools),
+		Temperature: p.options.Temperature,
+		TopP:        p.options.TopP,
+		MaxTokens:   p.options.MaxTokens,
+		Stream:      stream,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	res, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, statusError("openai", res)
+	}
+	return res, nil
+}
+
+// statusError describes a failed request with the start of the response body,
+// where the providers put the reason
+func statusError(provider string, res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return fmt.Errorf("%s: %s: %s", provider, res.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// collect reads a stream into its text and tool calls
+func collect(t *testing.T, provider Provider, req Request) Response {
+	t.Helper()
+	var all Response
+	for piece, err := range provider.Stream(context.Background(), req) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		all.Text += piece.Text
+		all.ToolCalls = append(all.ToolCalls, piece.ToolCalls...)
+	}
+	return all
+}
+
+func TestOpenAIStream(t *testing.T) {
+	var received openAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"delta":{"role":"assistant","content":"Hello"}}]}`,
+			`{"choices":[{"delta":{"content":", world"}}]}`,
+			// Arguments of two calls arrive interleaved, in pieces
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"type":"function","function":{"name":"set_value","arguments":"{\"cell\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":1,"type":"function","function":{"name":"insert_rows","arguments":""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"B3\",\"value\":\"10\"}"}}]}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	temperature := 0.5
+	provider := NewOpenAIProvider(server.URL+"/v1/", "key", Options{Model: "test-model", Temperature: &temperature, MaxTokens: 100})
+	got := collect(t, provider, Request{
+		System:   "Be brief.",
+		Messages: []Message{{Role: RoleUser, Content: "Hi"}},
+		Tools:    []Tool{{Name: "set_value", Parameters: map[string]any{"type": "object"}}},
+	})
+
+	if got.Text != "Hello, world" {
+		t.Errorf("text = %q, want %q", got.Text, "Hello, world")
+	}
+	if len(got.ToolCalls) != 2 {
+		t.Fatalf("got %d tool calls, want 2: %+v", len(got.ToolCalls), got.ToolCalls)
+	}
+	if got.ToolCalls[0].Name != "set_value" || string(got.ToolCalls[0].Arguments) != `{"cell":"B3","value":"10"}` {
+		t.Errorf("first call = %s %s", got.ToolCalls[0].Name, got.ToolCalls[0].Arguments)
+	}
+	// A call without arguments gets an empty object
+	if got.ToolCalls[1].Name != "insert_rows" || string(got.ToolCalls[1].Arguments) != "{}" {
+		t.Errorf("second call = %s %s", got.ToolCalls[1].Name, got.ToolCalls[1].Arguments)
+	}
+
+	if !received.Stream || received.Model != "test-model" || received.MaxTokens != 100 || received.Temperature == nil || *received.Temperature != 0.5 {
+		t.Errorf("request = %+v", received)
+	}
+	if len(received.Messages) != 2 || received.Messages[0].Role != "system" || received.Messages[1].Content != "Hi" {
+		t.Errorf("messages = %+v", received.Messages)
+	}
+	if len(received.Tools) != 1 || received.Tools[0].Type != "function" || received.Tools[0].Function.Name != "set_value" {
+		t.Errorf("tools = %+v", received.Tools)
+	}
+}
+
+func TestOpenAIGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Done","tool_calls":[{"index":0,"type":"function","function":{"name":"create_chart","arguments":"{\"range\":\"A1:B3\"}"}}]}}]}`)
+	}))
+	defer server.Close()
+
+	got, err := NewOpenAIProvider(server.URL, "", Options{}).Generate(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "Chart it"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "Done" || len(got.ToolCalls) != 1 || got.ToolCalls[0].Name != "create_chart" || string(got.ToolCalls[0].Arguments) != `{"range":"A1:B3"}` {
+		t.Errorf("Generate = %+v", got)
+	}
+}
+
+func TestOpenAIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"invalid api key"}}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(server.URL, "wrong", Options{})
+	_, err := provider.Generate(context.Background(), Request{})
+	if err == nil || !strings.Contains(err.Error(), "invalid api key") {
+		t.Errorf("Generate = %v, want the reason of the provider", err)
+	}
+	for _, err := range provider.Stream(context.Background(), Request{}) {
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("Stream = %v, want the status", err)
+		}
+	}
+}
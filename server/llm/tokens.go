@@ -0,0 +1,18 @@
+package llm
+
+import "unicode/utf8"
+
+// EstimateTokens guesses how many tokens text takes, without the model's tokenizer.
+// Tokenizers differ between models, so this is only close: about four characters
+// per token for English and code, and one token per character for scripts such as Japanese.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
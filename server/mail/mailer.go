@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+const defaultFrom = "Raxcel <noreply@raxcel.utcode.net>"
+
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Mailer delivers a rendered message
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by MAIL_BACKEND (resend, smtp, outbox or memory)
+func New() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = defaultFrom
+	}
+	switch backend := os.Getenv("MAIL_BACKEND"); backend {
+	case "", "resend":
+		return NewResendMailer(os.Getenv("RESEND_API_KEY"), from), nil
+	case "smtp":
+		return NewSMTPMailer(os.Getenv("SMTP_ADDR"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	case "outbox":
+		dir := os.Getenv("MAIL_OUTBOX_DIR")
+		if dir == "" {
+			dir = "outbox"
+		}
+		return NewOutboxMailer(dir, from), nil
+	case "memory":
+		return DefaultMemoryMailer, nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_BACKEND %q", backend)
+	}
+}
+
+// Send renders the template in the given language and delivers it with the configured mailer
+func Send(ctx context.Context, to string, tmpl Template, lang string, data any) error {
+	msg, err := Compose(to, tmpl, lang, data)
+	if err != nil {
+		return err
+	}
+	mailer, err := New()
+	if err != nil {
+		return err
+	}
+	return mailer.Send(ctx, msg)
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultMemoryMailer is the recorder used when MAIL_BACKEND=memory
+var DefaultMemoryMailer = &MemoryMailer{}
+
+// MemoryMailer keeps sent messages in memory for tests
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of every message sent so far
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
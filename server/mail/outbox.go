@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMailer writes every message as an .eml file instead of sending it,
+// so emails can be opened locally without any mail service
+type OutboxMailer struct {
+	dir  string
+	from string
+}
+
+func NewOutboxMailer(dir, from string) *OutboxMailer {
+	return &OutboxMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (m *OutboxMailer) Send(ctx context.Context, msg Message) error {
+	data, err := buildMIME(m.from, msg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create outbox: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), uuid.New().String())
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o644)
+}
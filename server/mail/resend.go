@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/resend/resend-go/v3"
+)
+
+type ResendMailer struct {
+	client *resend.Client
+	from   string
+}
+
+func NewResendMailer(apiKey, from string) *ResendMailer {
+	return &ResendMailer{
+		client: resend.NewClient(apiKey),
+		from:   from,
+	}
+}
+
+func (m *ResendMailer) Send(ctx context.Context, msg Message) error {
+	params := &resend.SendEmailRequest{
+		From:    m.from,
+		To:      []string{msg.To},
+		Subject: msg.Subject,
+		Html:    msg.HTML,
+		Text:    msg.Text,
+	}
+	_, err := m.client.Emails.SendWithContext(ctx, params)
+	return err
+}
@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+)
+
+// SMTPMailer sends through an SMTP server, such as MailHog during development
+type SMTPMailer struct {
+	addr     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(addr, username, password, from string) *SMTPMailer {
+	if addr == "" {
+		addr = "localhost:1025"
+	}
+	return &SMTPMailer{
+		addr:     addr,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send delivers the message like smtp.SendMail, but gives up when ctx is done,
+// so a server that does not answer cannot hold the request
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := buildMIME(m.from, msg)
+	if err != nil {
+		return err
+	}
+	sender, err := envelopeAddress(m.from)
+	if err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(m.addr)
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	// Closing the connection interrupts the exchange wherever it is
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if err := m.exchange(conn, host, sender, msg.To, data); err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// exchange runs the SMTP conversation over conn and closes it
+func (m *SMTPMailer) exchange(conn net.Conn, host, sender, to string, data []byte) error {
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(sender); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,114 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveSMTP answers one SMTP session on listener and returns the data of the message
+func serveSMTP(listener net.Listener) <-chan string {
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ready")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case command == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return received
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := serveSMTP(listener)
+
+	mailer := NewSMTPMailer(listener.Addr().String(), "", "", defaultFrom)
+	err = mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hello", Text: "Hi Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-received:
+		if !strings.Contains(data, "To: alice@example.com") || !strings.Contains(data, "Hi Alice") {
+			t.Errorf("unexpected message:\n%s", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server received no message")
+	}
+}
+
+func TestSMTPMailerSendHonorsContext(t *testing.T) {
+	// A server that accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewSMTPMailer(listener.Addr().String(), "", "", defaultFrom).Send(ctx, Message{To: "alice@example.com", Text: "Hi"})
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Send = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send did not return when the context ended")
+	}
+}
@@ -0,0 +1,88 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"slices"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+type Template string
+
+const (
+	TemplateVerification  Template = "verification"
+	TemplatePasswordReset Template = "password_reset"
+	TemplateEmailChange   Template = "email_change"
+	TemplateInvitation    Template = "invitation"
+	TemplateMagicLink     Template = "magic_link"
+)
+
+const defaultLanguage = "en"
+
+var supportedLanguages = []string{"en", "ja"}
+
+// Compose renders a template into a message. Each template has a .txt file
+// that also defines the "subject" block and an .html file for the body.
+func Compose(to string, tmpl Template, lang string, data any) (Message, error) {
+	if !isSupported(lang) {
+		lang = defaultLanguage
+	}
+	base := "templates/" + lang + "/" + string(tmpl)
+
+	text, err := texttemplate.ParseFS(templateFS, base+".txt")
+	if err != nil {
+		return Message{}, err
+	}
+	var subject, textBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return Message{}, err
+	}
+
+	html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", base+".html")
+	if err != nil {
+		return Message{}, err
+	}
+	var htmlBody bytes.Buffer
+	if err := html.ExecuteTemplate(&htmlBody, "layout", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    htmlBody.String(),
+		Text:    strings.TrimSpace(textBody.String()) + "\n",
+	}, nil
+}
+
+// Language picks the supported language the client prefers most from an Accept-Language header
+func Language(acceptLanguage string) string {
+	best, bestQuality := defaultLanguage, 0.0
+	for _, entry := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if isSupported(primary) && quality > bestQuality {
+			best, bestQuality = primary, quality
+		}
+	}
+	return best
+}
+
+func isSupported(lang string) bool {
+	return slices.Contains(supportedLanguages, lang)
+}
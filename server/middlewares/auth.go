@@ -3,11 +3,16 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
 	"github.com/ut-code/Raxcel/server/utils"
+	"gorm.io/gorm"
 )
 
+const lastSeenResolution = time.Minute
+
 type AuthMiddlewareReturn struct {
 	MiddlewareError string `json:"middlewareError"`
 }
@@ -28,14 +33,59 @@ func AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			})
 		}
 
-		userId, err := utils.ValidateJWT(tokenString)
+		database, err := db.ConnectDB()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, AuthMiddlewareReturn{
+				MiddlewareError: "failed to connect to database",
+			})
+		}
+		if strings.HasPrefix(tokenString, PersonalAccessTokenPrefix) {
+			return authenticatePersonalAccessToken(c, next, database, tokenString)
+		}
+
+		userId, sessionId, err := utils.ValidateJWT(tokenString)
 		if err != nil {
 			return c.JSON(http.StatusUnauthorized, AuthMiddlewareReturn{
 				MiddlewareError: "invalid token",
 			})
 		}
+		var session db.Session
+		if err := database.Where("id = ? AND user_id = ?", sessionId, userId).First(&session).Error; err != nil || session.RevokedAt != nil {
+			return c.JSON(http.StatusUnauthorized, AuthMiddlewareReturn{
+				MiddlewareError: "session has been revoked",
+			})
+		}
+		// Writing on every request is unnecessary for a "last seen" display
+		if time.Since(session.LastSeenAt) > lastSeenResolution {
+			database.Model(&session).Update("last_seen_at", time.Now())
+		}
 
 		c.Set("userId", userId)
+		c.Set("sessionId", sessionId)
 		return next(c)
 	}
 }
+
+// authenticatePersonalAccessToken accepts a token created under /users/me/tokens.
+// Its scopes are stored in the context for RequireScope.
+func authenticatePersonalAccessToken(c echo.Context, next echo.HandlerFunc, database *gorm.DB, tokenString string) error {
+	var token db.PersonalAccessToken
+	if err := database.Where("token_hash = ?", utils.HashToken(tokenString)).First(&token).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, AuthMiddlewareReturn{
+			MiddlewareError: "invalid token",
+		})
+	}
+	if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+		return c.JSON(http.StatusUnauthorized, AuthMiddlewareReturn{
+			MiddlewareError: "token has expired",
+		})
+	}
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > lastSeenResolution {
+		database.Model(&token).Update("last_used_at", time.Now())
+	}
+
+	c.Set("userId", token.UserId)
+	c.Set("tokenId", token.Id)
+	c.Set("scopes", strings.Fields(token.Scopes))
+	return next(c)
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RateLimitReturn struct {
+	Error string `json:"error"`
+}
+
+// Limiter is a set of token buckets that each hold up to burst tokens and
+// regain one token every interval
+type Limiter interface {
+	// Allow takes a token from the bucket for key. When the bucket is empty
+	// it returns false and how long to wait for the next token.
+	Allow(key string) (bool, time.Duration, error)
+}
+
+// NewLimiter returns a limiter backed by RATE_LIMIT_STORE (memory or postgres).
+// Serverless deployments default to postgres because memory does not survive between invocations.
+func NewLimiter(name string, burst int, interval time.Duration) Limiter {
+	store := os.Getenv("RATE_LIMIT_STORE")
+	if store == "" && os.Getenv("VERCEL") != "" {
+		store = "postgres"
+	}
+	if store == "postgres" {
+		return NewPostgresLimiter(name, burst, interval)
+	}
+	return NewMemoryLimiter(burst, interval)
+}
+
+// takeToken refills a bucket for the time elapsed since last and takes one token from it
+func takeToken(tokens float64, last, now time.Time, burst int, interval time.Duration) (float64, bool, time.Duration) {
+	tokens = math.Min(float64(burst), tokens+float64(now.Sub(last))/float64(interval))
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	return tokens, false, time.Duration((1 - tokens) * float64(interval))
+}
+
+type memoryBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryLimiter keeps buckets in process memory, which is enough for a single server
+type MemoryLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*memoryBucket
+	burst    int
+	interval time.Duration
+}
+
+func NewMemoryLimiter(burst int, interval time.Duration) *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:  make(map[string]*memoryBucket),
+		burst:    burst,
+		interval: interval,
+	}
+}
+
+const maxMemoryBuckets = 10000
+
+func (l *MemoryLimiter) Allow(key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if len(l.buckets) >= maxMemoryBuckets {
+		// Full buckets carry no state, so they can be dropped
+		full := time.Duration(l.burst) * l.interval
+		for k, b := range l.buckets {
+			if now.Sub(b.last) >= full {
+				delete(l.buckets, k)
+			}
+		}
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	tokens, allowed, retryAfter := takeToken(b.tokens, b.last, now, l.burst, l.interval)
+	b.tokens, b.last = tokens, now
+	return allowed, retryAfter, nil
+}
+
+// PostgresLimiter keeps buckets in the rate_limit_buckets table so every instance shares them
+type PostgresLimiter struct {
+	name     string
+	burst    int
+	interval time.Duration
+}
+
+func NewPostgresLimiter(name string, burst int, interval time.Duration) *PostgresLimiter {
+	return &PostgresLimiter{
+		name:     name,
+		burst:    burst,
+		interval: interval,
+	}
+}
+
+func (l *PostgresLimiter) Allow(key string) (bool, time.Duration, error) {
+	database, err := db.ConnectDB()
+	if err != nil {
+		return false, 0, err
+	}
+	id := l.name + ":" + key
+	var allowed bool
+	var retryAfter time.Duration
+	err = database.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&db.RateLimitBucket{
+			Id:        id,
+			Tokens:    float64(l.burst),
+			UpdatedAt: now,
+		}).Error; err != nil {
+			return err
+		}
+		var bucket db.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&bucket).Error; err != nil {
+			return err
+		}
+		var tokens float64
+		tokens, allowed, retryAfter = takeToken(bucket.Tokens, bucket.UpdatedAt, now, l.burst, l.interval)
+		return tx.Model(&bucket).Updates(map[string]any{
+			"tokens":     tokens,
+			"updated_at": now,
+		}).Error
+	})
+	return allowed, retryAfter, err
+}
+
+// KeyByIP identifies the client by its address, as found by ClientIPExtractor
+func KeyByIP(c echo.Context) string {
+	return c.RealIP()
+}
+
+// ClientIPExtractor finds the client address without trusting headers the client can set.
+// On Vercel, whose edge overwrites X-Forwarded-For with the client address, that header is used.
+// Elsewhere X-Forwarded-For is only read from the proxies listed in TRUSTED_PROXIES, as
+// comma separated CIDR ranges, and the address of the connection is used without them.
+func ClientIPExtractor() echo.IPExtractor {
+	if os.Getenv("VERCEL") != "" {
+		return func(req *http.Request) string {
+			client, _, _ := strings.Cut(req.Header.Get(echo.HeaderXForwardedFor), ",")
+			if client = strings.TrimSpace(client); client != "" {
+				return client
+			}
+			return echo.ExtractIPDirect()(req)
+		}
+	}
+	var proxies []echo.TrustOption
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Printf("Ignoring invalid TRUSTED_PROXIES entry %q: %v", proxy, err)
+			continue
+		}
+		proxies = append(proxies, echo.TrustIPRange(ipNet))
+	}
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	// Only the listed proxies, not every private address
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	return echo.ExtractIPFromXFFHeader(append(options, proxies...)...)
+}
+
+// KeyByEmail identifies the account named by the "email" field of a JSON body.
+// The body is restored so the handler can still bind it.
+func KeyByEmail(c echo.Context) string {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return ""
+	}
+	c.Request().Body = io.NopCloser(bytes.NewReader(body))
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(req.Email))
+}
+
+// RateLimit rejects requests with 429 once the bucket picked by keyFunc is empty
+func RateLimit(limiter Limiter, keyFunc func(c echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := keyFunc(c)
+			if key == "" {
+				return next(c)
+			}
+			allowed, retryAfter, err := limiter.Allow(key)
+			if err != nil {
+				// Fail open so a database hiccup does not lock everyone out
+				log.Printf("Rate limiter error: %v", err)
+				return next(c)
+			}
+			if !allowed {
+				return TooManyRequests(c, retryAfter, "too many requests")
+			}
+			return next(c)
+		}
+	}
+}
+
+// TooManyRequests responds with 429 and a Retry-After header in whole seconds
+func TooManyRequests(c echo.Context, retryAfter time.Duration, message string) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return c.JSON(http.StatusTooManyRequests, RateLimitReturn{
+		Error: fmt.Sprintf("%s, try again in %d seconds", message, seconds),
+	})
+}
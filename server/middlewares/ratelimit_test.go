@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/db/dbtest"
+)
+
+func TestTakeToken(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name       string
+		tokens     float64
+		elapsed    time.Duration
+		wantTokens float64
+		allowed    bool
+		retryAfter time.Duration
+	}{
+		{"full bucket", 3, 0, 2, true, 0},
+		{"last token", 1, 0, 0, true, 0},
+		{"empty bucket", 0, 0, 0, false, 10 * time.Second},
+		{"half refilled", 0, 5 * time.Second, 0.5, false, 5 * time.Second},
+		{"one refilled", 0, 10 * time.Second, 0, true, 0},
+		// A bucket never holds more than the burst
+		{"idle for long", 0, time.Hour, 2, true, 0},
+	}
+	for _, tt := range tests {
+		tokens, allowed, retryAfter := takeToken(tt.tokens, start, start.Add(tt.elapsed), 3, 10*time.Second)
+		if tokens != tt.wantTokens || allowed != tt.allowed || retryAfter != tt.retryAfter {
+			t.Errorf("%s: takeToken = %v, %v, %v, want %v, %v, %v", tt.name, tokens, allowed, retryAfter, tt.wantTokens, tt.allowed, tt.retryAfter)
+		}
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	limiter := NewMemoryLimiter(3, 50*time.Millisecond)
+	for i := range 3 {
+		if allowed, _, _ := limiter.Allow("a"); !allowed {
+			t.Fatalf("request %d of the burst was rejected", i+1)
+		}
+	}
+	allowed, retryAfter, _ := limiter.Allow("a")
+	if allowed || retryAfter <= 0 || retryAfter > 50*time.Millisecond {
+		t.Errorf("after the burst: allowed = %v, retry after %v", allowed, retryAfter)
+	}
+	// Buckets are per key
+	if allowed, _, _ := limiter.Allow("b"); !allowed {
+		t.Error("another key was rejected")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if allowed, _, _ := limiter.Allow("a"); !allowed {
+		t.Error("rejected after a token was refilled")
+	}
+	if allowed, _, _ := limiter.Allow("a"); allowed {
+		t.Error("more than the refilled token was allowed")
+	}
+}
+
+func TestPostgresLimiterConcurrent(t *testing.T) {
+	database := dbtest.New(t)
+	limiter := NewPostgresLimiter("test", 5, time.Hour)
+
+	const requests = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, _, err := limiter.Allow("client")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				allowed++
+			}
+		}()
+	}
+	wg.Wait()
+	// Every request took its token from the same bucket, so exactly the burst got through
+	if allowed != 5 {
+		t.Errorf("%d of %d requests allowed, want 5", allowed, requests)
+	}
+	var bucket db.RateLimitBucket
+	if err := database.First(&bucket, "id = ?", "test:client").Error; err != nil {
+		t.Fatal(err)
+	}
+	if bucket.Tokens >= 1 {
+		t.Errorf("bucket has %v tokens left", bucket.Tokens)
+	}
+	// Other limiters and keys have their own buckets
+	if ok, _, _ := NewPostgresLimiter("other", 5, time.Hour).Allow("client"); !ok {
+		t.Error("another limiter shared the bucket")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := RateLimit(NewMemoryLimiter(1, time.Hour), KeyByIP)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		if err := handler(echo.New().NewContext(req, rec)); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	if rec := request("192.0.2.1"); rec.Code != http.StatusOK {
+		t.Errorf("first request: status = %d", rec.Code)
+	}
+	rec := request("192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "3600" {
+		t.Errorf("second request: status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := request("192.0.2.2"); rec.Code != http.StatusOK {
+		t.Errorf("another client: status = %d", rec.Code)
+	}
+}
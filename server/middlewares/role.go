@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+)
+
+// RequireRole lets only users with the role through. It must run after AuthMiddleware.
+// The role is read from the database so a demotion takes effect immediately.
+func RequireRole(role string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userId, ok := c.Get("userId").(string)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, AuthMiddlewareReturn{
+					MiddlewareError: "Failed to get userId from context",
+				})
+			}
+			database, err := db.ConnectDB()
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, AuthMiddlewareReturn{
+					MiddlewareError: "failed to connect to database",
+				})
+			}
+			var user db.User
+			if err := database.Select("id", "role", "disabled_at").Where("id = ?", userId).First(&user).Error; err != nil {
+				return c.JSON(http.StatusUnauthorized, AuthMiddlewareReturn{
+					MiddlewareError: "user not found",
+				})
+			}
+			if user.Role != role || user.DisabledAt != nil {
+				return c.JSON(http.StatusForbidden, AuthMiddlewareReturn{
+					MiddlewareError: "insufficient permissions",
+				})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// PersonalAccessTokenPrefix tells personal access tokens apart from JWTs
+// and makes them easy to find when leaked
+const PersonalAccessTokenPrefix = "rxp_"
+
+const (
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+)
+
+// Scopes lists every scope a personal access token can be granted
+var Scopes = []string{ScopeMessagesRead, ScopeMessagesWrite}
+
+// RequireScope rejects personal access tokens without the scope.
+// Signed-in sessions can do everything the user can.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scopes, ok := c.Get("scopes").([]string)
+			if ok && !slices.Contains(scopes, scope) {
+				return c.JSON(http.StatusForbidden, AuthMiddlewareReturn{
+					MiddlewareError: "token is missing the " + scope + " scope",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireSession rejects personal access tokens, so they cannot manage the
+// account or create more tokens
+func RequireSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, ok := c.Get("sessionId").(string); !ok {
+			return c.JSON(http.StatusForbidden, AuthMiddlewareReturn{
+				MiddlewareError: "this endpoint requires signing in",
+			})
+		}
+		return next(c)
+	}
+}
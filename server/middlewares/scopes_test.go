@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/db/dbtest"
+	"github.com/ut-code/Raxcel/server/utils"
+	"gorm.io/gorm"
+)
+
+// createPersonalAccessToken stores a token of user-1 and returns it in plain text
+func createPersonalAccessToken(t *testing.T, database *gorm.DB, id, scopes string, expiresAt *time.Time) string {
+	t.Helper()
+	token := PersonalAccessTokenPrefix + id + "-0123456789"
+	if err := database.Create(&db.PersonalAccessToken{
+		Id:        id,
+		UserId:    "user-1",
+		Name:      id,
+		TokenHash: utils.HashToken(token),
+		Prefix:    token[:8],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// serve calls a handler behind AuthMiddleware and the given middlewares with the token
+func serve(t *testing.T, token string, middlewares ...echo.MiddlewareFunc) int {
+	t.Helper()
+	e := echo.New()
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, append([]echo.MiddlewareFunc{AuthMiddleware}, middlewares...)...)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestPersonalAccessTokenScopes(t *testing.T) {
+	database := dbtest.New(t)
+	readOnly := createPersonalAccessToken(t, database, "read", ScopeMessagesRead, nil)
+	readWrite := createPersonalAccessToken(t, database, "readwrite", ScopeMessagesRead+" "+ScopeMessagesWrite, nil)
+
+	tests := []struct {
+		name  string
+		token string
+		scope string
+		want  int
+	}{
+		{"granted scope", readOnly, ScopeMessagesRead, http.StatusOK},
+		{"missing scope", readOnly, ScopeMessagesWrite, http.StatusForbidden},
+		{"every scope", readWrite, ScopeMessagesWrite, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := serve(t, tt.token, RequireScope(tt.scope)); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPersonalAccessTokenRejected(t *testing.T) {
+	database := dbtest.New(t)
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	expired := createPersonalAccessToken(t, database, "expired", ScopeMessagesRead, &past)
+	unexpired := createPersonalAccessToken(t, database, "unexpired", ScopeMessagesRead, &future)
+	revoked := createPersonalAccessToken(t, database, "revoked", ScopeMessagesRead, nil)
+	// Revoking a token deletes it
+	database.Delete(&db.PersonalAccessToken{}, "id = ?", "revoked")
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"expired", expired, http.StatusUnauthorized},
+		{"revoked", revoked, http.StatusUnauthorized},
+		{"unknown", PersonalAccessTokenPrefix + "unknown", http.StatusUnauthorized},
+		{"not expired yet", unexpired, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := serve(t, tt.token, RequireScope(ScopeMessagesRead)); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	database := dbtest.New(t)
+	t.Setenv("JWT_KEYS", "")
+	t.Setenv("SECRET_KEY", "test-secret")
+	token := createPersonalAccessToken(t, database, "all", ScopeMessagesRead+" "+ScopeMessagesWrite, nil)
+	session := db.Session{Id: "session-1", UserId: "user-1", LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := database.Create(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	accessToken, _, err := utils.GenerateJWT("user-1", session.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Whatever its scopes, a personal access token cannot manage the account
+	if got := serve(t, token, RequireSession); got != http.StatusForbidden {
+		t.Errorf("personal access token: status = %d, want %d", got, http.StatusForbidden)
+	}
+	// A signed-in session reaches both kinds of routes
+	if got := serve(t, accessToken, RequireSession); got != http.StatusOK {
+		t.Errorf("session: status = %d, want %d", got, http.StatusOK)
+	}
+	if got := serve(t, accessToken, RequireScope(ScopeMessagesWrite)); got != http.StatusOK {
+		t.Errorf("session on a scoped route: status = %d, want %d", got, http.StatusOK)
+	}
+
+	database.Model(&session).Update("revoked_at", time.Now())
+	if got := serve(t, accessToken, RequireSession); got != http.StatusUnauthorized {
+		t.Errorf("revoked session: status = %d, want %d", got, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,8 @@
+-- Distinguish verification tokens from refresh tokens
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS type VARCHAR(50) NOT NULL DEFAULT 'verification';
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS family_id VARCHAR(255);
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMP;
+
+-- Create indexes on tokens.type and tokens.family_id
+CREATE INDEX IF NOT EXISTS idx_tokens_type ON tokens(type);
+CREATE INDEX IF NOT EXISTS idx_tokens_family_id ON tokens(family_id);
@@ -0,0 +1,10 @@
+-- Track failed signins for progressive lockout
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_signin_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+-- Create rate_limit_buckets table
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    id VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
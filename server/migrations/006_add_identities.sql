@@ -0,0 +1,14 @@
+-- Create identities table
+CREATE TABLE IF NOT EXISTS identities (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_identities_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create indexes on identities
+CREATE INDEX IF NOT EXISTS idx_identities_user_id ON identities(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_identities_issuer_subject ON identities(issuer, subject);
@@ -0,0 +1,2 @@
+-- Pending state of a token, such as the new address of an email change
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS data TEXT;
@@ -0,0 +1,50 @@
+-- Create organizations table
+CREATE TABLE IF NOT EXISTS organizations (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    chat_quota INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create memberships table
+CREATE TABLE IF NOT EXISTS memberships (
+    id VARCHAR(255) PRIMARY KEY,
+    organization_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT 'member',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_memberships_organization FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_memberships_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create indexes on memberships
+CREATE UNIQUE INDEX IF NOT EXISTS idx_memberships_organization_user ON memberships(organization_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_memberships_user_id ON memberships(user_id);
+
+-- Create invitations table
+CREATE TABLE IF NOT EXISTS invitations (
+    id VARCHAR(255) PRIMARY KEY,
+    organization_id VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT 'member',
+    token_hash VARCHAR(255) UNIQUE NOT NULL,
+    invited_by VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_invitations_organization FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+-- Create indexes on invitations
+CREATE INDEX IF NOT EXISTS idx_invitations_organization_id ON invitations(organization_id);
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations(email);
+
+-- Messages can count towards an organization's chat quota
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS organization_id VARCHAR(255);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_organizations_messages') THEN
+        ALTER TABLE messages ADD CONSTRAINT fk_organizations_messages FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE SET NULL;
+    END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_messages_organization_id ON messages(organization_id);
@@ -0,0 +1,33 @@
+-- Create conversations table
+CREATE TABLE IF NOT EXISTS conversations (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    workbook VARCHAR(1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_conversations_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create index on conversations
+CREATE INDEX IF NOT EXISTS idx_conversations_user_id ON conversations(user_id);
+
+-- Messages belong to a conversation
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS conversation_id VARCHAR(255);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_conversations_messages') THEN
+        ALTER TABLE messages ADD CONSTRAINT fk_conversations_messages FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id);
+
+-- Move the messages sent before conversations existed into one conversation per user
+INSERT INTO conversations (id, user_id, title, created_at, updated_at)
+SELECT 'earlier-' || user_id, user_id, 'Earlier messages', MIN(created_at), MAX(created_at)
+FROM messages
+WHERE conversation_id IS NULL
+GROUP BY user_id
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE messages SET conversation_id = 'earlier-' || user_id WHERE conversation_id IS NULL;
@@ -0,0 +1,3 @@
+-- Chat history is paged by creation time, per user and per conversation
+CREATE INDEX IF NOT EXISTS idx_messages_user_id_created_at ON messages(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id_created_at ON messages(conversation_id, created_at);
@@ -0,0 +1,3 @@
+-- Older messages of a conversation are sent to the model as a rolling summary
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '';
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS summarized_until TIMESTAMP;
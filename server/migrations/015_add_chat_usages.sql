@@ -0,0 +1,17 @@
+-- Organization chat quotas count recorded usage, which members cannot delete
+CREATE TABLE IF NOT EXISTS chat_usages (
+    id VARCHAR(255) PRIMARY KEY,
+    organization_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_organizations_chat_usages FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_usages_organization_id_created_at ON chat_usages(organization_id, created_at);
+
+-- Carry over the usage of the messages sent so far
+INSERT INTO chat_usages (id, organization_id, user_id, created_at)
+SELECT 'message-' || id, organization_id, user_id, created_at
+FROM messages
+WHERE organization_id IS NOT NULL AND role = 'user'
+ON CONFLICT (id) DO NOTHING;
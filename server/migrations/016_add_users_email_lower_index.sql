@@ -0,0 +1,10 @@
+-- Addresses are looked up regardless of case, so they must also be unique regardless of case.
+-- Accounts that only differ in case have to be merged by hand before the index can be created.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users GROUP BY lower(email) HAVING COUNT(*) > 1) THEN
+        RAISE NOTICE 'users has emails that only differ in case, skipping idx_users_email_lower';
+    ELSE
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users(lower(email));
+    END IF;
+END $$;
@@ -0,0 +1,168 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// mockIdP is an identity provider with discovery, token and userinfo endpoints
+type mockIdP struct {
+	server *httptest.Server
+	// issuer is what the discovery document claims, the server URL unless set
+	issuer      string
+	userInfo    map[string]any
+	discoveries atomic.Int32
+	// form is the last token request
+	form map[string]string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	idp := &mockIdP{
+		userInfo: map[string]any{"sub": "user-1", "email": "alice@example.com", "email_verified": true},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		idp.discoveries.Add(1)
+		issuer := idp.issuer
+		if issuer == "" {
+			issuer = idp.server.URL
+		}
+		json.NewEncoder(w).Encode(Metadata{
+			Issuer:                issuer,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			UserinfoEndpoint:      idp.server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.form = map[string]string{}
+		for key := range r.PostForm {
+			idp.form[key] = r.PostForm.Get(key)
+		}
+		if r.PostForm.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access-1", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(idp.userInfo)
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	t.Cleanup(func() {
+		discoveredMu.Lock()
+		delete(discovered, idp.server.URL)
+		discoveredMu.Unlock()
+	})
+	t.Setenv("OIDC_ISSUER", idp.server.URL)
+	t.Setenv("OIDC_CLIENT_ID", "raxcel")
+	t.Setenv("OIDC_CLIENT_SECRET", "")
+	t.Setenv("OIDC_SCOPES", "")
+	return idp
+}
+
+func TestFromEnvNotConfigured(t *testing.T) {
+	t.Setenv("OIDC_ISSUER", "")
+	t.Setenv("OIDC_CLIENT_ID", "")
+	if _, err := FromEnv(context.Background()); err != ErrNotConfigured {
+		t.Errorf("FromEnv = %v, want ErrNotConfigured", err)
+	}
+}
+
+func TestFromEnvIssuerMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.issuer = "https://other.example.com"
+	if _, err := FromEnv(context.Background()); err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Errorf("FromEnv = %v, want an issuer mismatch", err)
+	}
+}
+
+func TestFromEnvCachesDiscovery(t *testing.T) {
+	idp := newMockIdP(t)
+	for range 3 {
+		if _, err := FromEnv(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := idp.discoveries.Load(); got != 1 {
+		t.Errorf("discovery fetched %d times, want 1", got)
+	}
+}
+
+func TestAuthorizationURL(t *testing.T) {
+	idp := newMockIdP(t)
+	provider, err := FromEnv(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := provider.AuthorizationURL("http://127.0.0.1:5000/callback", "state-1", "challenge-1")
+	for _, want := range []string{
+		idp.server.URL + "/authorize?",
+		"client_id=raxcel",
+		"redirect_uri=http%3A%2F%2F127.0.0.1%3A5000%2Fcallback",
+		"state=state-1",
+		"code_challenge=challenge-1",
+		"code_challenge_method=S256",
+		"scope=openid+email+profile",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("AuthorizationURL = %s, missing %s", got, want)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := newMockIdP(t)
+	provider, err := FromEnv(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := provider.Exchange(context.Background(), "good-code", "verifier-1", "http://127.0.0.1:5000/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Subject != "user-1" || info.Email != "alice@example.com" || !info.EmailVerified {
+		t.Errorf("Exchange = %+v", info)
+	}
+	for key, want := range map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          "good-code",
+		"code_verifier": "verifier-1",
+		"redirect_uri":  "http://127.0.0.1:5000/callback",
+		"client_id":     "raxcel",
+	} {
+		if idp.form[key] != want {
+			t.Errorf("token request %s = %q, want %q", key, idp.form[key], want)
+		}
+	}
+	if _, ok := idp.form["client_secret"]; ok {
+		t.Error("a public client sent a client secret")
+	}
+}
+
+func TestExchangeErrors(t *testing.T) {
+	idp := newMockIdP(t)
+	provider, err := FromEnv(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(context.Background(), "bad-code", "verifier-1", "http://127.0.0.1/"); err == nil {
+		t.Error("Exchange accepted a code the provider rejected")
+	}
+	idp.userInfo = map[string]any{"email": "alice@example.com"}
+	if _, err := provider.Exchange(context.Background(), "good-code", "verifier-1", "http://127.0.0.1/"); err == nil {
+		t.Error("Exchange accepted userinfo without a subject")
+	}
+}
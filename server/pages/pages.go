@@ -0,0 +1,54 @@
+// Package pages renders the few HTML pages the server shows in a browser,
+// such as the one opened from the verification email.
+package pages
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+type Page string
+
+const (
+	PageVerified            Page = "verified"
+	PageVerificationExpired Page = "verification_expired"
+	PageVerificationInvalid Page = "verification_invalid"
+	PageVerificationResent  Page = "verification_resent"
+	PageMagicLinkConfirm    Page = "magic_link_confirm"
+	PageMagicLinkApproved   Page = "magic_link_approved"
+	PageMagicLinkInvalid    Page = "magic_link_invalid"
+	PageEmailChanged        Page = "email_changed"
+	PageEmailChangeInvalid  Page = "email_change_invalid"
+	PageEmailChangeConflict Page = "email_change_conflict"
+	PageError               Page = "error"
+)
+
+const defaultLanguage = "en"
+
+// Render renders a page in lang, falling back to English when the page is not translated.
+// Each page defines a "title" and a "content" block that layout.html wraps.
+func Render(page Page, lang string, data map[string]any) (string, error) {
+	base := "templates/" + lang + "/" + string(page) + ".html"
+	if _, err := fs.Stat(templateFS, base); err != nil {
+		lang = defaultLanguage
+		base = "templates/" + lang + "/" + string(page) + ".html"
+	}
+	tmpl, err := template.ParseFS(templateFS, "templates/layout.html", base)
+	if err != nil {
+		return "", err
+	}
+	if data == nil {
+		data = map[string]any{}
+	}
+	data["Lang"] = lang
+	var out bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&out, "layout", data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
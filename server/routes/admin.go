@@ -0,0 +1,325 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 200
+)
+
+// AdminUser is what administrators see of an account. Secrets are never included.
+type AdminUser struct {
+	Id          string     `json:"id"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	IsVerified  bool       `json:"isVerified"`
+	TotpEnabled bool       `json:"totpEnabled"`
+	DisabledAt  *time.Time `json:"disabledAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+func toAdminUser(user db.User) AdminUser {
+	return AdminUser{
+		Id:          user.Id,
+		Email:       user.Email,
+		Role:        user.Role,
+		IsVerified:  user.IsVerified,
+		TotpEnabled: user.TotpEnabled,
+		DisabledAt:  user.DisabledAt,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+type AdminListUsersResponse struct {
+	Error string      `json:"error,omitempty"`
+	Users []AdminUser `json:"users,omitempty"`
+	Total int64       `json:"total"`
+}
+
+// AdminListUsers lists accounts, newest first. ?q= searches the email,
+// and ?limit= and ?offset= page through the results.
+func AdminListUsers(c echo.Context) error {
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultAdminPageSize
+	}
+	limit = min(limit, maxAdminPageSize)
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminListUsersResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	query := database.Model(&db.User{})
+	if q := strings.TrimSpace(c.QueryParam("q")); q != "" {
+		query = query.Where("email ILIKE ?", "%"+escapeLike(q)+"%")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminListUsersResponse{
+			Error: "failed to count users",
+		})
+	}
+	var users []db.User
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminListUsersResponse{
+			Error: "failed to fetch users",
+		})
+	}
+	result := make([]AdminUser, len(users))
+	for i, user := range users {
+		result[i] = toAdminUser(user)
+	}
+	return c.JSON(http.StatusOK, AdminListUsersResponse{
+		Users: result,
+		Total: total,
+	})
+}
+
+// escapeLike makes % and _ in a search term match literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+type AdminUserResponse struct {
+	Error string     `json:"error,omitempty"`
+	User  *AdminUser `json:"user,omitempty"`
+}
+
+func AdminGetUser(c echo.Context) error {
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminUserResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, AdminUserResponse{
+			Error: "user not found",
+		})
+	}
+	adminUser := toAdminUser(user)
+	return c.JSON(http.StatusOK, AdminUserResponse{
+		User: &adminUser,
+	})
+}
+
+// adminUpdateUser loads the target user, applies update and responds with the result.
+// Administrators cannot apply it to themselves, so they cannot lock themselves out.
+func adminUpdateUser(c echo.Context, action string, update func(tx *gorm.DB, user *db.User) error) error {
+	adminId, _ := c.Get("userId").(string)
+	targetId := c.Param("id")
+	if targetId == adminId {
+		return c.JSON(http.StatusBadRequest, AdminUserResponse{
+			Error: "you cannot " + action + " your own account",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminUserResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", targetId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, AdminUserResponse{
+			Error: "user not found",
+		})
+	}
+	if err := database.Transaction(func(tx *gorm.DB) error {
+		return update(tx, &user)
+	}); err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminUserResponse{
+			Error: "failed to " + action + " user",
+		})
+	}
+	log.Printf("Admin %s: %s user %s", adminId, action, user.Id)
+	adminUser := toAdminUser(user)
+	return c.JSON(http.StatusOK, AdminUserResponse{
+		User: &adminUser,
+	})
+}
+
+// AdminVerifyUser marks the email as verified without the verification email
+func AdminVerifyUser(c echo.Context) error {
+	return adminUpdateUser(c, "verify", func(tx *gorm.DB, user *db.User) error {
+		user.IsVerified = true
+		if err := tx.Model(user).Update("is_verified", true).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND type = ?", user.Id, db.TokenTypeVerification).Delete(&db.Token{}).Error
+	})
+}
+
+// AdminDisableUser blocks signin and signs the user out of every device and token
+func AdminDisableUser(c echo.Context) error {
+	return adminUpdateUser(c, "disable", func(tx *gorm.DB, user *db.User) error {
+		now := time.Now()
+		user.DisabledAt = &now
+		if err := tx.Model(user).Update("disabled_at", now).Error; err != nil {
+			return err
+		}
+		return signOutEverywhere(tx, user.Id)
+	})
+}
+
+func AdminEnableUser(c echo.Context) error {
+	return adminUpdateUser(c, "enable", func(tx *gorm.DB, user *db.User) error {
+		user.DisabledAt = nil
+		return tx.Model(user).Update("disabled_at", nil).Error
+	})
+}
+
+type AdminSetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func AdminSetRole(c echo.Context) error {
+	req := new(AdminSetRoleRequest)
+	if err := c.Bind(req); err != nil || (req.Role != db.RoleUser && req.Role != db.RoleAdmin) {
+		return c.JSON(http.StatusBadRequest, AdminUserResponse{
+			Error: "role must be user or admin",
+		})
+	}
+	return adminUpdateUser(c, "change the role of", func(tx *gorm.DB, user *db.User) error {
+		user.Role = req.Role
+		return tx.Model(user).Update("role", req.Role).Error
+	})
+}
+
+// AdminResetChatHistory deletes every conversation and message of the user
+func AdminResetChatHistory(c echo.Context) error {
+	return adminUpdateUser(c, "reset the chat history of", func(tx *gorm.DB, user *db.User) error {
+		if err := tx.Where("user_id = ?", user.Id).Delete(&db.Message{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.Id).Delete(&db.Conversation{}).Error
+	})
+}
+
+type AdminDeleteUserResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func AdminDeleteUser(c echo.Context) error {
+	adminId, _ := c.Get("userId").(string)
+	targetId := c.Param("id")
+	if targetId == adminId {
+		return c.JSON(http.StatusBadRequest, AdminDeleteUserResponse{
+			Error: "you cannot delete your own account here",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminDeleteUserResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", targetId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, AdminDeleteUserResponse{
+			Error: "user not found",
+		})
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := deleteUserMemberships(tx, user.Id); err != nil {
+			return err
+		}
+		return tx.Select(clause.Associations).Delete(&user).Error
+	})
+	if errors.Is(err, errLastOwner) {
+		return c.JSON(http.StatusConflict, AdminDeleteUserResponse{
+			Error: "the user is the last owner of an organization",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminDeleteUserResponse{
+			Error: "failed to delete user",
+		})
+	}
+	log.Printf("Admin %s: delete user %s", adminId, user.Id)
+	return c.JSON(http.StatusOK, AdminDeleteUserResponse{})
+}
+
+// UserUsage summarizes how much a user has used the service
+type UserUsage struct {
+	Messages          int64      `json:"messages"`
+	UserMessages      int64      `json:"userMessages"`
+	AssistantMessages int64      `json:"assistantMessages"`
+	MessagesLast30d   int64      `json:"messagesLast30d"`
+	LastMessageAt     *time.Time `json:"lastMessageAt,omitempty"`
+	ActiveSessions    int64      `json:"activeSessions"`
+	AccessTokens      int64      `json:"accessTokens"`
+}
+
+type AdminUserUsageResponse struct {
+	Error string     `json:"error,omitempty"`
+	Usage *UserUsage `json:"usage,omitempty"`
+}
+
+func AdminUserUsage(c echo.Context) error {
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminUserUsageResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, AdminUserUsageResponse{
+			Error: "user not found",
+		})
+	}
+	var usage UserUsage
+	var lastMessageAt time.Time
+	messages := func() *gorm.DB {
+		return database.Model(&db.Message{}).Where("user_id = ?", user.Id)
+	}
+	err = messages().Count(&usage.Messages).Error
+	if err == nil {
+		err = messages().Where("role = ?", "user").Count(&usage.UserMessages).Error
+	}
+	if err == nil {
+		err = messages().Where("role = ?", "assistant").Count(&usage.AssistantMessages).Error
+	}
+	if err == nil {
+		err = messages().Where("created_at > ?", time.Now().AddDate(0, 0, -30)).Count(&usage.MessagesLast30d).Error
+	}
+	if err == nil && usage.Messages > 0 {
+		err = messages().Select("MAX(created_at)").Scan(&lastMessageAt).Error
+		usage.LastMessageAt = &lastMessageAt
+	}
+	if err == nil {
+		err = database.Model(&db.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.Id, time.Now()).
+			Count(&usage.ActiveSessions).Error
+	}
+	if err == nil {
+		err = database.Model(&db.PersonalAccessToken{}).Where("user_id = ?", user.Id).Count(&usage.AccessTokens).Error
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, AdminUserUsageResponse{
+			Error: "failed to compute usage",
+		})
+	}
+	return c.JSON(http.StatusOK, AdminUserUsageResponse{
+		Usage: &usage,
+	})
+}
@@ -5,15 +5,19 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"html/template"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"time"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
-	"github.com/resend/resend-go/v3"
 	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/mail"
+	middleware "github.com/ut-code/Raxcel/server/middlewares"
+	"github.com/ut-code/Raxcel/server/pages"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -24,7 +28,9 @@ type SignupRequest struct {
 }
 
 type SignupResponse struct {
-	Error  string `json:"error,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Code is one of the SignupCode constants when the signup is rejected
+	Code   string `json:"code,omitempty"`
 	UserId string `json:"userId,omitempty"`
 }
 
@@ -41,9 +47,17 @@ func Signup(c echo.Context) error {
 			Error: "email and password are required",
 		})
 	}
+	email, ok := normalizeEmail(req.Email)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, SignupResponse{
+			Error: "invalid email address",
+			Code:  SignupCodeInvalidEmail,
+		})
+	}
 	if len(req.Password) < 8 {
 		return c.JSON(http.StatusBadRequest, SignupResponse{
 			Error: "password must be at least 8 characters",
+			Code:  SignupCodeWeakPassword,
 		})
 	}
 	database, err := db.ConnectDB()
@@ -52,6 +66,17 @@ func Signup(c echo.Context) error {
 			Error: "failed to connect to database",
 		})
 	}
+	if policyErr := checkSignupPolicy(database, email); policyErr != nil {
+		return c.JSON(http.StatusForbidden, SignupResponse{
+			Error: policyErr.Message,
+			Code:  policyErr.Code,
+		})
+	}
+	var existing int64
+	whereEmail(database.Model(&db.User{}), email).Count(&existing)
+	if existing > 0 {
+		return signupExistingEmail(c, database, email)
+	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, SignupResponse{
@@ -60,34 +85,19 @@ func Signup(c echo.Context) error {
 	}
 	user := db.User{
 		Id:           uuid.New().String(),
-		Email:        req.Email,
+		Email:        email,
 		PasswordHash: string(hashedPassword),
 		IsVerified:   false,
 	}
 	if err := database.Create(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
-			return c.JSON(http.StatusConflict, SignupResponse{
-				Error: "the email is already used",
-			})
+			return signupExistingEmail(c, database, email)
 		}
 		return c.JSON(http.StatusInternalServerError, SignupResponse{
 			Error: "failed to create user",
 		})
 	}
-	tokenString := generateSecureToken()
-	token := db.Token{
-		Id:        uuid.New().String(),
-		UserId:    user.Id,
-		Token:     tokenString,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-	}
-	if err := database.Create(&token).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, SignupResponse{
-			Error: "failed to create verification token",
-		})
-	}
-
-	if err := sendVerificationEmail(user.Email, tokenString); err != nil {
+	if err := issueVerificationEmail(c, database, user); err != nil {
 		fmt.Println(err)
 		return c.JSON(http.StatusInternalServerError, SignupResponse{
 			Error: "failed to send verification email",
@@ -104,8 +114,16 @@ type SigninRequest struct {
 }
 
 type SigninResponse struct {
-	Error string `json:"error,omitempty"`
-	Token string `json:"token,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Token        string    `json:"token,omitempty"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt,omitzero"`
+	// Set instead of the tokens when the account has 2FA enabled;
+	// the challenge token is exchanged at /auth/2fa/verify
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+	// Pending is set by /auth/magic-link/complete until the link has been opened
+	Pending bool `json:"pending,omitempty"`
 }
 
 func Signin(c echo.Context) error {
@@ -122,29 +140,66 @@ func Signin(c echo.Context) error {
 		})
 	}
 	var user db.User
-	if err := database.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	if err := whereEmail(database, req.Email).First(&user).Error; err != nil {
 		return c.JSON(http.StatusNotFound, SigninResponse{
 			Error: "user not found",
 		})
 	}
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return middleware.TooManyRequests(c, time.Until(*user.LockedUntil), "account is temporarily locked after too many failed signins")
+	}
 	if !user.IsVerified {
 		return c.JSON(http.StatusForbidden, SigninResponse{
 			Error: "email not verified",
 		})
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		recordFailedSignin(database, user)
 		return c.JSON(http.StatusUnauthorized, SigninResponse{
 			Error: "invalid email or password",
 		})
 	}
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		Issuer:    user.Id,
-		ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
-	})
-	secretKey := os.Getenv("SECRET_KEY")
-	signedToken, _ := claims.SignedString([]byte(secretKey))
+	return completeSignin(c, database, user)
+}
+
+// completeSignin is called once the user has proven who they are with a first factor.
+// It asks for the second factor when 2FA is enabled, otherwise it issues the session tokens.
+func completeSignin(c echo.Context, database *gorm.DB, user db.User) error {
+	if user.DisabledAt != nil {
+		return c.JSON(http.StatusForbidden, SigninResponse{
+			Error: "account has been disabled",
+		})
+	}
+	if user.TotpEnabled {
+		// Failed signins are not reset until the second factor is verified,
+		// otherwise the password alone would allow unlimited code guesses
+		challenge, err := issueTwoFactorChallenge(database, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, SigninResponse{
+				Error: "failed to create two-factor challenge",
+			})
+		}
+		return c.JSON(http.StatusOK, SigninResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		})
+	}
+	resetFailedSignins(database, user)
+	return respondWithSessionTokens(c, database, user)
+}
+
+// respondWithSessionTokens starts a session for the device making the request
+func respondWithSessionTokens(c echo.Context, database *gorm.DB, user db.User) error {
+	tokens, err := startSession(c, database, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, SigninResponse{
+			Error: "failed to issue tokens",
+		})
+	}
 	return c.JSON(http.StatusOK, SigninResponse{
-		Token: signedToken,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
 	})
 }
 
@@ -154,39 +209,102 @@ func generateSecureToken() string {
 	return hex.EncodeToString(b)
 }
 
+// VerifyEmail is opened in the browser from the verification email, so it answers with a page.
+// An expired link does not send a new email by itself, since mail scanners open links too.
 func VerifyEmail(c echo.Context) error {
-	//TODO: send html instead of json
 	reqToken := c.QueryParam("token")
 	database, err := db.ConnectDB()
 	if err != nil {
-		return c.String(http.StatusInternalServerError, "failed to connect to database")
+		return renderPage(c, http.StatusInternalServerError, pages.PageError, nil)
 	}
 	var token db.Token
-	if err := database.Where("token = ?", reqToken).First(&token).Error; err != nil {
-		return c.String(http.StatusNotFound, "invalid verification token")
+	if err := database.Where("token = ? AND type = ?", reqToken, db.TokenTypeVerification).First(&token).Error; err != nil {
+		return renderPage(c, http.StatusNotFound, pages.PageVerificationInvalid, nil)
 	}
-
 	if time.Now().After(token.ExpiresAt) {
-		return c.String(http.StatusBadRequest, "verification token has expired")
+		return renderPage(c, http.StatusBadRequest, pages.PageVerificationExpired, map[string]any{
+			"Token": reqToken,
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", token.UserId).First(&user).Error; err != nil {
+		return renderPage(c, http.StatusNotFound, pages.PageVerificationInvalid, nil)
 	}
-	if err := database.Model(&db.User{}).Where("id = ?", token.UserId).Update("is_verified", true).Error; err != nil {
-		return c.String(http.StatusInternalServerError, "failed to verify user")
+	if err := database.Model(&user).Update("is_verified", true).Error; err != nil {
+		return renderPage(c, http.StatusInternalServerError, pages.PageError, nil)
 	}
 	database.Delete(&token)
-	return c.String(http.StatusOK, "email verified!")
+	return renderPage(c, http.StatusOK, pages.PageVerified, map[string]any{
+		"Email":   user.Email,
+		"AppLink": appLink("verified", url.Values{"email": {user.Email}}),
+	})
 }
 
-func sendVerificationEmail(email, token string) error {
-	apiKey := os.Getenv("RESEND_API_KEY")
+// ResendVerificationFromPage is posted by the expired link page. The expired token
+// identifies the account, so the user does not have to type their email again.
+func ResendVerificationFromPage(c echo.Context) error {
+	reqToken := c.FormValue("token")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return renderPage(c, http.StatusInternalServerError, pages.PageError, nil)
+	}
+	var token db.Token
+	if err := database.Where("token = ? AND type = ?", reqToken, db.TokenTypeVerification).First(&token).Error; err != nil {
+		return renderPage(c, http.StatusNotFound, pages.PageVerificationInvalid, nil)
+	}
+	var user db.User
+	if err := database.Where("id = ?", token.UserId).First(&user).Error; err != nil {
+		return renderPage(c, http.StatusNotFound, pages.PageVerificationInvalid, nil)
+	}
+	// A new email was sent moments ago when the cooldown has not passed
+	if canResendVerification(database, user.Id) {
+		if err := issueVerificationEmail(c, database, user); err != nil {
+			log.Printf("Failed to resend verification email: %v", err)
+			return renderPage(c, http.StatusInternalServerError, pages.PageError, nil)
+		}
+	}
+	return renderPage(c, http.StatusOK, pages.PageVerificationResent, map[string]any{
+		"Email": user.Email,
+	})
+}
+
+func sendVerificationEmail(c echo.Context, email, token string) error {
 	apiUrl := os.Getenv("API_URL")
-	client := resend.NewClient(apiKey)
+	return mail.Send(c.Request().Context(), email, mail.TemplateVerification, requestLanguage(c), map[string]string{
+		"Link": fmt.Sprintf("%s/auth/verify-email?token=%s", apiUrl, token),
+	})
+}
+
+func sendPasswordResetEmail(c echo.Context, email, token string) error {
+	return mail.Send(c.Request().Context(), email, mail.TemplatePasswordReset, requestLanguage(c), map[string]string{
+		"Code": token,
+	})
+}
 
-	params := &resend.SendEmailRequest{
-		From:    "Raxcel <noreply@raxcel.utcode.net>",
-		To:      []string{email},
-		Subject: "Verify your account",
-		Html:    fmt.Sprintf("<p>Click the link below to verify your email</p><a href=%s/auth/verify-email?token=%s>Click here!</a>", apiUrl, token),
+func sendEmailChangeEmail(c echo.Context, email, token string) error {
+	apiUrl := os.Getenv("API_URL")
+	return mail.Send(c.Request().Context(), email, mail.TemplateEmailChange, requestLanguage(c), map[string]string{
+		"Link": fmt.Sprintf("%s/auth/confirm-email-change?token=%s", apiUrl, token),
+	})
+}
+
+// requestLanguage is the language used for emails and pages sent in response to the request
+func requestLanguage(c echo.Context) string {
+	return mail.Language(c.Request().Header.Get("Accept-Language"))
+}
+
+func renderPage(c echo.Context, status int, page pages.Page, data map[string]any) error {
+	html, err := pages.Render(page, requestLanguage(c), data)
+	if err != nil {
+		log.Printf("Failed to render %s page: %v", page, err)
+		return c.String(http.StatusInternalServerError, "failed to render page")
 	}
-	_, err := client.Emails.Send(params)
-	return err
+	return c.HTML(status, html)
+}
+
+// appLink builds a raxcel:// link that opens the desktop app
+func appLink(action string, query url.Values) template.URL {
+	link := url.URL{Scheme: "raxcel", Host: action, RawQuery: query.Encode()}
+	// The scheme is ours, so it is safe in an href even though html/template does not know it
+	return template.URL(link.String())
 }
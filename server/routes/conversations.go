@@ -0,0 +1,305 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/llm"
+	"gorm.io/gorm"
+)
+
+const (
+	maxConversationTitleLength = 100
+	maxWorkbookLength          = 1024
+)
+
+var errConversationNotFound = errors.New("conversation not found")
+
+// findConversation returns the user's conversation, or errConversationNotFound
+// when it does not exist or belongs to someone else
+func findConversation(database *gorm.DB, conversationId, userId string) (*db.Conversation, error) {
+	var conversation db.Conversation
+	err := database.Where("id = ? AND user_id = ?", conversationId, userId).First(&conversation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errConversationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// truncateTitle cuts a title to maxConversationTitleLength characters
+func truncateTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if utf8.RuneCountInString(title) <= maxConversationTitleLength {
+		return title
+	}
+	return string([]rune(title)[:maxConversationTitleLength-1]) + "…"
+}
+
+// generateTitle asks the model to name a conversation after its first exchange.
+// The start of the question is used when the model fails.
+func generateTitle(ctx context.Context, provider llm.Provider, question, answer string) string {
+	result, err := provider.Generate(ctx, llm.Request{
+		System: "Write a short title of at most six words for the conversation below, in the language of the user. Reply with the title only.",
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Content: question},
+			{Role: llm.RoleAssistant, Content: answer},
+			{Role: llm.RoleUser, Content: "Title this conversation."},
+		},
+	})
+	title := strings.Trim(strings.TrimSpace(result.Text), "\"'「」*#")
+	if err != nil || title == "" {
+		title = question
+	}
+	return truncateTitle(title)
+}
+
+type ConversationResponse struct {
+	Error        string           `json:"error,omitempty"`
+	Conversation *db.Conversation `json:"conversation,omitempty"`
+	// Messages is the latest page of the conversation; earlier pages are loaded
+	// from /messages with PrevCursor
+	Messages   []db.Message `json:"messages,omitempty"`
+	PrevCursor string       `json:"prevCursor,omitempty"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
+
+type ListConversationsResponse struct {
+	Error         string            `json:"error,omitempty"`
+	Conversations []db.Conversation `json:"conversations,omitempty"`
+}
+
+type DeleteConversationResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+type CreateConversationRequest struct {
+	Title    string `json:"title"`
+	Workbook string `json:"workbook,omitempty"`
+}
+
+type UpdateConversationRequest struct {
+	Title *string `json:"title,omitempty"`
+	// Workbook links the conversation to another workbook; "" removes the link
+	Workbook *string `json:"workbook,omitempty"`
+}
+
+// ListConversations returns the user's conversations, most recently active first.
+// The workbook query parameter keeps only the conversations about that workbook.
+func ListConversations(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ListConversationsResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ListConversationsResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	query := database.Where("user_id = ?", userId)
+	if workbook := c.QueryParam("workbook"); workbook != "" {
+		query = query.Where("workbook = ?", workbook)
+	}
+	var conversations []db.Conversation
+	if err := query.Order("updated_at DESC").Find(&conversations).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, ListConversationsResponse{
+			Error: "failed to fetch conversations",
+		})
+	}
+	return c.JSON(http.StatusOK, ListConversationsResponse{
+		Conversations: conversations,
+	})
+}
+
+// CreateConversation starts an empty conversation. Sending a message without a
+// conversation id also starts one, so this is only needed to name it up front.
+func CreateConversation(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ConversationResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	req := new(CreateConversationRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ConversationResponse{
+			Error: "invalid format",
+		})
+	}
+	if len(req.Workbook) > maxWorkbookLength {
+		return c.JSON(http.StatusBadRequest, ConversationResponse{
+			Error: "workbook must be at most 1024 characters",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ConversationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	conversation := db.Conversation{
+		Id:       uuid.New().String(),
+		UserId:   userId,
+		Title:    truncateTitle(req.Title),
+		Workbook: req.Workbook,
+	}
+	if err := database.Create(&conversation).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, ConversationResponse{
+			Error: "failed to create conversation",
+		})
+	}
+	return c.JSON(http.StatusCreated, ConversationResponse{
+		Conversation: &conversation,
+	})
+}
+
+// GetConversation returns the conversation with its latest messages in the order they were sent
+func GetConversation(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ConversationResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ConversationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	conversation, err := findConversation(database, c.Param("id"), userId)
+	if errors.Is(err, errConversationNotFound) {
+		return c.JSON(http.StatusNotFound, ConversationResponse{
+			Error: "conversation not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ConversationResponse{
+			Error: "failed to fetch conversation",
+		})
+	}
+	page, err := loadHistoryPage(database.Where("conversation_id = ?", conversation.Id), "", "", defaultHistoryPageSize)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ConversationResponse{
+			Error: "failed to fetch messages",
+		})
+	}
+	return c.JSON(http.StatusOK, ConversationResponse{
+		Conversation: conversation,
+		Messages:     page.Messages,
+		PrevCursor:   page.PrevCursor,
+		NextCursor:   page.NextCursor,
+	})
+}
+
+// UpdateConversation renames the conversation or changes its workbook
+func UpdateConversation(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ConversationResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	req := new(UpdateConversationRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ConversationResponse{
+			Error: "invalid format",
+		})
+	}
+	updates := map[string]any{}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" || utf8.RuneCountInString(title) > maxConversationTitleLength {
+			return c.JSON(http.StatusBadRequest, ConversationResponse{
+				Error: "title is required and must be at most 100 characters",
+			})
+		}
+		updates["title"] = title
+	}
+	if req.Workbook != nil {
+		if len(*req.Workbook) > maxWorkbookLength {
+			return c.JSON(http.StatusBadRequest, ConversationResponse{
+				Error: "workbook must be at most 1024 characters",
+			})
+		}
+		updates["workbook"] = *req.Workbook
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ConversationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	conversation, err := findConversation(database, c.Param("id"), userId)
+	if errors.Is(err, errConversationNotFound) {
+		return c.JSON(http.StatusNotFound, ConversationResponse{
+			Error: "conversation not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ConversationResponse{
+			Error: "failed to fetch conversation",
+		})
+	}
+	if len(updates) > 0 {
+		// Renaming is not activity, so the conversation keeps its place in the list
+		if err := database.Model(conversation).UpdateColumns(updates).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, ConversationResponse{
+				Error: "failed to update conversation",
+			})
+		}
+	}
+	return c.JSON(http.StatusOK, ConversationResponse{
+		Conversation: conversation,
+	})
+}
+
+// DeleteConversation removes the conversation with its messages
+func DeleteConversation(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, DeleteConversationResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, DeleteConversationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	conversation, err := findConversation(database, c.Param("id"), userId)
+	if errors.Is(err, errConversationNotFound) {
+		return c.JSON(http.StatusNotFound, DeleteConversationResponse{
+			Error: "conversation not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, DeleteConversationResponse{
+			Error: "failed to fetch conversation",
+		})
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", conversation.Id).Delete(&db.Message{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(conversation).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, DeleteConversationResponse{
+			Error: "failed to delete conversation",
+		})
+	}
+	return c.JSON(http.StatusOK, DeleteConversationResponse{})
+}
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db/dbtest"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens a SQLite database in place of Postgres, see dbtest.New
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return dbtest.New(t)
+}
+
+// newJSONContext is a request with a JSON body. Handlers behind AuthMiddleware also need
+// the userId and sessionId it sets.
+func newJSONContext(t *testing.T, method, target string, body any) (echo.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(string(payload)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+// useTestKeys signs access tokens with a test secret
+func useTestKeys(t *testing.T) {
+	t.Setenv("JWT_KEYS", "")
+	t.Setenv("SECRET_KEY", "test-secret")
+}
@@ -0,0 +1,192 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/ut-code/Raxcel/server/llm"
+)
+
+// Kinds of SpreadsheetEdit. Each is also the name of the tool the model calls to propose it.
+const (
+	EditSetValue    = "set_value"
+	EditSetFormula  = "set_formula"
+	EditInsertRows  = "insert_rows"
+	EditCreateChart = "create_chart"
+)
+
+const maxInsertedRows = 1000
+
+// SpreadsheetEdit is a change the model proposes. Nothing is changed until the
+// user applies it in the app. Cells and ranges use A1 references.
+type SpreadsheetEdit struct {
+	Type string `json:"type"`
+	// Cell is the cell of set_value and set_formula, such as "B3"
+	Cell  string `json:"cell,omitempty"`
+	Value string `json:"value,omitempty"`
+	// Formula starts with "="
+	Formula string `json:"formula,omitempty"`
+	// Row is the 1-based row before which insert_rows inserts Count empty rows
+	Row   int `json:"row,omitempty"`
+	Count int `json:"count,omitempty"`
+	// Range is the cells create_chart plots, such as "A1:B10"
+	Range string `json:"range,omitempty"`
+}
+
+var (
+	cellPattern  = regexp.MustCompile(`^[A-Z]{1,3}[1-9][0-9]{0,6}$`)
+	rangePattern = regexp.MustCompile(`^[A-Z]{1,3}[1-9][0-9]{0,6}:[A-Z]{1,3}[1-9][0-9]{0,6}$`)
+)
+
+// editSystemPrompt tells the model how its tool calls are used
+const editSystemPrompt = "You can propose changes to the spreadsheet with the tools. " +
+	"The user reviews the proposed changes before applying them, so propose them instead of describing them. " +
+	"Cells are A1 references, where row 1 is the first row."
+
+// editTools are the tools that propose spreadsheet edits
+var editTools = []llm.Tool{
+	{
+		Name:        EditSetValue,
+		Description: "Set a cell to a plain value such as a number or text.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"cell":  map[string]any{"type": "string", "description": "A1 reference of the cell, such as B3"},
+				"value": map[string]any{"type": "string", "description": "The value to enter"},
+			},
+			"required": []string{"cell", "value"},
+		},
+	},
+	{
+		Name:        EditSetFormula,
+		Description: "Write a formula into a cell. Formulas support cell references, ranges and functions such as SUM and AVERAGE.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"cell":    map[string]any{"type": "string", "description": "A1 reference of the cell, such as B3"},
+				"formula": map[string]any{"type": "string", "description": "The formula, starting with =, such as =SUM(A1:A10)"},
+			},
+			"required": []string{"cell", "formula"},
+		},
+	},
+	{
+		Name:        EditInsertRows,
+		Description: "Insert empty rows, moving the rows below down.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"row":   map[string]any{"type": "integer", "description": "1-based row before which the rows are inserted"},
+				"count": map[string]any{"type": "integer", "description": "Number of rows to insert"},
+			},
+			"required": []string{"row", "count"},
+		},
+	},
+	{
+		Name:        EditCreateChart,
+		Description: "Create a scatter chart of a range of numbers, read as x and y pairs row by row.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"range": map[string]any{"type": "string", "description": "A1 range of the data, such as A2:B20"},
+			},
+			"required": []string{"range"},
+		},
+	},
+}
+
+// editArguments are the arguments of every edit tool
+type editArguments struct {
+	Cell string `json:"cell"`
+	// Value is usually a string, but models also send numbers and booleans
+	Value   json.RawMessage `json:"value"`
+	Formula string          `json:"formula"`
+	Row     int             `json:"row"`
+	Count   int             `json:"count"`
+	Range   string          `json:"range"`
+}
+
+// editFromToolCall turns a tool call into an edit, checking its arguments
+func editFromToolCall(call llm.ToolCall) (*SpreadsheetEdit, error) {
+	var args editArguments
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	edit := SpreadsheetEdit{
+		Type:  call.Name,
+		Cell:  strings.ToUpper(strings.TrimSpace(args.Cell)),
+		Range: strings.ToUpper(strings.TrimSpace(args.Range)),
+	}
+	switch edit.Type {
+	case EditSetValue:
+		if !cellPattern.MatchString(edit.Cell) {
+			return nil, fmt.Errorf("invalid cell %q", edit.Cell)
+		}
+		var value string
+		if err := json.Unmarshal(args.Value, &value); err != nil {
+			value = string(args.Value)
+		}
+		return &SpreadsheetEdit{Type: edit.Type, Cell: edit.Cell, Value: value}, nil
+	case EditSetFormula:
+		if !cellPattern.MatchString(edit.Cell) {
+			return nil, fmt.Errorf("invalid cell %q", edit.Cell)
+		}
+		formula := strings.TrimSpace(args.Formula)
+		if formula == "" {
+			return nil, fmt.Errorf("empty formula")
+		}
+		if !strings.HasPrefix(formula, "=") {
+			formula = "=" + formula
+		}
+		return &SpreadsheetEdit{Type: edit.Type, Cell: edit.Cell, Formula: formula}, nil
+	case EditInsertRows:
+		if args.Row < 1 || args.Count < 1 || args.Count > maxInsertedRows {
+			return nil, fmt.Errorf("invalid rows %d+%d", args.Row, args.Count)
+		}
+		return &SpreadsheetEdit{Type: edit.Type, Row: args.Row, Count: args.Count}, nil
+	case EditCreateChart:
+		if !rangePattern.MatchString(edit.Range) {
+			return nil, fmt.Errorf("invalid range %q", edit.Range)
+		}
+		return &SpreadsheetEdit{Type: edit.Type, Range: edit.Range}, nil
+	default:
+		return nil, fmt.Errorf("unknown tool %q", call.Name)
+	}
+}
+
+// editsFromToolCalls keeps the valid edits among the tool calls. Invalid calls
+// are logged and dropped, since the model cannot be asked to retry them.
+func editsFromToolCalls(calls []llm.ToolCall) []SpreadsheetEdit {
+	var edits []SpreadsheetEdit
+	for _, call := range calls {
+		edit, err := editFromToolCall(call)
+		if err != nil {
+			log.Printf("Dropping tool call %s: %v", call.Name, err)
+			continue
+		}
+		edits = append(edits, *edit)
+	}
+	return edits
+}
+
+// describeEdits writes the edits as text, which is saved as the answer when the
+// model proposed edits without saying anything, so the history shows what happened
+func describeEdits(edits []SpreadsheetEdit) string {
+	var b strings.Builder
+	b.WriteString("Proposed changes:")
+	for _, edit := range edits {
+		switch edit.Type {
+		case EditSetValue:
+			fmt.Fprintf(&b, "\n- Set %s to %s", edit.Cell, edit.Value)
+		case EditSetFormula:
+			fmt.Fprintf(&b, "\n- Write %s in %s", edit.Formula, edit.Cell)
+		case EditInsertRows:
+			fmt.Fprintf(&b, "\n- Insert %d rows before row %d", edit.Count, edit.Row)
+		case EditCreateChart:
+			fmt.Fprintf(&b, "\n- Chart %s", edit.Range)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/mail"
+)
+
+func TestAccountEmails(t *testing.T) {
+	t.Setenv("MAIL_BACKEND", "memory")
+	t.Setenv("API_URL", "https://api.example.com")
+
+	tests := []struct {
+		name     string
+		language string
+		send     func(c echo.Context) error
+		template mail.Template
+		data     map[string]string
+		// want is the link or code the email must carry
+		want string
+	}{
+		{
+			name: "verification",
+			send: func(c echo.Context) error {
+				return sendVerificationEmail(c, "alice@example.com", "verify-token")
+			},
+			template: mail.TemplateVerification,
+			want:     "https://api.example.com/auth/verify-email?token=verify-token",
+		},
+		{
+			name:     "password reset",
+			language: "ja",
+			send: func(c echo.Context) error {
+				return sendPasswordResetEmail(c, "alice@example.com", "reset-code")
+			},
+			template: mail.TemplatePasswordReset,
+			want:     "reset-code",
+		},
+		{
+			name: "magic link",
+			send: func(c echo.Context) error {
+				return sendMagicLinkEmail(c, "alice@example.com", "magic+token")
+			},
+			template: mail.TemplateMagicLink,
+			want:     "https://api.example.com/auth/magic-link/verify?token=magic%2Btoken",
+		},
+		{
+			name: "invitation",
+			send: func(c echo.Context) error {
+				return sendInvitationEmail(c, "alice@example.com", "invite-code", "ut.code();", "bob@example.com")
+			},
+			template: mail.TemplateInvitation,
+			data:     map[string]string{"Organization": "ut.code();"},
+			want:     "invite-code",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mail.DefaultMemoryMailer.Reset()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.language != "" {
+				req.Header.Set("Accept-Language", tt.language)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			if err := tt.send(c); err != nil {
+				t.Fatal(err)
+			}
+
+			messages := mail.DefaultMemoryMailer.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(messages))
+			}
+			message := messages[0]
+			if message.To != "alice@example.com" {
+				t.Errorf("To = %q, want %q", message.To, "alice@example.com")
+			}
+			// The subject tells which template was rendered, in the language asked for
+			language := tt.language
+			if language == "" {
+				language = "en"
+			}
+			expected, err := mail.Compose(message.To, tt.template, language, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if message.Subject != expected.Subject {
+				t.Errorf("Subject = %q, want %q", message.Subject, expected.Subject)
+			}
+			if !strings.Contains(message.Text, tt.want) {
+				t.Errorf("text does not contain %q:\n%s", tt.want, message.Text)
+			}
+			if !strings.Contains(message.HTML, tt.want) {
+				t.Errorf("HTML does not contain %q:\n%s", tt.want, message.HTML)
+			}
+		})
+	}
+}
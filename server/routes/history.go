@@ -0,0 +1,132 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/llm"
+	"gorm.io/gorm"
+)
+
+const (
+	// historyTokenBudget is about how many tokens of earlier messages are sent with a message
+	historyTokenBudget = 4000
+	// maxHistoryMessages bounds the earlier messages sent, however short they are
+	maxHistoryMessages = 50
+	// summaryInputTokens is about how many tokens of messages one summary update folds in
+	summaryInputTokens = 8000
+	summaryTimeout     = 30 * time.Second
+)
+
+// historyWindow returns the latest messages of the conversation before current that fit
+// the token budget, in the order they were sent, leaving out the ones the summary covers.
+// leftOut is the latest message the window leaves out that the summary does not cover yet.
+func historyWindow(database *gorm.DB, conversation *db.Conversation, current *db.Message) (window []db.Message, leftOut *db.Message, err error) {
+	query := database.Where("conversation_id = ? AND id <> ?", conversation.Id, current.Id)
+	if conversation.SummarizedUntil != nil {
+		query = query.Where("created_at > ?", *conversation.SummarizedUntil)
+	}
+	var recent []db.Message
+	// One more than the window tells whether messages were left out
+	if err := query.Order("created_at DESC, id DESC").Limit(maxHistoryMessages + 1).Find(&recent).Error; err != nil {
+		return nil, nil, err
+	}
+	tokens := 0
+	for i, message := range recent {
+		tokens += llm.EstimateTokens(message.Content)
+		if tokens > historyTokenBudget || i == maxHistoryMessages {
+			leftOut = &recent[i]
+			break
+		}
+		window = append(window, message)
+	}
+	// The turns sent to the model start with the user
+	oldest := len(window)
+	for oldest > 0 && window[oldest-1].Role != "user" {
+		oldest--
+	}
+	if oldest < len(window) {
+		dropped := window[oldest]
+		leftOut = &dropped
+		window = window[:oldest]
+	}
+	slices.Reverse(window)
+	return window, leftOut, nil
+}
+
+// summarizeInBackground updates the summary of the turn's conversation when messages
+// have left the history window, without holding up the answer. A summary that fails,
+// or is stopped with a serverless function, is made again with the next message.
+func summarizeInBackground(database *gorm.DB, provider llm.Provider, turn *chatTurn) {
+	if turn.summarizeUntil == nil {
+		return
+	}
+	go summarizeConversation(database, provider, turn)
+}
+
+func summarizeConversation(database *gorm.DB, provider llm.Provider, turn *chatTurn) {
+	ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+	defer cancel()
+	if err := summarizeHistory(ctx, database, provider, turn.conversation, *turn.summarizeUntil); err != nil {
+		log.Printf("Failed to summarize conversation %s: %v", turn.conversation.Id, err)
+	}
+}
+
+// summarizeHistory folds the messages sent after the conversation's summary and up to
+// the given time into it. Many messages are folded in over several updates.
+func summarizeHistory(ctx context.Context, database *gorm.DB, provider llm.Provider, conversation *db.Conversation, until time.Time) error {
+	query := database.Where("conversation_id = ? AND created_at <= ?", conversation.Id, until)
+	if conversation.SummarizedUntil != nil {
+		query = query.Where("created_at > ?", *conversation.SummarizedUntil)
+	}
+	var messages []db.Message
+	if err := query.Order("created_at ASC, id ASC").Limit(maxHistoryMessages).Find(&messages).Error; err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	if conversation.Summary != "" {
+		b.WriteString("Summary so far:\n" + conversation.Summary + "\n\n")
+	}
+	b.WriteString("New messages:")
+	var summarized time.Time
+	tokens := 0
+	for i, message := range messages {
+		tokens += llm.EstimateTokens(message.Content)
+		if i > 0 && tokens > summaryInputTokens {
+			break
+		}
+		author := "User"
+		if message.Role != "user" {
+			author = "Assistant"
+		}
+		fmt.Fprintf(&b, "\n\n%s: %s", author, message.Content)
+		summarized = message.CreatedAt
+	}
+
+	result, err := provider.Generate(ctx, llm.Request{
+		System: "You keep a summary of a conversation between a user and an assistant that helps with spreadsheets. " +
+			"Update the summary with the new messages. Keep the facts, numbers, decisions and open questions that later messages may refer to. " +
+			"Write it in the language of the conversation, in at most 200 words, and reply with the summary only.",
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: b.String()}},
+	})
+	if err != nil {
+		return err
+	}
+	summary := strings.TrimSpace(result.Text)
+	if summary == "" {
+		return fmt.Errorf("empty summary")
+	}
+	// Only moves the summary forward, in case another server updated it meanwhile
+	return database.Model(&db.Conversation{}).
+		Where("id = ? AND (summarized_until IS NULL OR summarized_until < ?)", conversation.Id, summarized).
+		UpdateColumns(map[string]any{"summary": summary, "summarized_until": summarized}).Error
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/utils"
+)
+
+type JWKSResponse struct {
+	Error string      `json:"error,omitempty"`
+	Keys  []utils.JWK `json:"keys"`
+}
+
+// JWKS publishes the Ed25519 public keys so other services can verify access tokens
+// without sharing a secret. Keys that only verify are listed until they are removed.
+func JWKS(c echo.Context) error {
+	keys, err := utils.PublicJWKs()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, JWKSResponse{
+			Error: "failed to load signing keys",
+		})
+	}
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, JWKSResponse{
+		Keys: keys,
+	})
+}
@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/ut-code/Raxcel/server/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// lockoutThreshold wrong passwords in a row lock the account
+	lockoutThreshold = 5
+	// the lock doubles with every further failure up to lockoutMaxDuration
+	lockoutBaseDuration = time.Minute
+	lockoutMaxDuration  = time.Hour
+)
+
+// recordFailedSignin counts a wrong password in the database itself, so guesses
+// sent in parallel each count, and locks the account past the threshold
+func recordFailedSignin(database *gorm.DB, user db.User) {
+	var updated db.User
+	err := database.Model(&updated).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_signin_count"}}}).
+		Where("id = ?", user.Id).
+		UpdateColumn("failed_signin_count", gorm.Expr("failed_signin_count + 1")).Error
+	if err != nil {
+		return
+	}
+	count := updated.FailedSigninCount
+	if count >= lockoutThreshold {
+		lockout := lockoutMaxDuration
+		if shift := count - lockoutThreshold; shift < 6 {
+			lockout = min(lockoutBaseDuration<<shift, lockoutMaxDuration)
+		}
+		database.Model(&db.User{}).Where("id = ?", user.Id).UpdateColumn("locked_until", time.Now().Add(lockout))
+	}
+}
+
+func resetFailedSignins(database *gorm.DB, user db.User) {
+	if user.FailedSigninCount == 0 && user.LockedUntil == nil {
+		return
+	}
+	database.Model(&db.User{}).Where("id = ?", user.Id).Updates(map[string]any{
+		"failed_signin_count": 0,
+		"locked_until":        nil,
+	})
+}
@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/db"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func newPasswordUser(t *testing.T, database *gorm.DB, password string) db.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := db.User{Id: "user-1", Email: "alice@example.com", PasswordHash: string(hash), IsVerified: true}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func signin(t *testing.T, email, password string) int {
+	t.Helper()
+	c, rec := newJSONContext(t, http.MethodPost, "/auth/signin", SigninRequest{Email: email, Password: password})
+	if err := Signin(c); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code
+}
+
+func TestSigninLockout(t *testing.T) {
+	useTestKeys(t)
+	database := newTestDB(t)
+	user := newPasswordUser(t, database, "correct horse")
+
+	for i := range lockoutThreshold {
+		if status := signin(t, user.Email, "wrong"); status != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want %d", i+1, status, http.StatusUnauthorized)
+		}
+	}
+	// Locked, even with the right password
+	if status := signin(t, user.Email, "correct horse"); status != http.StatusTooManyRequests {
+		t.Errorf("locked account: status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+	database.First(&user, "id = ?", user.Id)
+	if user.LockedUntil == nil || time.Until(*user.LockedUntil) > lockoutBaseDuration || time.Until(*user.LockedUntil) < lockoutBaseDuration-time.Minute/2 {
+		t.Errorf("locked until %v, want about %v from now", user.LockedUntil, lockoutBaseDuration)
+	}
+
+	// Once the lock has passed, the right password signs in and clears the failures
+	database.Model(&user).Update("locked_until", time.Now().Add(-time.Second))
+	if status := signin(t, user.Email, "correct horse"); status != http.StatusOK {
+		t.Fatalf("after the lock: status = %d, want %d", status, http.StatusOK)
+	}
+	var signedIn db.User
+	database.First(&signedIn, "id = ?", user.Id)
+	if signedIn.FailedSigninCount != 0 || signedIn.LockedUntil != nil {
+		t.Errorf("after signing in: %d failures, locked until %v", signedIn.FailedSigninCount, signedIn.LockedUntil)
+	}
+}
+
+func TestSigninResetsFailures(t *testing.T) {
+	useTestKeys(t)
+	database := newTestDB(t)
+	user := newPasswordUser(t, database, "correct horse")
+
+	for range lockoutThreshold - 1 {
+		signin(t, user.Email, "wrong")
+	}
+	if status := signin(t, user.Email, "correct horse"); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	// The count starts again, so the next failure does not lock the account
+	if status := signin(t, user.Email, "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	database.First(&user, "id = ?", user.Id)
+	if user.FailedSigninCount != 1 || user.LockedUntil != nil {
+		t.Errorf("%d failures, locked until %v, want 1 and unlocked", user.FailedSigninCount, user.LockedUntil)
+	}
+}
+
+func TestRecordFailedSigninDoublesLock(t *testing.T) {
+	database := newTestDB(t)
+	user := newPasswordUser(t, database, "correct horse")
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{lockoutThreshold - 1, 0},
+		{lockoutThreshold, lockoutBaseDuration},
+		{lockoutThreshold + 1, 2 * lockoutBaseDuration},
+		{lockoutThreshold + 2, 4 * lockoutBaseDuration},
+		{lockoutThreshold + 10, lockoutMaxDuration},
+		{lockoutThreshold + 100, lockoutMaxDuration},
+	}
+	count := 0
+	for _, tt := range tests {
+		for ; count < tt.failures; count++ {
+			recordFailedSignin(database, user)
+		}
+		database.First(&user, "id = ?", user.Id)
+		if user.FailedSigninCount != tt.failures {
+			t.Errorf("counted %d failures, want %d", user.FailedSigninCount, tt.failures)
+		}
+		var locked time.Duration
+		if user.LockedUntil != nil {
+			locked = time.Until(*user.LockedUntil).Round(time.Minute)
+		}
+		if locked != tt.want {
+			t.Errorf("after %d failures locked for %v, want %v", tt.failures, locked, tt.want)
+		}
+	}
+}
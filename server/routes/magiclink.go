@@ -0,0 +1,246 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/mail"
+	"github.com/ut-code/Raxcel/server/pages"
+	"github.com/ut-code/Raxcel/server/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	magicLinkLifetime = 15 * time.Minute
+	// maxShownUserAgentLength is how many characters of the requesting browser the confirmation page shows
+	maxShownUserAgentLength = 200
+)
+
+// magicLinkData is kept in the Data of a magic link token. The confirmation page shows
+// where the link was requested from, so that a link someone else requested is not approved.
+type magicLinkData struct {
+	IP          string    `json:"ip"`
+	UserAgent   string    `json:"userAgent"`
+	RequestedAt time.Time `json:"requestedAt"`
+}
+
+type RequestMagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+type RequestMagicLinkResponse struct {
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+	// PendingId is kept by the app and exchanged at /auth/magic-link/complete
+	// once the link in the email has been opened
+	PendingId string    `json:"pendingId,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+}
+
+// RequestMagicLink emails a single-use signin link. The response is the same whether
+// or not the account exists, so this endpoint cannot be used to find registered emails.
+func RequestMagicLink(c echo.Context) error {
+	req := new(RequestMagicLinkRequest)
+	if err := c.Bind(req); err != nil || req.Email == "" {
+		return c.JSON(http.StatusBadRequest, RequestMagicLinkResponse{
+			Error: "email is required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, RequestMagicLinkResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	pendingId := generateSecureToken()
+	expiresAt := time.Now().Add(magicLinkLifetime)
+	sent := RequestMagicLinkResponse{
+		Message:   "if the email is registered, a sign-in link has been sent",
+		PendingId: pendingId,
+		ExpiresAt: expiresAt,
+	}
+	var user db.User
+	if err := whereEmail(database, req.Email).First(&user).Error; err != nil || user.DisabledAt != nil {
+		return c.JSON(http.StatusOK, sent)
+	}
+
+	userAgent := c.Request().UserAgent()
+	if runes := []rune(userAgent); len(runes) > maxShownUserAgentLength {
+		userAgent = string(runes[:maxShownUserAgentLength])
+	}
+	data, err := json.Marshal(magicLinkData{
+		IP:          c.RealIP(),
+		UserAgent:   userAgent,
+		RequestedAt: time.Now(),
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, RequestMagicLinkResponse{
+			Error: "failed to create magic link",
+		})
+	}
+
+	tokenString := generateSecureToken()
+	err = database.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link stays valid
+		if err := tx.Where("user_id = ? AND type = ?", user.Id, db.TokenTypeMagicLink).Delete(&db.Token{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&db.Token{
+			Id:            uuid.New().String(),
+			UserId:        user.Id,
+			Token:         utils.HashToken(tokenString),
+			Type:          db.TokenTypeMagicLink,
+			PendingIdHash: utils.HashToken(pendingId),
+			Data:          string(data),
+			ExpiresAt:     expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, RequestMagicLinkResponse{
+			Error: "failed to create magic link",
+		})
+	}
+	// A failure is only logged, since an error would tell that the email is registered
+	if err := sendMagicLinkEmail(c, user.Email, tokenString); err != nil {
+		log.Printf("Failed to send magic link email: %v", err)
+	}
+	return c.JSON(http.StatusOK, sent)
+}
+
+func sendMagicLinkEmail(c echo.Context, email, token string) error {
+	apiUrl := os.Getenv("API_URL")
+	return mail.Send(c.Request().Context(), email, mail.TemplateMagicLink, requestLanguage(c), map[string]string{
+		"Link": fmt.Sprintf("%s/auth/magic-link/verify?token=%s", apiUrl, url.QueryEscape(token)),
+	})
+}
+
+// findMagicLink returns the user and the unexpired, unused magic link for a link token
+func findMagicLink(database *gorm.DB, tokenString string) (*db.Token, *db.User, bool) {
+	var token db.Token
+	if err := database.Where("token = ? AND type = ?", utils.HashToken(tokenString), db.TokenTypeMagicLink).First(&token).Error; err != nil {
+		return nil, nil, false
+	}
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, nil, false
+	}
+	var user db.User
+	if err := database.Where("id = ?", token.UserId).First(&user).Error; err != nil {
+		return nil, nil, false
+	}
+	return &token, &user, true
+}
+
+// ShowMagicLink is opened from the email. It only asks for confirmation,
+// because mail scanners that open links must not sign anyone in.
+func ShowMagicLink(c echo.Context) error {
+	reqToken := c.QueryParam("token")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return renderPage(c, http.StatusInternalServerError, pages.PageError, nil)
+	}
+	token, user, ok := findMagicLink(database, reqToken)
+	if !ok {
+		return renderPage(c, http.StatusNotFound, pages.PageMagicLinkInvalid, nil)
+	}
+	var data magicLinkData
+	if err := json.Unmarshal([]byte(token.Data), &data); err != nil {
+		return renderPage(c, http.StatusNotFound, pages.PageMagicLinkInvalid, nil)
+	}
+	return renderPage(c, http.StatusOK, pages.PageMagicLinkConfirm, map[string]any{
+		"Email":       user.Email,
+		"Token":       reqToken,
+		"IP":          data.IP,
+		"UserAgent":   data.UserAgent,
+		"RequestedAt": data.RequestedAt.UTC().Format("2006-01-02 15:04 MST"),
+	})
+}
+
+// ApproveMagicLink is posted by the confirmation page. It marks the link as used,
+// which lets the app holding the pending id complete the signin.
+func ApproveMagicLink(c echo.Context) error {
+	reqToken := c.FormValue("token")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return renderPage(c, http.StatusInternalServerError, pages.PageError, nil)
+	}
+	token, user, ok := findMagicLink(database, reqToken)
+	if !ok {
+		return renderPage(c, http.StatusNotFound, pages.PageMagicLinkInvalid, nil)
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(token).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// Receiving the link proves ownership of the email
+		return tx.Model(user).Update("is_verified", true).Error
+	})
+	if err != nil {
+		return renderPage(c, http.StatusNotFound, pages.PageMagicLinkInvalid, nil)
+	}
+	return renderPage(c, http.StatusOK, pages.PageMagicLinkApproved, map[string]any{
+		"AppLink": appLink("magic-link", nil),
+	})
+}
+
+type CompleteMagicLinkRequest struct {
+	PendingId string `json:"pendingId"`
+}
+
+// CompleteMagicLink is polled by the app with the pending id. It answers 202 until the
+// link has been opened, then signs in like /auth/signin and consumes the link.
+// Unknown ids are pending as well, so they do not reveal whether the email is registered;
+// the app stops polling when the link expires.
+func CompleteMagicLink(c echo.Context) error {
+	req := new(CompleteMagicLinkRequest)
+	if err := c.Bind(req); err != nil || req.PendingId == "" {
+		return c.JSON(http.StatusBadRequest, SigninResponse{
+			Error: "pending id is required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, SigninResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	pending := SigninResponse{
+		Pending: true,
+	}
+	var token db.Token
+	if err := database.Where("pending_id_hash = ? AND type = ?", utils.HashToken(req.PendingId), db.TokenTypeMagicLink).First(&token).Error; err != nil {
+		return c.JSON(http.StatusAccepted, pending)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		database.Delete(&token)
+		return c.JSON(http.StatusGone, SigninResponse{
+			Error: "the sign-in link has expired",
+		})
+	}
+	if token.UsedAt == nil {
+		return c.JSON(http.StatusAccepted, pending)
+	}
+	// Deleting first makes sure concurrent polls cannot both sign in
+	result := database.Delete(&token)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return c.JSON(http.StatusAccepted, pending)
+	}
+	var user db.User
+	if err := database.Where("id = ?", token.UserId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, SigninResponse{
+			Error: "user not found",
+		})
+	}
+	return completeSignin(c, database, user)
+}
@@ -0,0 +1,225 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/mail"
+	"gorm.io/gorm"
+)
+
+var magicLinkToken = regexp.MustCompile(`magic-link/verify\?token=([0-9a-f]+)`)
+
+func newMagicLinkTest(t *testing.T) (*gorm.DB, db.User) {
+	t.Helper()
+	t.Setenv("MAIL_BACKEND", "memory")
+	t.Setenv("API_URL", "https://api.example.com")
+	useTestKeys(t)
+	mail.DefaultMemoryMailer.Reset()
+	database := newTestDB(t)
+	user := db.User{Id: "user-1", Email: "alice@example.com"}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return database, user
+}
+
+// requestMagicLink asks for a link and returns the pending id and the token of the emailed link
+func requestMagicLink(t *testing.T, email string) (RequestMagicLinkResponse, string) {
+	t.Helper()
+	mail.DefaultMemoryMailer.Reset()
+	c, rec := newJSONContext(t, http.MethodPost, "/auth/magic-link", RequestMagicLinkRequest{Email: email})
+	if err := RequestMagicLink(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var res RequestMagicLinkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	var token string
+	if messages := mail.DefaultMemoryMailer.Messages(); len(messages) == 1 {
+		if match := magicLinkToken.FindStringSubmatch(messages[0].Text); match != nil {
+			token = match[1]
+		}
+	}
+	return res, token
+}
+
+func showMagicLink(t *testing.T, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/auth/magic-link/verify?token="+token, nil)
+	rec := httptest.NewRecorder()
+	if err := ShowMagicLink(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func approveMagicLink(t *testing.T, token string) int {
+	t.Helper()
+	form := url.Values{"token": {token}}
+	req := httptest.NewRequest(http.MethodPost, "/auth/magic-link/verify", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	if err := ApproveMagicLink(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code
+}
+
+func completeMagicLink(t *testing.T, pendingId string) (int, SigninResponse) {
+	t.Helper()
+	c, rec := newJSONContext(t, http.MethodPost, "/auth/magic-link/complete", CompleteMagicLinkRequest{PendingId: pendingId})
+	if err := CompleteMagicLink(c); err != nil {
+		t.Error(err)
+	}
+	var res SigninResponse
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec.Code, res
+}
+
+func TestMagicLinkSignin(t *testing.T) {
+	database, user := newMagicLinkTest(t)
+
+	requested, token := requestMagicLink(t, "ALICE@example.com")
+	if requested.PendingId == "" || token == "" {
+		t.Fatalf("pending id %q, emailed token %q", requested.PendingId, token)
+	}
+	if status, res := completeMagicLink(t, requested.PendingId); status != http.StatusAccepted || !res.Pending {
+		t.Errorf("before approval: status = %d, response = %+v", status, res)
+	}
+
+	// Opening the link only shows where it was requested from
+	rec := showMagicLink(t, token)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), user.Email) || !strings.Contains(rec.Body.String(), "192.0.2.1") {
+		t.Errorf("confirmation page: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if status, _ := completeMagicLink(t, requested.PendingId); status != http.StatusAccepted {
+		t.Errorf("after showing the page: status = %d, want %d", status, http.StatusAccepted)
+	}
+
+	if status := approveMagicLink(t, token); status != http.StatusOK {
+		t.Fatalf("approve: status = %d", status)
+	}
+	status, res := completeMagicLink(t, requested.PendingId)
+	if status != http.StatusOK || res.Token == "" || res.RefreshToken == "" {
+		t.Fatalf("after approval: status = %d, response = %+v", status, res)
+	}
+	database.First(&user, "id = ?", user.Id)
+	if !user.IsVerified {
+		t.Error("signing in with a magic link did not verify the email")
+	}
+
+	// The link and the pending id work only once
+	if status, _ := completeMagicLink(t, requested.PendingId); status != http.StatusAccepted {
+		t.Errorf("second completion: status = %d, want %d", status, http.StatusAccepted)
+	}
+	if status := approveMagicLink(t, token); status != http.StatusNotFound {
+		t.Errorf("second approval: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestMagicLinkUnknownEmail(t *testing.T) {
+	newMagicLinkTest(t)
+
+	requested, token := requestMagicLink(t, "nobody@example.com")
+	// The answer looks the same as for a registered email
+	if requested.PendingId == "" || requested.Message == "" || token != "" {
+		t.Errorf("response = %+v, emailed token %q", requested, token)
+	}
+	if status, _ := completeMagicLink(t, requested.PendingId); status != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", status, http.StatusAccepted)
+	}
+}
+
+func TestMagicLinkOnlyLatestIsValid(t *testing.T) {
+	newMagicLinkTest(t)
+
+	first, firstToken := requestMagicLink(t, "alice@example.com")
+	second, secondToken := requestMagicLink(t, "alice@example.com")
+	if status := approveMagicLink(t, firstToken); status != http.StatusNotFound {
+		t.Errorf("approving the older link: status = %d, want %d", status, http.StatusNotFound)
+	}
+	if status := approveMagicLink(t, secondToken); status != http.StatusOK {
+		t.Fatalf("approving the latest link: status = %d", status)
+	}
+	if status, _ := completeMagicLink(t, first.PendingId); status != http.StatusAccepted {
+		t.Errorf("older pending id: status = %d, want %d", status, http.StatusAccepted)
+	}
+	if status, _ := completeMagicLink(t, second.PendingId); status != http.StatusOK {
+		t.Errorf("latest pending id: status = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestMagicLinkExpires(t *testing.T) {
+	database, user := newMagicLinkTest(t)
+
+	requested, token := requestMagicLink(t, user.Email)
+	database.Model(&db.Token{}).Where("user_id = ? AND type = ?", user.Id, db.TokenTypeMagicLink).
+		Update("expires_at", time.Now().Add(-time.Minute))
+
+	if rec := showMagicLink(t, token); rec.Code != http.StatusNotFound {
+		t.Errorf("show: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if status := approveMagicLink(t, token); status != http.StatusNotFound {
+		t.Errorf("approve: status = %d, want %d", status, http.StatusNotFound)
+	}
+	if status, _ := completeMagicLink(t, requested.PendingId); status != http.StatusGone {
+		t.Errorf("complete: status = %d, want %d", status, http.StatusGone)
+	}
+	var count int64
+	database.Model(&db.Token{}).Where("type = ?", db.TokenTypeMagicLink).Count(&count)
+	if count != 0 {
+		t.Errorf("%d expired magic links left, want 0", count)
+	}
+}
+
+func TestMagicLinkConcurrentPolls(t *testing.T) {
+	database, user := newMagicLinkTest(t)
+
+	requested, token := requestMagicLink(t, user.Email)
+	if status := approveMagicLink(t, token); status != http.StatusOK {
+		t.Fatalf("approve: status = %d", status)
+	}
+
+	const polls = 5
+	statuses := make(chan int, polls)
+	var wg sync.WaitGroup
+	for range polls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, _ := completeMagicLink(t, requested.PendingId)
+			statuses <- status
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	signins := 0
+	for status := range statuses {
+		switch status {
+		case http.StatusOK:
+			signins++
+		case http.StatusAccepted:
+		default:
+			t.Errorf("status = %d", status)
+		}
+	}
+	var sessions int64
+	database.Model(&db.Session{}).Where("user_id = ?", user.Id).Count(&sessions)
+	if signins != 1 || sessions != 1 {
+		t.Errorf("%d polls signed in and %d sessions started, want 1", signins, sessions)
+	}
+}
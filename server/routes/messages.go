@@ -2,15 +2,22 @@ package routes
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/ut-code/Raxcel/server/db"
-	"google.golang.org/genai"
+	"github.com/ut-code/Raxcel/server/llm"
+	"gorm.io/gorm"
 )
 
 func Greet(c echo.Context) error {
@@ -18,36 +25,341 @@ func Greet(c echo.Context) error {
 }
 
 type ChatWithAIRequest struct {
-	Message            string `json:"message"`
-	SpreadsheetContext string `json:"spreadsheetContext,omitempty"`
+	Message string `json:"message"`
+	// Spreadsheet is the sheet the message is about, if any
+	Spreadsheet *SpreadsheetContext `json:"spreadsheet,omitempty"`
+	// OrganizationId charges the message to an organization the user belongs to
+	OrganizationId string `json:"organizationId,omitempty"`
+	// ConversationId continues a conversation; without it a new one is started
+	ConversationId string `json:"conversationId,omitempty"`
+	// Workbook links a new conversation to the workbook file it is about
+	Workbook string `json:"workbook,omitempty"`
+	// ProposeEdits lets the model answer with spreadsheet edits for the user to review
+	ProposeEdits bool `json:"proposeEdits,omitempty"`
 }
 
 type ChatWithAIResponse struct {
-	Error     string `json:"error,omitempty"`
-	AiMessage string `json:"aiMessage,omitempty"`
+	Error          string `json:"error,omitempty"`
+	AiMessage      string `json:"aiMessage,omitempty"`
+	ConversationId string `json:"conversationId,omitempty"`
+	// Title is the conversation's title, generated after the first answer
+	Title string `json:"title,omitempty"`
+	// Edits are the spreadsheet edits the model proposes, when asked with ProposeEdits
+	Edits []SpreadsheetEdit `json:"edits,omitempty"`
 }
 
 type LoadChatHistoryResponse struct {
 	Error    string       `json:"error,omitempty"`
 	Messages []db.Message `json:"messages,omitempty"`
+	// PrevCursor is passed as before to get the messages sent before this page.
+	// It is empty when the page starts the history.
+	PrevCursor string `json:"prevCursor,omitempty"`
+	// NextCursor is passed as after to get the messages sent after this page,
+	// including ones sent later. It is empty when the page is empty.
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
-func ChatWithAI(c echo.Context) error {
-	log.Println("ChatWithAI called")
+const (
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 200
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeHistoryCursor points at a message by its creation time and id, which
+// together order the history even when two messages share a timestamp
+func encodeHistoryCursor(message db.Message) string {
+	cursor := strconv.FormatInt(message.CreatedAt.UnixNano(), 10) + ":" + message.Id
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
+
+func decodeHistoryCursor(cursor string) (time.Time, string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", errInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(decoded), ":")
+	if !ok || id == "" {
+		return time.Time{}, "", errInvalidCursor
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, "", errInvalidCursor
+	}
+	// The database returns timestamps in UTC, so the cursor must compare in UTC too
+	return time.Unix(0, unixNano).UTC(), id, nil
+}
+
+// loadHistoryPage returns up to limit messages of the query in the order they were sent:
+// the ones right after the after cursor, the ones right before the before cursor,
+// or the latest ones when neither is given
+func loadHistoryPage(query *gorm.DB, before, after string, limit int) (*LoadChatHistoryResponse, error) {
+	page := &LoadChatHistoryResponse{}
+	var messages []db.Message
+	if after != "" {
+		createdAt, id, err := decodeHistoryCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		if err := query.Where("created_at > ? OR (created_at = ? AND id > ?)", createdAt, createdAt, id).
+			Order("created_at ASC, id ASC").Limit(limit).Find(&messages).Error; err != nil {
+			return nil, err
+		}
+		if len(messages) > 0 {
+			// The message at the cursor comes before this page
+			page.PrevCursor = encodeHistoryCursor(messages[0])
+		}
+	} else {
+		if before != "" {
+			createdAt, id, err := decodeHistoryCursor(before)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", createdAt, createdAt, id)
+		}
+		// One more than the page tells whether there are older messages
+		if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+			return nil, err
+		}
+		if len(messages) > limit {
+			messages = messages[:limit]
+			page.PrevCursor = encodeHistoryCursor(messages[limit-1])
+		}
+		slices.Reverse(messages)
+	}
+	if len(messages) > 0 {
+		page.NextCursor = encodeHistoryCursor(messages[len(messages)-1])
+	}
+	page.Messages = messages
+	return page, nil
+}
+
+var errChatQuotaExceeded = errors.New("chat quota exceeded")
+
+// chatTurn is a user message that has been saved and is waiting for an answer
+type chatTurn struct {
+	userId         string
+	organizationId *string
+	conversation   *db.Conversation
+	question       string
+	request        llm.Request
+	// summarizeUntil is set when the messages sent up to it left the history
+	// without being summarized
+	summarizeUntil *time.Time
+}
+
+// startChatTurn checks the request and the organization's chat quota, finds or starts the conversation,
+// saves the user message and builds the model request with the conversation so far. When ok is false the error
+// response has already been written and err is what the handler returns. The quota is only used once the
+// answer is saved, see saveAnswer.
+func startChatTurn(c echo.Context, database *gorm.DB, userId string, message *ChatWithAIRequest) (*chatTurn, bool, error) {
+	if strings.TrimSpace(message.Message) == "" {
+		return nil, false, c.JSON(http.StatusBadRequest, ChatWithAIResponse{
+			Error: "message is required",
+		})
+	}
+	var sheet *sheetGrid
+	if message.Spreadsheet != nil {
+		grid, err := newSheetGrid(message.Spreadsheet)
+		if err != nil {
+			return nil, false, c.JSON(http.StatusBadRequest, ChatWithAIResponse{
+				Error: err.Error(),
+			})
+		}
+		sheet = grid
+	}
+
+	var conversation *db.Conversation
+	if message.ConversationId != "" {
+		found, err := findConversation(database, message.ConversationId, userId)
+		if errors.Is(err, errConversationNotFound) {
+			return nil, false, c.JSON(http.StatusNotFound, ChatWithAIResponse{
+				Error: "conversation not found",
+			})
+		}
+		if err != nil {
+			return nil, false, c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
+				Error: "Failed to fetch conversation",
+			})
+		}
+		conversation = found
+	} else if len(message.Workbook) > maxWorkbookLength {
+		return nil, false, c.JSON(http.StatusBadRequest, ChatWithAIResponse{
+			Error: "workbook must be at most 1024 characters",
+		})
+	}
+
+	var organizationId *string
+	if message.OrganizationId != "" {
+		if _, err := findMembership(database, message.OrganizationId, userId); err != nil {
+			return nil, false, c.JSON(http.StatusForbidden, ChatWithAIResponse{
+				Error: "you are not a member of the organization",
+			})
+		}
+		var organization db.Organization
+		err := database.Transaction(func(tx *gorm.DB) error {
+			if err := lockOrganization(tx, message.OrganizationId, &organization); err != nil {
+				return err
+			}
+			if organization.ChatQuota == 0 {
+				return nil
+			}
+			used, err := organizationMessagesThisMonth(tx, organization.Id)
+			if err != nil {
+				return err
+			}
+			if used >= int64(organization.ChatQuota) {
+				return errChatQuotaExceeded
+			}
+			return nil
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, c.JSON(http.StatusNotFound, ChatWithAIResponse{
+				Error: "organization not found",
+			})
+		}
+		if errors.Is(err, errChatQuotaExceeded) {
+			return nil, false, c.JSON(http.StatusTooManyRequests, ChatWithAIResponse{
+				Error: "the organization has used up its chat quota for this month",
+			})
+		}
+		if err != nil {
+			log.Printf("Failed to check chat quota: %v", err)
+			return nil, false, c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
+				Error: "Failed to check chat quota",
+			})
+		}
+		organizationId = &organization.Id
+	}
+
+	if conversation == nil {
+		conversation = &db.Conversation{
+			Id:       uuid.New().String(),
+			UserId:   userId,
+			Workbook: message.Workbook,
+		}
+		if err := database.Create(conversation).Error; err != nil {
+			return nil, false, c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
+				Error: "Failed to create conversation",
+			})
+		}
+	}
+
+	// Save user message
+	userMsg := db.Message{
+		Id:             uuid.New().String(),
+		UserId:         userId,
+		ConversationId: &conversation.Id,
+		OrganizationId: organizationId,
+		Content:        message.Message,
+		Role:           "user",
+	}
+	if err := database.Create(&userMsg).Error; err != nil {
+		log.Printf("Failed to save user message: %v", err)
+		return nil, false, c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
+			Error: "Failed to save message",
+		})
+	}
 
+	// The latest messages that fit the token budget; the older ones are in the summary
+	contextMessages, leftOut, err := historyWindow(database, conversation, &userMsg)
+	if err != nil {
+		log.Printf("Failed to get recent messages: %v", err)
+	}
+	var summarizeUntil *time.Time
+	if leftOut != nil {
+		summarizeUntil = &leftOut.CreatedAt
+	}
+
+	// The spreadsheet goes into the instructions, the history becomes the turns before the message
+	var request llm.Request
+	var system []string
+	if message.ProposeEdits {
+		system = append(system, editSystemPrompt)
+		request.Tools = editTools
+	}
+	if conversation.Summary != "" {
+		system = append(system, "Summary of the earlier conversation:\n"+conversation.Summary)
+	}
+	if sheet != nil {
+		spreadsheet := sheet.serialize(spreadsheetTokenBudget)
+		system = append(system, "Current Spreadsheet Data:\n"+spreadsheet)
+	}
+	request.System = strings.Join(system, "\n\n")
+	for _, m := range contextMessages {
+		role := llm.RoleAssistant
+		if m.Role == "user" {
+			role = llm.RoleUser
+		}
+		request.Messages = append(request.Messages, llm.Message{Role: role, Content: m.Content})
+	}
+	request.Messages = append(request.Messages, llm.Message{Role: llm.RoleUser, Content: message.Message})
+
+	return &chatTurn{
+		userId:         userId,
+		organizationId: organizationId,
+		conversation:   conversation,
+		question:       message.Message,
+		request:        request,
+		summarizeUntil: summarizeUntil,
+	}, true, nil
+}
+
+// saveAnswer stores the assistant message that answers the turn, records the chat usage
+// of its organization and marks the conversation as active. An answer with only edits
+// is saved as their description.
+func saveAnswer(database *gorm.DB, turn *chatTurn, content string, edits []SpreadsheetEdit) (*db.Message, error) {
+	if content == "" && len(edits) > 0 {
+		content = describeEdits(edits)
+	}
+	assistantMsg := db.Message{
+		Id:             uuid.New().String(),
+		UserId:         turn.userId,
+		ConversationId: &turn.conversation.Id,
+		OrganizationId: turn.organizationId,
+		Content:        content,
+		Role:           "assistant",
+	}
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&assistantMsg).Error; err != nil {
+			return err
+		}
+		if turn.organizationId == nil {
+			return nil
+		}
+		return tx.Create(&db.ChatUsage{
+			Id:             uuid.New().String(),
+			OrganizationId: *turn.organizationId,
+			UserId:         turn.userId,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := database.Model(turn.conversation).UpdateColumn("updated_at", assistantMsg.CreatedAt).Error; err != nil {
+		log.Printf("Failed to update conversation: %v", err)
+	}
+	return &assistantMsg, nil
+}
+
+// nameConversation gives an untitled conversation a title generated from the turn
+func nameConversation(ctx context.Context, database *gorm.DB, provider llm.Provider, turn *chatTurn, answer string) {
+	if turn.conversation.Title != "" {
+		return
+	}
+	title := generateTitle(ctx, provider, turn.question, answer)
+	if err := database.Model(turn.conversation).UpdateColumn("title", title).Error; err != nil {
+		log.Printf("Failed to save conversation title: %v", err)
+	}
+}
+
+func ChatWithAI(c echo.Context) error {
 	// Get userId from context (set by AuthMiddleware)
 	userId, ok := c.Get("userId").(string)
-	log.Println("UserId from context:", userId, "ok:", ok)
 	if !ok {
-		log.Println("Unauthorized: userId not found in context")
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
 	}
 
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		log.Fatal("GEMINI_API_KEY is not set")
-	}
-
 	// Parse user message
 	message := new(ChatWithAIRequest)
 	if err := c.Bind(message); err != nil {
@@ -56,7 +368,6 @@ func ChatWithAI(c echo.Context) error {
 			Error: "Invalid JSON",
 		})
 	}
-	log.Println("Received message:", message.Message)
 
 	// Connect to database
 	database, err := db.ConnectDB()
@@ -64,131 +375,213 @@ func ChatWithAI(c echo.Context) error {
 		log.Println("Database connection error:", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database connection failed"})
 	}
-	log.Println("Database connected")
 
-	// Save user message
-	userMsg := db.Message{
-		Id:      uuid.New().String(),
-		UserId:  userId,
-		Content: message.Message,
-		Role:    "user",
+	turn, ok, err := startChatTurn(c, database, userId, message)
+	if !ok {
+		return err
 	}
-	log.Println("Saving user message to database...")
-	if err := database.Create(&userMsg).Error; err != nil {
-		log.Printf("Failed to save user message: %v", err)
+
+	// Setup the model provider
+	ctx := c.Request().Context()
+	provider, err := llm.New(ctx)
+	if err != nil {
+		log.Printf("Error creating LLM provider: %v", err)
 		return c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
-			Error: "Failed to save message",
+			Error: "Failed to generate content",
 		})
 	}
-	log.Println("User message saved successfully")
 
-	// Get recent messages (最新7件取得して、最後の1件=現在のメッセージを除外)
-	log.Println("Fetching recent messages for context...")
-	var recentMessages []db.Message
-	err = database.Where("user_id = ?", userId).Order("created_at DESC").Limit(7).Find(&recentMessages).Error
+	// Generate AI response
+	answer, err := provider.Generate(ctx, turn.request)
 	if err != nil {
-		log.Printf("Failed to get recent messages: %v", err)
+		log.Printf("LLM error: %v", err)
+		return c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
+			Error: "Failed to generate content",
+		})
 	}
-	log.Printf("Retrieved %d messages", len(recentMessages))
+	aiMessage := answer.Text
+	edits := editsFromToolCalls(answer.ToolCalls)
 
-	// 現在のメッセージを除外して、時系列順に並び替え
-	var contextMessages []db.Message
-	for i := len(recentMessages) - 1; i >= 1; i-- { // i >= 1 で最新(現在のメッセージ)を除外
-		contextMessages = append(contextMessages, recentMessages[i])
+	// Save AI message
+	if _, err := saveAnswer(database, turn, aiMessage, edits); err != nil {
+		log.Printf("Failed to save AI message: %v", err)
+		return c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
+			Error: "Failed to save AI message",
+		})
 	}
-	log.Printf("Using %d messages as context", len(contextMessages))
+	nameConversation(ctx, database, provider, turn, aiMessage)
+	summarizeInBackground(database, provider, turn)
 
-	// Build prompt with conversation history
-	prompt := ""
+	return c.JSON(http.StatusCreated, ChatWithAIResponse{
+		AiMessage:      aiMessage,
+		ConversationId: turn.conversation.Id,
+		Title:          turn.conversation.Title,
+		Edits:          edits,
+	})
+}
 
-	// Add spreadsheet context if provided
-	if message.SpreadsheetContext != "" {
-		prompt += "Current Spreadsheet Data:\n"
-		prompt += message.SpreadsheetContext + "\n\n"
-		log.Printf("Added spreadsheet context (%d characters)", len(message.SpreadsheetContext))
-	}
+// Events of /messages/stream. Each is sent as "event: <name>" with the JSON below as data.
+const (
+	ChatEventChunk = "chunk"
+	ChatEventDone  = "done"
+	ChatEventError = "error"
+)
 
-	if len(contextMessages) > 0 {
-		prompt += "Previous conversation:\n"
-		for _, m := range contextMessages {
-			if m.Role == "user" {
-				prompt += fmt.Sprintf("User: %s\n", m.Content)
-			} else {
-				prompt += fmt.Sprintf("Assistant: %s\n", m.Content)
-			}
-		}
-		prompt += "\nCurrent message:\n"
-	}
-	prompt += fmt.Sprintf("User: %s", message.Message)
-	log.Printf("Prompt length: %d characters", len(prompt))
+// ConversationIdHeader carries the conversation of a streamed answer before it starts
+const ConversationIdHeader = "X-Conversation-Id"
 
-	// Setup gemini client
-	log.Println("Creating Gemini client...")
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey: apiKey,
-	})
+// ChatChunkEvent is the next piece of the answer
+type ChatChunkEvent struct {
+	Text string `json:"text"`
+}
+
+// ChatDoneEvent ends the stream once the whole answer has been saved
+type ChatDoneEvent struct {
+	MessageId      string `json:"messageId"`
+	AiMessage      string `json:"aiMessage"`
+	ConversationId string `json:"conversationId"`
+	Title          string `json:"title"`
+	// Edits are the spreadsheet edits the model proposes
+	Edits []SpreadsheetEdit `json:"edits,omitempty"`
+}
+
+// ChatErrorEvent ends the stream when the answer could not be generated or saved
+type ChatErrorEvent struct {
+	Error string `json:"error"`
+}
+
+func writeChatEvent(w *echo.Response, event string, data any) error {
+	payload, err := json.Marshal(data)
 	if err != nil {
-		log.Fatal("Error creating Gemini client", err)
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
 	}
+	w.Flush()
+	return nil
+}
 
-	// Generate AI response
-	log.Println("Generating AI response...")
-	result, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash", genai.Text(prompt), nil)
+// StreamChatWithAI answers like ChatWithAI, but sends the answer as Server-Sent Events
+// while it is generated. Errors before the answer starts are plain JSON responses.
+// When the client disconnects, generation stops and the partial answer is saved,
+// so the history shows what the user saw.
+func StreamChatWithAI(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ChatWithAIResponse{
+			Error: "Unauthorized",
+		})
+	}
+	message := new(ChatWithAIRequest)
+	if err := c.Bind(message); err != nil {
+		return c.JSON(http.StatusBadRequest, ChatWithAIResponse{
+			Error: "Invalid JSON",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
+			Error: "Database connection failed",
+		})
+	}
+	turn, ok, err := startChatTurn(c, database, userId, message)
+	if !ok {
+		return err
+	}
+	ctx := c.Request().Context()
+	provider, err := llm.New(ctx)
 	if err != nil {
-		log.Printf("Gemini API error: %v", err)
+		log.Printf("Error creating LLM provider: %v", err)
 		return c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
 			Error: "Failed to generate content",
 		})
 	}
-	log.Println("AI response generated")
 
-	var aiMessage string
-	if result != nil && len(result.Candidates) > 0 {
-		candidate := result.Candidates[0]
-		if len(candidate.Content.Parts) > 0 {
-			aiMessage = candidate.Content.Parts[0].Text
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	// Stops reverse proxies such as nginx from holding the events back
+	w.Header().Set("X-Accel-Buffering", "no")
+	// Lets the client continue the conversation even if it stops reading before done
+	w.Header().Set(ConversationIdHeader, turn.conversation.Id)
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	var answer strings.Builder
+	var toolCalls []llm.ToolCall
+	for piece, err := range provider.Stream(ctx, turn.request) {
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("LLM error: %v", err)
+			return writeChatEvent(w, ChatEventError, ChatErrorEvent{
+				Error: "Failed to generate content",
+			})
+		}
+		toolCalls = append(toolCalls, piece.ToolCalls...)
+		if piece.Text == "" {
+			continue
+		}
+		answer.WriteString(piece.Text)
+		if err := writeChatEvent(w, ChatEventChunk, ChatChunkEvent{Text: piece.Text}); err != nil {
+			break
 		}
 	}
-	log.Println("AI Message content:", aiMessage)
-
-	// Save AI message
-	log.Println("Saving AI message to database...")
-	assistantMsg := db.Message{
-		Id:      uuid.New().String(),
-		UserId:  userId,
-		Content: aiMessage,
-		Role:    "assistant",
+	edits := editsFromToolCalls(toolCalls)
+	if answer.Len() == 0 && len(edits) == 0 && ctx.Err() != nil {
+		return nil
 	}
-	if err := database.Create(&assistantMsg).Error; err != nil {
+	assistantMsg, err := saveAnswer(database, turn, answer.String(), edits)
+	if err != nil {
 		log.Printf("Failed to save AI message: %v", err)
-		return c.JSON(http.StatusInternalServerError, ChatWithAIResponse{
+		return writeChatEvent(w, ChatEventError, ChatErrorEvent{
 			Error: "Failed to save AI message",
 		})
 	}
-	log.Println("AI message saved successfully")
-
-	log.Println("Returning response to client")
-	return c.JSON(http.StatusCreated, ChatWithAIResponse{
-		AiMessage: aiMessage,
+	nameConversation(ctx, database, provider, turn, assistantMsg.Content)
+	err = writeChatEvent(w, ChatEventDone, ChatDoneEvent{
+		MessageId:      assistantMsg.Id,
+		AiMessage:      assistantMsg.Content,
+		ConversationId: turn.conversation.Id,
+		Title:          turn.conversation.Title,
+		Edits:          edits,
 	})
+	summarizeInBackground(database, provider, turn)
+	return err
 }
 
+// LoadChatHistory returns a page of the user's messages, optionally of one conversation.
+// The before and after query parameters take the cursors of an earlier page, and
+// limit sets the page size.
 func LoadChatHistory(c echo.Context) error {
-	log.Println("GetMessages called")
-
 	// Get userId from context (set by AuthMiddleware)
 	userId, ok := c.Get("userId").(string)
-	log.Println("UserId from context:", userId, "ok:", ok)
 	if !ok {
-		log.Println("Unauthorized: userId not found in context")
 		return c.JSON(http.StatusUnauthorized, LoadChatHistoryResponse{
 			Error: "Unauthorized",
 		})
 	}
 
+	before, after := c.QueryParam("before"), c.QueryParam("after")
+	if before != "" && after != "" {
+		return c.JSON(http.StatusBadRequest, LoadChatHistoryResponse{
+			Error: "before and after cannot be used together",
+		})
+	}
+	limit := defaultHistoryPageSize
+	if value := c.QueryParam("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxHistoryPageSize {
+			return c.JSON(http.StatusBadRequest, LoadChatHistoryResponse{
+				Error: "limit must be between 1 and 200",
+			})
+		}
+		limit = parsed
+	}
+
 	// Connect to database
-	log.Println("Connecting to database...")
 	database, err := db.ConnectDB()
 	if err != nil {
 		log.Println("Database connection error:", err)
@@ -196,21 +589,23 @@ func LoadChatHistory(c echo.Context) error {
 			Error: "Database connection failed",
 		})
 	}
-	log.Println("Database connected")
 
-	// Get all messages for the user, ordered by creation time
-	log.Println("Fetching messages for user:", userId)
-	var messages []db.Message
-	if err := database.Where("user_id = ?", userId).Order("created_at ASC").Find(&messages).Error; err != nil {
+	query := database.Where("user_id = ?", userId)
+	if conversationId := c.QueryParam("conversationId"); conversationId != "" {
+		query = query.Where("conversation_id = ?", conversationId)
+	}
+	page, err := loadHistoryPage(query, before, after, limit)
+	if errors.Is(err, errInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, LoadChatHistoryResponse{
+			Error: "invalid cursor",
+		})
+	}
+	if err != nil {
 		log.Printf("Failed to fetch messages: %v", err)
 		return c.JSON(http.StatusInternalServerError, LoadChatHistoryResponse{
 			Error: "Failed to fetch messages",
 		})
 	}
-	log.Printf("Found %d messages", len(messages))
 
-	log.Println("Returning messages to client")
-	return c.JSON(http.StatusOK, LoadChatHistoryResponse{
-		Messages: messages,
-	})
+	return c.JSON(http.StatusOK, page)
 }
@@ -0,0 +1,201 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+)
+
+// newChatContext is a request from a signed-in user, as AuthMiddleware leaves it
+func newChatContext(t *testing.T, userId string, body any) (echo.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	c, rec := newJSONContext(t, http.MethodPost, "/messages", body)
+	c.Set("userId", userId)
+	return c, rec
+}
+
+func newChatTestUser(t *testing.T) *db.User {
+	t.Helper()
+	t.Setenv("LLM_PROVIDER", "fake")
+	for _, key := range []string{"LLM_TEMPERATURE", "LLM_TOP_P", "LLM_MAX_TOKENS"} {
+		t.Setenv(key, "")
+	}
+	database := newTestDB(t)
+	user := db.User{Id: "user-1", Email: "alice@example.com", IsVerified: true}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func TestChatWithAI(t *testing.T) {
+	user := newChatTestUser(t)
+	c, rec := newChatContext(t, user.Id, ChatWithAIRequest{Message: "How do I sum a column?"})
+
+	if err := ChatWithAI(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var res ChatWithAIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.AiMessage != "You said: How do I sum a column?" || res.ConversationId == "" || res.Title == "" {
+		t.Errorf("response = %+v", res)
+	}
+
+	database, err := db.ConnectDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []db.Message
+	database.Where("conversation_id = ?", res.ConversationId).Order("created_at, role DESC").Find(&messages)
+	if len(messages) != 2 || messages[0].Role != "user" || messages[1].Content != res.AiMessage {
+		t.Errorf("saved messages = %+v", messages)
+	}
+
+	// The conversation goes on with the history
+	c, rec = newChatContext(t, user.Id, ChatWithAIRequest{Message: "Thanks", ConversationId: res.ConversationId})
+	if err := ChatWithAI(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var count int64
+	database.Model(&db.Message{}).Where("conversation_id = ?", res.ConversationId).Count(&count)
+	if count != 4 {
+		t.Errorf("%d messages in the conversation, want 4", count)
+	}
+}
+
+func TestChatWithAIProposesEdits(t *testing.T) {
+	user := newChatTestUser(t)
+	c, rec := newChatContext(t, user.Id, ChatWithAIRequest{
+		Message:      `call set_value {"cell": "B3", "value": "10"}`,
+		ProposeEdits: true,
+	})
+
+	if err := ChatWithAI(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var res ChatWithAIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Edits) != 1 || res.Edits[0] != (SpreadsheetEdit{Type: EditSetValue, Cell: "B3", Value: "10"}) {
+		t.Errorf("edits = %+v", res.Edits)
+	}
+}
+
+func TestChatWithAIRejectsUnknownConversation(t *testing.T) {
+	user := newChatTestUser(t)
+	c, rec := newChatContext(t, user.Id, ChatWithAIRequest{Message: "Hi", ConversationId: "missing"})
+
+	if err := ChatWithAI(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestStreamChatWithAI(t *testing.T) {
+	user := newChatTestUser(t)
+	c, rec := newChatContext(t, user.Id, ChatWithAIRequest{Message: "Hello there"})
+
+	if err := StreamChatWithAI(c); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "event: chunk\ndata: {\"text\":\"You \"}\n\n") {
+		t.Errorf("no chunk event in:\n%s", body)
+	}
+	_, done, ok := strings.Cut(body, "event: done\ndata: ")
+	if !ok {
+		t.Fatalf("no done event in:\n%s", body)
+	}
+	var event ChatDoneEvent
+	if err := json.Unmarshal([]byte(strings.TrimSpace(done)), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.AiMessage != "You said: Hello there" || event.ConversationId != rec.Header().Get(ConversationIdHeader) {
+		t.Errorf("done event = %+v", event)
+	}
+}
+
+func TestChatWithAIRejectsEmptyMessage(t *testing.T) {
+	user := newChatTestUser(t)
+	c, rec := newChatContext(t, user.Id, ChatWithAIRequest{Message: "  \n"})
+
+	if err := ChatWithAI(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestChatWithAIOrganizationQuota(t *testing.T) {
+	user := newChatTestUser(t)
+	database, err := db.ConnectDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	organization := db.Organization{Id: "org-1", Name: "Acme", ChatQuota: 1}
+	if err := database.Create(&organization).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Create(&db.Membership{Id: "membership-1", OrganizationId: organization.Id, UserId: user.Id}).Error; err != nil {
+		t.Fatal(err)
+	}
+	send := func(req ChatWithAIRequest) int {
+		req.OrganizationId = organization.Id
+		c, rec := newChatContext(t, user.Id, req)
+		if err := ChatWithAI(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code
+	}
+	used := func() int64 {
+		var count int64
+		database.Model(&db.ChatUsage{}).Where("organization_id = ?", organization.Id).Count(&count)
+		return count
+	}
+
+	// Requests that are rejected or not answered do not use the quota
+	if got := send(ChatWithAIRequest{Message: "Hi", ConversationId: "missing"}); got != http.StatusNotFound {
+		t.Errorf("unknown conversation: status = %d, want %d", got, http.StatusNotFound)
+	}
+	if got := send(ChatWithAIRequest{Message: "Hi", Workbook: strings.Repeat("a", maxWorkbookLength+1)}); got != http.StatusBadRequest {
+		t.Errorf("long workbook: status = %d, want %d", got, http.StatusBadRequest)
+	}
+	t.Setenv("LLM_PROVIDER", "unknown")
+	if got := send(ChatWithAIRequest{Message: "Hi"}); got != http.StatusInternalServerError {
+		t.Errorf("failed generation: status = %d, want %d", got, http.StatusInternalServerError)
+	}
+	if n := used(); n != 0 {
+		t.Fatalf("%d messages counted after failed requests, want 0", n)
+	}
+
+	t.Setenv("LLM_PROVIDER", "fake")
+	if got := send(ChatWithAIRequest{Message: "Hi"}); got != http.StatusCreated {
+		t.Errorf("first answer: status = %d, want %d", got, http.StatusCreated)
+	}
+	if n := used(); n != 1 {
+		t.Errorf("%d messages counted, want 1", n)
+	}
+	if got := send(ChatWithAIRequest{Message: "Hi again"}); got != http.StatusTooManyRequests {
+		t.Errorf("over the quota: status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+}
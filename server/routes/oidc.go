@@ -0,0 +1,203 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/oidc"
+	"gorm.io/gorm"
+)
+
+type StartOIDCRequest struct {
+	// RedirectUri is the loopback address the desktop app listens on
+	RedirectUri   string `json:"redirectUri"`
+	State         string `json:"state"`
+	CodeChallenge string `json:"codeChallenge"`
+}
+
+type StartOIDCResponse struct {
+	Error            string `json:"error,omitempty"`
+	AuthorizationUrl string `json:"authorizationUrl,omitempty"`
+}
+
+func StartOIDC(c echo.Context) error {
+	req := new(StartOIDCRequest)
+	if err := c.Bind(req); err != nil || req.State == "" || req.CodeChallenge == "" {
+		return c.JSON(http.StatusBadRequest, StartOIDCResponse{
+			Error: "redirect uri, state and code challenge are required",
+		})
+	}
+	if !isLoopbackRedirect(req.RedirectUri) {
+		return c.JSON(http.StatusBadRequest, StartOIDCResponse{
+			Error: "redirect uri must be a loopback address",
+		})
+	}
+	provider, err := oidc.FromEnv(c.Request().Context())
+	if err != nil {
+		status, message := oidcProviderError(err)
+		return c.JSON(status, StartOIDCResponse{
+			Error: message,
+		})
+	}
+	return c.JSON(http.StatusOK, StartOIDCResponse{
+		AuthorizationUrl: provider.AuthorizationURL(req.RedirectUri, req.State, req.CodeChallenge),
+	})
+}
+
+type OIDCCallbackRequest struct {
+	Code         string `json:"code"`
+	CodeVerifier string `json:"codeVerifier"`
+	RedirectUri  string `json:"redirectUri"`
+}
+
+// OIDCCallback redeems the code the desktop app received on its loopback address
+// and signs in the linked user with the usual Raxcel tokens
+func OIDCCallback(c echo.Context) error {
+	req := new(OIDCCallbackRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" || req.CodeVerifier == "" {
+		return c.JSON(http.StatusBadRequest, SigninResponse{
+			Error: "code and code verifier are required",
+		})
+	}
+	if !isLoopbackRedirect(req.RedirectUri) {
+		return c.JSON(http.StatusBadRequest, SigninResponse{
+			Error: "redirect uri must be a loopback address",
+		})
+	}
+	provider, err := oidc.FromEnv(c.Request().Context())
+	if err != nil {
+		status, message := oidcProviderError(err)
+		return c.JSON(status, SigninResponse{
+			Error: message,
+		})
+	}
+	info, err := provider.Exchange(c.Request().Context(), req.Code, req.CodeVerifier, req.RedirectUri)
+	if err != nil {
+		log.Printf("OIDC exchange failed: %v", err)
+		return c.JSON(http.StatusUnauthorized, SigninResponse{
+			Error: "failed to sign in with the identity provider",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, SigninResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	user, err := linkIdentity(database, provider.Issuer, info)
+	if err != nil {
+		if errors.Is(err, errUnverifiedIdentityEmail) {
+			return c.JSON(http.StatusForbidden, SigninResponse{
+				Error: "the identity provider has not verified your email",
+			})
+		}
+		var policyErr *signupPolicyError
+		if errors.As(err, &policyErr) {
+			return c.JSON(http.StatusForbidden, SigninResponse{
+				Error: policyErr.Message,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, SigninResponse{
+			Error: "failed to link identity",
+		})
+	}
+	return completeSignin(c, database, *user)
+}
+
+var errUnverifiedIdentityEmail = errors.New("identity email is not verified")
+
+// linkIdentity finds the user for an external identity. A new identity is attached
+// to the user with the same email, or to a new user, but only when the provider
+// vouches for the email; otherwise anyone could claim an existing account.
+func linkIdentity(database *gorm.DB, issuer string, info *oidc.UserInfo) (*db.User, error) {
+	var identity db.Identity
+	err := database.Where("issuer = ? AND subject = ?", issuer, info.Subject).First(&identity).Error
+	if err == nil {
+		var user db.User
+		if err := database.Where("id = ?", identity.UserId).First(&user).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if info.Email == "" || !info.EmailVerified {
+		return nil, errUnverifiedIdentityEmail
+	}
+	email, ok := normalizeEmail(info.Email)
+	if !ok {
+		return nil, errUnverifiedIdentityEmail
+	}
+
+	var user db.User
+	err = database.Transaction(func(tx *gorm.DB) error {
+		err := whereEmail(tx, email).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// New accounts follow the signup policy like a signup with a password
+			if policyErr := checkSignupPolicy(tx, email); policyErr != nil {
+				return policyErr
+			}
+			// Accounts created through a provider have no password until one is reset
+			user = db.User{
+				Id:         uuid.New().String(),
+				Email:      email,
+				IsVerified: true,
+			}
+			err = tx.Create(&user).Error
+		} else if err == nil && !user.IsVerified {
+			// Whoever chose the password of an unverified account never proved they own
+			// the email, so the password is dropped rather than trusted
+			user.IsVerified = true
+			err = tx.Model(&user).Updates(map[string]any{
+				"is_verified":   true,
+				"password_hash": "",
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Create(&db.Identity{
+			Id:      uuid.New().String(),
+			UserId:  user.Id,
+			Issuer:  issuer,
+			Subject: info.Subject,
+			Email:   email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// isLoopbackRedirect only allows http://127.0.0.1, http://[::1] and http://localhost,
+// as native apps use per RFC 8252
+func isLoopbackRedirect(redirectUri string) bool {
+	u, err := url.Parse(redirectUri)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// oidcProviderError maps a failure to load the provider to a status and message
+func oidcProviderError(err error) (int, string) {
+	if errors.Is(err, oidc.ErrNotConfigured) {
+		return http.StatusNotImplemented, "sign in with an identity provider is not configured"
+	}
+	log.Printf("OIDC provider error: %v", err)
+	return http.StatusBadGateway, "failed to reach the identity provider"
+}
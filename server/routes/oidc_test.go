@@ -0,0 +1,137 @@
+package routes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/oidc"
+)
+
+const testIssuer = "https://idp.example.com"
+
+func TestLinkIdentityCreatesAccount(t *testing.T) {
+	t.Setenv("SIGNUP_MODE", "")
+	database := newTestDB(t)
+
+	user, err := linkIdentity(database, testIssuer, &oidc.UserInfo{Subject: "sub-1", Email: " Alice@Example.com ", EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The address is trimmed and its domain lowercased
+	if user.Email != "Alice@example.com" || !user.IsVerified || user.PasswordHash != "" {
+		t.Errorf("created user = %+v", user)
+	}
+
+	// The same identity signs in to the same account, whatever its email is now
+	again, err := linkIdentity(database, testIssuer, &oidc.UserInfo{Subject: "sub-1", Email: "renamed@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Id != user.Id {
+		t.Errorf("signed in as %s, want %s", again.Id, user.Id)
+	}
+	var users int64
+	database.Model(&db.User{}).Count(&users)
+	if users != 1 {
+		t.Errorf("%d users, want 1", users)
+	}
+}
+
+func TestLinkIdentityLinksVerifiedEmail(t *testing.T) {
+	database := newTestDB(t)
+	existing := db.User{Id: "user-1", Email: "alice@example.com", PasswordHash: "hash", IsVerified: true}
+	if err := database.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := linkIdentity(database, testIssuer, &oidc.UserInfo{Subject: "sub-1", Email: "ALICE@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != existing.Id {
+		t.Errorf("linked to %s, want %s", user.Id, existing.Id)
+	}
+	var identity db.Identity
+	if err := database.Where("issuer = ? AND subject = ?", testIssuer, "sub-1").First(&identity).Error; err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserId != existing.Id {
+		t.Errorf("identity user = %s, want %s", identity.UserId, existing.Id)
+	}
+	// The password of a verified account is kept
+	var reloaded db.User
+	database.Where("id = ?", existing.Id).First(&reloaded)
+	if reloaded.PasswordHash != "hash" {
+		t.Error("the password of a verified account was dropped")
+	}
+}
+
+func TestLinkIdentityDropsPasswordOfUnverifiedAccount(t *testing.T) {
+	database := newTestDB(t)
+	existing := db.User{Id: "user-1", Email: "alice@example.com", PasswordHash: "hash"}
+	if err := database.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := linkIdentity(database, testIssuer, &oidc.UserInfo{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true}); err != nil {
+		t.Fatal(err)
+	}
+	var reloaded db.User
+	database.Where("id = ?", existing.Id).First(&reloaded)
+	if !reloaded.IsVerified || reloaded.PasswordHash != "" {
+		t.Errorf("user = %+v, want it verified without a password", reloaded)
+	}
+}
+
+func TestLinkIdentityRejectsUnverifiedEmail(t *testing.T) {
+	database := newTestDB(t)
+	existing := db.User{Id: "user-1", Email: "alice@example.com", PasswordHash: "hash", IsVerified: true}
+	if err := database.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, info := range []*oidc.UserInfo{
+		{Subject: "sub-1", Email: "alice@example.com", EmailVerified: false},
+		{Subject: "sub-1", Email: "", EmailVerified: true},
+		{Subject: "sub-1", Email: "not an email", EmailVerified: true},
+	} {
+		if _, err := linkIdentity(database, testIssuer, info); !errors.Is(err, errUnverifiedIdentityEmail) {
+			t.Errorf("linkIdentity(%+v) = %v, want errUnverifiedIdentityEmail", info, err)
+		}
+	}
+	var identities int64
+	database.Model(&db.Identity{}).Count(&identities)
+	if identities != 0 {
+		t.Errorf("%d identities were linked, want 0", identities)
+	}
+}
+
+func TestLinkIdentityFollowsSignupPolicy(t *testing.T) {
+	t.Setenv("SIGNUP_MODE", SignupModeInviteOnly)
+	database := newTestDB(t)
+
+	_, err := linkIdentity(database, testIssuer, &oidc.UserInfo{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true})
+	var policyErr *signupPolicyError
+	if !errors.As(err, &policyErr) || policyErr.Code != SignupCodeInviteRequired {
+		t.Fatalf("linkIdentity = %v, want an invitation to be required", err)
+	}
+	var users int64
+	database.Model(&db.User{}).Count(&users)
+	if users != 0 {
+		t.Errorf("%d users were created, want 0", users)
+	}
+
+	// An invitation lets the address in
+	organization := db.Organization{Id: "org-1", Name: "ut.code();"}
+	if err := database.Create(&organization).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Create(&db.Invitation{Id: "invitation-1", OrganizationId: organization.Id, Email: "alice@example.com", TokenHash: "hash", InvitedBy: "user-0", ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := linkIdentity(database, testIssuer, &oidc.UserInfo{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true}); err != nil {
+		t.Errorf("linkIdentity with an invitation = %v", err)
+	}
+}
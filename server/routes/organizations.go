@@ -0,0 +1,846 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/mail"
+	"github.com/ut-code/Raxcel/server/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	invitationLifetime        = 7 * 24 * time.Hour
+	maxOrganizationNameLength = 100
+)
+
+// orgRoleRank orders the organization roles so that higher roles include the lower ones
+var orgRoleRank = map[string]int{
+	db.OrgRoleMember: 1,
+	db.OrgRoleAdmin:  2,
+	db.OrgRoleOwner:  3,
+}
+
+var errNotMember = errors.New("not a member of the organization")
+
+// findMembership returns the user's membership, or errNotMember
+func findMembership(database *gorm.DB, organizationId, userId string) (*db.Membership, error) {
+	var membership db.Membership
+	err := database.Where("organization_id = ? AND user_id = ?", organizationId, userId).First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errNotMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// requireOrgRole responds with an error unless the user has at least the role in the
+// organization. Non-members get 404 so that organization ids cannot be probed.
+func requireOrgRole(c echo.Context, database *gorm.DB, organizationId, userId, role string) (*db.Membership, bool, error) {
+	membership, err := findMembership(database, organizationId, userId)
+	if errors.Is(err, errNotMember) {
+		return nil, false, c.JSON(http.StatusNotFound, OrganizationActionResponse{
+			Error: "organization not found",
+		})
+	}
+	if err != nil {
+		return nil, false, c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to fetch membership",
+		})
+	}
+	if orgRoleRank[membership.Role] < orgRoleRank[role] {
+		return nil, false, c.JSON(http.StatusForbidden, OrganizationActionResponse{
+			Error: "you need to be an organization " + role + " to do this",
+		})
+	}
+	return membership, true, nil
+}
+
+// countOwners is used to keep at least one owner in every organization
+func countOwners(database *gorm.DB, organizationId string) (int64, error) {
+	var count int64
+	err := database.Model(&db.Membership{}).
+		Where("organization_id = ? AND role = ?", organizationId, db.OrgRoleOwner).
+		Count(&count).Error
+	return count, err
+}
+
+var errLastOwner = errors.New("an organization needs at least one owner")
+
+// lockOrganization locks the organization row until the transaction ends, so checks
+// that count its members or usage are not raced by a parallel request
+func lockOrganization(tx *gorm.DB, organizationId string, organization *db.Organization) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", organizationId).First(organization).Error
+}
+
+// requireAnotherOwner returns errLastOwner unless the organization has an owner
+// besides the one who is leaving
+func requireAnotherOwner(tx *gorm.DB, organizationId string) error {
+	var organization db.Organization
+	if err := lockOrganization(tx, organizationId, &organization); err != nil {
+		return err
+	}
+	owners, err := countOwners(tx, organizationId)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return errLastOwner
+	}
+	return nil
+}
+
+// deleteUserMemberships takes a user whose account is being deleted out of their
+// organizations. It returns errLastOwner if one of them would be left without an owner.
+func deleteUserMemberships(tx *gorm.DB, userId string) error {
+	var owned []db.Membership
+	if err := tx.Where("user_id = ? AND role = ?", userId, db.OrgRoleOwner).Find(&owned).Error; err != nil {
+		return err
+	}
+	for _, membership := range owned {
+		if err := requireAnotherOwner(tx, membership.OrganizationId); err != nil {
+			return err
+		}
+	}
+	return tx.Where("user_id = ?", userId).Delete(&db.Membership{}).Error
+}
+
+// organizationMessagesThisMonth counts the messages members sent on behalf of the organization
+// since the start of the calendar month. It counts the recorded usage, not the messages,
+// which members can delete.
+func organizationMessagesThisMonth(database *gorm.DB, organizationId string) (int64, error) {
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	var count int64
+	err := database.Model(&db.ChatUsage{}).
+		Where("organization_id = ? AND created_at >= ?", organizationId, monthStart).
+		Count(&count).Error
+	return count, err
+}
+
+type OrganizationActionResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+type OrganizationResponse struct {
+	Error        string               `json:"error,omitempty"`
+	Organization *db.Organization     `json:"organization,omitempty"`
+	Members      []OrganizationMember `json:"members,omitempty"`
+}
+
+type OrganizationMember struct {
+	UserId   string    `json:"userId"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+func CreateOrganization(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, OrganizationResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	req := new(CreateOrganizationRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, OrganizationResponse{
+			Error: "invalid format",
+		})
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxOrganizationNameLength {
+		return c.JSON(http.StatusBadRequest, OrganizationResponse{
+			Error: "name is required and must be at most 100 characters",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	organization := db.Organization{
+		Id:   uuid.New().String(),
+		Name: name,
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&organization).Error; err != nil {
+			return err
+		}
+		return tx.Create(&db.Membership{
+			Id:             uuid.New().String(),
+			OrganizationId: organization.Id,
+			UserId:         userId,
+			Role:           db.OrgRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationResponse{
+			Error: "failed to create organization",
+		})
+	}
+	return c.JSON(http.StatusCreated, OrganizationResponse{
+		Organization: &organization,
+	})
+}
+
+type OrganizationSummary struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	ChatQuota int    `json:"chatQuota"`
+}
+
+type ListOrganizationsResponse struct {
+	Error         string                `json:"error,omitempty"`
+	Organizations []OrganizationSummary `json:"organizations,omitempty"`
+}
+
+// ListOrganizations returns the organizations the user belongs to with their role in each
+func ListOrganizations(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ListOrganizationsResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ListOrganizationsResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var organizations []OrganizationSummary
+	if err := database.Table("organizations").
+		Select("organizations.id, organizations.name, organizations.chat_quota, memberships.role").
+		Joins("JOIN memberships ON memberships.organization_id = organizations.id").
+		Where("memberships.user_id = ?", userId).
+		Order("organizations.name").
+		Scan(&organizations).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, ListOrganizationsResponse{
+			Error: "failed to fetch organizations",
+		})
+	}
+	return c.JSON(http.StatusOK, ListOrganizationsResponse{
+		Organizations: organizations,
+	})
+}
+
+func GetOrganization(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, OrganizationResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	organizationId := c.Param("id")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	if _, ok, err := requireOrgRole(c, database, organizationId, userId, db.OrgRoleMember); !ok {
+		return err
+	}
+	var organization db.Organization
+	if err := database.Where("id = ?", organizationId).First(&organization).Error; err != nil {
+		return c.JSON(http.StatusNotFound, OrganizationResponse{
+			Error: "organization not found",
+		})
+	}
+	var members []OrganizationMember
+	if err := database.Table("memberships").
+		Select("memberships.user_id, users.email, memberships.role, memberships.created_at AS joined_at").
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.organization_id = ?", organizationId).
+		Order("memberships.created_at").
+		Scan(&members).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationResponse{
+			Error: "failed to fetch members",
+		})
+	}
+	return c.JSON(http.StatusOK, OrganizationResponse{
+		Organization: &organization,
+		Members:      members,
+	})
+}
+
+type UpdateOrganizationRequest struct {
+	Name *string `json:"name,omitempty"`
+	// ChatQuota of 0 removes the limit
+	ChatQuota *int `json:"chatQuota,omitempty"`
+}
+
+func UpdateOrganization(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, OrganizationResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	organizationId := c.Param("id")
+	req := new(UpdateOrganizationRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, OrganizationResponse{
+			Error: "invalid format",
+		})
+	}
+	updates := map[string]any{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > maxOrganizationNameLength {
+			return c.JSON(http.StatusBadRequest, OrganizationResponse{
+				Error: "name is required and must be at most 100 characters",
+			})
+		}
+		updates["name"] = name
+	}
+	if req.ChatQuota != nil {
+		if *req.ChatQuota < 0 {
+			return c.JSON(http.StatusBadRequest, OrganizationResponse{
+				Error: "chat quota must not be negative",
+			})
+		}
+		updates["chat_quota"] = *req.ChatQuota
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	if _, ok, err := requireOrgRole(c, database, organizationId, userId, db.OrgRoleAdmin); !ok {
+		return err
+	}
+	var organization db.Organization
+	if err := database.Where("id = ?", organizationId).First(&organization).Error; err != nil {
+		return c.JSON(http.StatusNotFound, OrganizationResponse{
+			Error: "organization not found",
+		})
+	}
+	if len(updates) > 0 {
+		if err := database.Model(&organization).Updates(updates).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, OrganizationResponse{
+				Error: "failed to update organization",
+			})
+		}
+	}
+	return c.JSON(http.StatusOK, OrganizationResponse{
+		Organization: &organization,
+	})
+}
+
+// DeleteOrganization removes the organization with its memberships and invitations.
+// Messages sent on its behalf stay with their authors.
+func DeleteOrganization(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, OrganizationActionResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	organizationId := c.Param("id")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	if _, ok, err := requireOrgRole(c, database, organizationId, userId, db.OrgRoleOwner); !ok {
+		return err
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&db.Message{}).Where("organization_id = ?", organizationId).Update("organization_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", organizationId).Delete(&db.Membership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", organizationId).Delete(&db.Invitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", organizationId).Delete(&db.Organization{}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to delete organization",
+		})
+	}
+	return c.JSON(http.StatusOK, OrganizationActionResponse{})
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+// UpdateMember changes a member's role. Only owners can do this, and the last owner cannot step down.
+func UpdateMember(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, OrganizationActionResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	organizationId := c.Param("id")
+	req := new(UpdateMemberRequest)
+	if err := c.Bind(req); err != nil || orgRoleRank[req.Role] == 0 {
+		return c.JSON(http.StatusBadRequest, OrganizationActionResponse{
+			Error: "role must be owner, admin or member",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	if _, ok, err := requireOrgRole(c, database, organizationId, userId, db.OrgRoleOwner); !ok {
+		return err
+	}
+	target, err := findMembership(database, organizationId, c.Param("userId"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, OrganizationActionResponse{
+			Error: "member not found",
+		})
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if target.Role == db.OrgRoleOwner && req.Role != db.OrgRoleOwner {
+			if err := requireAnotherOwner(tx, organizationId); err != nil {
+				return err
+			}
+		}
+		return tx.Model(target).Update("role", req.Role).Error
+	})
+	if errors.Is(err, errLastOwner) {
+		return c.JSON(http.StatusConflict, OrganizationActionResponse{
+			Error: "an organization needs at least one owner",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to update member",
+		})
+	}
+	return c.JSON(http.StatusOK, OrganizationActionResponse{})
+}
+
+// RemoveMember removes someone from the organization. Members can remove themselves,
+// admins can remove members and admins, and owners can remove anyone.
+func RemoveMember(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, OrganizationActionResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	organizationId := c.Param("id")
+	targetUserId := c.Param("userId")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	minimumRole := db.OrgRoleAdmin
+	if targetUserId == userId {
+		minimumRole = db.OrgRoleMember
+	}
+	membership, ok, err := requireOrgRole(c, database, organizationId, userId, minimumRole)
+	if !ok {
+		return err
+	}
+	target, err := findMembership(database, organizationId, targetUserId)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, OrganizationActionResponse{
+			Error: "member not found",
+		})
+	}
+	if target.Role == db.OrgRoleOwner && membership.Role != db.OrgRoleOwner {
+		return c.JSON(http.StatusForbidden, OrganizationActionResponse{
+			Error: "only owners can remove owners",
+		})
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if target.Role == db.OrgRoleOwner {
+			if err := requireAnotherOwner(tx, organizationId); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(target).Error
+	})
+	if errors.Is(err, errLastOwner) {
+		return c.JSON(http.StatusConflict, OrganizationActionResponse{
+			Error: "an organization needs at least one owner",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to remove member",
+		})
+	}
+	return c.JSON(http.StatusOK, OrganizationActionResponse{})
+}
+
+type OrganizationUsageResponse struct {
+	Error             string `json:"error,omitempty"`
+	ChatQuota         int    `json:"chatQuota"`
+	MessagesThisMonth int64  `json:"messagesThisMonth"`
+}
+
+func GetOrganizationUsage(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, OrganizationUsageResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	organizationId := c.Param("id")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationUsageResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	if _, ok, err := requireOrgRole(c, database, organizationId, userId, db.OrgRoleMember); !ok {
+		return err
+	}
+	var organization db.Organization
+	if err := database.Where("id = ?", organizationId).First(&organization).Error; err != nil {
+		return c.JSON(http.StatusNotFound, OrganizationUsageResponse{
+			Error: "organization not found",
+		})
+	}
+	used, err := organizationMessagesThisMonth(database, organizationId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationUsageResponse{
+			Error: "failed to count messages",
+		})
+	}
+	return c.JSON(http.StatusOK, OrganizationUsageResponse{
+		ChatQuota:         organization.ChatQuota,
+		MessagesThisMonth: used,
+	})
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type InvitationResponse struct {
+	Error      string         `json:"error,omitempty"`
+	Invitation *db.Invitation `json:"invitation,omitempty"`
+}
+
+// CreateInvitation emails a code that lets the address join the organization.
+// Only owners can invite new owners.
+func CreateInvitation(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, InvitationResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	organizationId := c.Param("id")
+	req := new(CreateInvitationRequest)
+	if err := c.Bind(req); err != nil || req.Email == "" {
+		return c.JSON(http.StatusBadRequest, InvitationResponse{
+			Error: "email is required",
+		})
+	}
+	if req.Role == "" {
+		req.Role = db.OrgRoleMember
+	}
+	if orgRoleRank[req.Role] == 0 {
+		return c.JSON(http.StatusBadRequest, InvitationResponse{
+			Error: "role must be owner, admin or member",
+		})
+	}
+	email, ok := normalizeEmail(req.Email)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, InvitationResponse{
+			Error: "invalid email address",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, InvitationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	membership, ok, err := requireOrgRole(c, database, organizationId, userId, db.OrgRoleAdmin)
+	if !ok {
+		return err
+	}
+	if orgRoleRank[req.Role] > orgRoleRank[membership.Role] {
+		return c.JSON(http.StatusForbidden, InvitationResponse{
+			Error: "you cannot invite someone with a higher role than yours",
+		})
+	}
+	var organization db.Organization
+	if err := database.Where("id = ?", organizationId).First(&organization).Error; err != nil {
+		return c.JSON(http.StatusNotFound, InvitationResponse{
+			Error: "organization not found",
+		})
+	}
+	var inviter db.User
+	if err := database.Where("id = ?", userId).First(&inviter).Error; err != nil {
+		return c.JSON(http.StatusNotFound, InvitationResponse{
+			Error: "user not found",
+		})
+	}
+	var count int64
+	database.Model(&db.Membership{}).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.organization_id = ? AND LOWER(users.email) = LOWER(?)", organizationId, email).
+		Count(&count)
+	if count > 0 {
+		return c.JSON(http.StatusConflict, InvitationResponse{
+			Error: "the user is already a member",
+		})
+	}
+
+	tokenString := generateSecureToken()
+	invitation := db.Invitation{
+		Id:             uuid.New().String(),
+		OrganizationId: organizationId,
+		Email:          email,
+		Role:           req.Role,
+		TokenHash:      utils.HashToken(tokenString),
+		InvitedBy:      userId,
+		ExpiresAt:      time.Now().Add(invitationLifetime),
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		// Inviting the same address again replaces the previous invitation
+		if err := whereEmail(tx, email).Where("organization_id = ?", organizationId).Delete(&db.Invitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, InvitationResponse{
+			Error: "failed to create invitation",
+		})
+	}
+	if err := sendInvitationEmail(c, email, tokenString, organization.Name, inviter.Email); err != nil {
+		log.Printf("Failed to send invitation email: %v", err)
+		database.Delete(&invitation)
+		return c.JSON(http.StatusInternalServerError, InvitationResponse{
+			Error: "failed to send invitation email",
+		})
+	}
+	return c.JSON(http.StatusCreated, InvitationResponse{
+		Invitation: &invitation,
+	})
+}
+
+func sendInvitationEmail(c echo.Context, email, token, organization, inviter string) error {
+	return mail.Send(c.Request().Context(), email, mail.TemplateInvitation, requestLanguage(c), map[string]string{
+		"Code":         token,
+		"Organization": organization,
+		"Inviter":      inviter,
+	})
+}
+
+type ListInvitationsResponse struct {
+	Error       string          `json:"error,omitempty"`
+	Invitations []db.Invitation `json:"invitations,omitempty"`
+}
+
+func ListInvitations(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ListInvitationsResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	organizationId := c.Param("id")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ListInvitationsResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	if _, ok, err := requireOrgRole(c, database, organizationId, userId, db.OrgRoleAdmin); !ok {
+		return err
+	}
+	var invitations []db.Invitation
+	if err := database.Where("organization_id = ? AND expires_at > ?", organizationId, time.Now()).
+		Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, ListInvitationsResponse{
+			Error: "failed to fetch invitations",
+		})
+	}
+	return c.JSON(http.StatusOK, ListInvitationsResponse{
+		Invitations: invitations,
+	})
+}
+
+func RevokeInvitation(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, OrganizationActionResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	organizationId := c.Param("id")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	if _, ok, err := requireOrgRole(c, database, organizationId, userId, db.OrgRoleAdmin); !ok {
+		return err
+	}
+	result := database.Where("id = ? AND organization_id = ?", c.Param("invitationId"), organizationId).Delete(&db.Invitation{})
+	if result.Error != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to revoke invitation",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, OrganizationActionResponse{
+			Error: "invitation not found",
+		})
+	}
+	return c.JSON(http.StatusOK, OrganizationActionResponse{})
+}
+
+type RespondToInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+type AcceptInvitationResponse struct {
+	Error          string `json:"error,omitempty"`
+	OrganizationId string `json:"organizationId,omitempty"`
+}
+
+// findInvitation loads a valid invitation addressed to the signed-in user. The email must
+// match so that a forwarded code cannot be used by someone else.
+func findInvitation(database *gorm.DB, token, userId string) (*db.Invitation, *db.User, int, string) {
+	var user db.User
+	if err := database.Where("id = ?", userId).First(&user).Error; err != nil {
+		return nil, nil, http.StatusNotFound, "user not found"
+	}
+	var invitation db.Invitation
+	if err := database.Where("token_hash = ?", utils.HashToken(strings.TrimSpace(token))).First(&invitation).Error; err != nil {
+		return nil, nil, http.StatusNotFound, "invalid invitation"
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		database.Delete(&invitation)
+		return nil, nil, http.StatusBadRequest, "invitation has expired"
+	}
+	if !user.IsVerified || !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, nil, http.StatusForbidden, "this invitation was sent to a different email"
+	}
+	return &invitation, &user, http.StatusOK, ""
+}
+
+func AcceptInvitation(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, AcceptInvitationResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	req := new(RespondToInvitationRequest)
+	if err := c.Bind(req); err != nil || req.Token == "" {
+		return c.JSON(http.StatusBadRequest, AcceptInvitationResponse{
+			Error: "invitation code is required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, AcceptInvitationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	invitation, user, status, message := findInvitation(database, req.Token, userId)
+	if invitation == nil {
+		return c.JSON(status, AcceptInvitationResponse{
+			Error: message,
+		})
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(invitation)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(&db.Membership{
+			Id:             uuid.New().String(),
+			OrganizationId: invitation.OrganizationId,
+			UserId:         user.Id,
+			Role:           invitation.Role,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, AcceptInvitationResponse{
+				Error: "you are already a member",
+			})
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, AcceptInvitationResponse{
+				Error: "invalid invitation",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, AcceptInvitationResponse{
+			Error: "failed to accept invitation",
+		})
+	}
+	return c.JSON(http.StatusOK, AcceptInvitationResponse{
+		OrganizationId: invitation.OrganizationId,
+	})
+}
+
+func DeclineInvitation(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, OrganizationActionResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	req := new(RespondToInvitationRequest)
+	if err := c.Bind(req); err != nil || req.Token == "" {
+		return c.JSON(http.StatusBadRequest, OrganizationActionResponse{
+			Error: "invitation code is required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	invitation, _, status, message := findInvitation(database, req.Token, userId)
+	if invitation == nil {
+		return c.JSON(status, OrganizationActionResponse{
+			Error: message,
+		})
+	}
+	if err := database.Delete(invitation).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, OrganizationActionResponse{
+			Error: "failed to decline invitation",
+		})
+	}
+	return c.JSON(http.StatusOK, OrganizationActionResponse{})
+}
@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ut-code/Raxcel/server/db"
+	"gorm.io/gorm"
+)
+
+func createInvitation(t *testing.T, email string) (int, InvitationResponse) {
+	t.Helper()
+	c, rec := newJSONContext(t, http.MethodPost, "/orgs/org-1/invitations", CreateInvitationRequest{Email: email})
+	c.SetParamNames("id")
+	c.SetParamValues("org-1")
+	c.Set("userId", "user-1")
+	if err := CreateInvitation(c); err != nil {
+		t.Fatal(err)
+	}
+	var res InvitationResponse
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec.Code, res
+}
+
+func newOrganizationTest(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("MAIL_BACKEND", "memory")
+	database := newTestDB(t)
+	for _, record := range []any{
+		&db.User{Id: "user-1", Email: "alice@example.com", IsVerified: true},
+		&db.Organization{Id: "org-1", Name: "Acme"},
+		&db.Membership{Id: "membership-1", OrganizationId: "org-1", UserId: "user-1", Role: db.OrgRoleOwner},
+	} {
+		if err := database.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database
+}
+
+func TestCreateInvitationNormalizesEmail(t *testing.T) {
+	database := newOrganizationTest(t)
+
+	status, res := createInvitation(t, " Bob@Example.COM ")
+	if status != http.StatusCreated && status != http.StatusOK {
+		t.Fatalf("status = %d, response = %+v", status, res)
+	}
+	// Addresses are normalized as at signup: trimmed, with the domain lowercased
+	if res.Invitation == nil || res.Invitation.Email != "Bob@example.com" {
+		t.Errorf("invitation = %+v", res.Invitation)
+	}
+
+	// The same address in another case replaces the invitation
+	if status, _ := createInvitation(t, "bob@EXAMPLE.com"); status != http.StatusCreated && status != http.StatusOK {
+		t.Errorf("second invitation: status = %d", status)
+	}
+	var count int64
+	database.Model(&db.Invitation{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d invitations, want 1", count)
+	}
+
+	// Members are recognized in any case
+	if status, _ := createInvitation(t, "ALICE@example.com"); status != http.StatusConflict {
+		t.Errorf("inviting a member: status = %d, want %d", status, http.StatusConflict)
+	}
+	if status, _ := createInvitation(t, "Bob <bob@example.com>"); status != http.StatusBadRequest {
+		t.Errorf("invalid address: status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestInvitationLetsSignupInWhateverTheCase(t *testing.T) {
+	newOrganizationTest(t)
+	t.Setenv("SIGNUP_MODE", SignupModeInviteOnly)
+	t.Setenv("SIGNUP_BLOCKED_DOMAINS", "")
+
+	database, err := db.ConnectDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policyErr := checkSignupPolicy(database, "bob@example.com"); policyErr == nil || policyErr.Code != SignupCodeInviteRequired {
+		t.Fatalf("before the invitation: %v, want an invitation to be required", policyErr)
+	}
+	createInvitation(t, "Bob@Example.com")
+	for _, email := range []string{"Bob@example.com", "bob@example.com", "BOB@EXAMPLE.COM"} {
+		if policyErr := checkSignupPolicy(database, email); policyErr != nil {
+			t.Errorf("signup of %s: %v", email, policyErr)
+		}
+	}
+}
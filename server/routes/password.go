@@ -0,0 +1,163 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const passwordResetTokenLifetime = time.Hour
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ForgotPasswordResponse struct {
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func ForgotPassword(c echo.Context) error {
+	req := new(ForgotPasswordRequest)
+	if err := c.Bind(req); err != nil || req.Email == "" {
+		return c.JSON(http.StatusBadRequest, ForgotPasswordResponse{
+			Error: "email is required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ForgotPasswordResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	// The response is the same whether or not the account exists,
+	// so this endpoint cannot be used to find registered emails
+	sent := ForgotPasswordResponse{
+		Message: "if the email is registered, a password reset email has been sent",
+	}
+	var user db.User
+	if err := whereEmail(database, req.Email).First(&user).Error; err != nil {
+		return c.JSON(http.StatusOK, sent)
+	}
+
+	tokenString := generateSecureToken()
+	err = database.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent reset email stays valid
+		if err := tx.Where("user_id = ? AND type = ?", user.Id, db.TokenTypePasswordReset).Delete(&db.Token{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&db.Token{
+			Id:        uuid.New().String(),
+			UserId:    user.Id,
+			Token:     utils.HashToken(tokenString),
+			Type:      db.TokenTypePasswordReset,
+			ExpiresAt: time.Now().Add(passwordResetTokenLifetime),
+		}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ForgotPasswordResponse{
+			Error: "failed to create password reset token",
+		})
+	}
+	// A failure is only logged, since an error would tell that the email is registered
+	if err := sendPasswordResetEmail(c, user.Email, tokenString); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+	return c.JSON(http.StatusOK, sent)
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ResetPasswordResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func ResetPassword(c echo.Context) error {
+	req := new(ResetPasswordRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ResetPasswordResponse{
+			Error: "invalid format",
+		})
+	}
+	if req.Token == "" || req.Password == "" {
+		return c.JSON(http.StatusBadRequest, ResetPasswordResponse{
+			Error: "token and password are required",
+		})
+	}
+	if len(req.Password) < 8 {
+		return c.JSON(http.StatusBadRequest, ResetPasswordResponse{
+			Error: "password must be at least 8 characters",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ResetPasswordResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var token db.Token
+	if err := database.Where("token = ? AND type = ?", utils.HashToken(req.Token), db.TokenTypePasswordReset).First(&token).Error; err != nil {
+		return c.JSON(http.StatusBadRequest, ResetPasswordResponse{
+			Error: "invalid password reset token",
+		})
+	}
+	if time.Now().After(token.ExpiresAt) {
+		database.Delete(&token)
+		return c.JSON(http.StatusBadRequest, ResetPasswordResponse{
+			Error: "password reset token has expired",
+		})
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ResetPasswordResponse{
+			Error: "failed to hash password",
+		})
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		// Deleting the token first makes it single-use even under concurrent requests
+		result := tx.Delete(&token)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// Receiving the email proves ownership of the address
+		if err := tx.Model(&db.User{}).Where("id = ?", token.UserId).Updates(map[string]any{
+			"password_hash":       string(hashedPassword),
+			"is_verified":         true,
+			"failed_signin_count": 0,
+			"locked_until":        nil,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND type = ?", token.UserId, db.TokenTypePasswordReset).Delete(&db.Token{}).Error; err != nil {
+			return err
+		}
+		// Sign out every device and token that was using the old password
+		return signOutEverywhere(tx, token.UserId)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusBadRequest, ResetPasswordResponse{
+				Error: "invalid password reset token",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, ResetPasswordResponse{
+			Error: "failed to reset password",
+		})
+	}
+	return c.JSON(http.StatusOK, ResetPasswordResponse{})
+}
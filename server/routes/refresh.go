@@ -0,0 +1,137 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/utils"
+	"gorm.io/gorm"
+)
+
+const refreshTokenLifetime = 30 * 24 * time.Hour
+
+type sessionTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// issueSessionTokens signs a new access token for the session and stores a new refresh token in its family
+func issueSessionTokens(database *gorm.DB, userId, sessionId string) (*sessionTokens, error) {
+	accessToken, expiresAt, err := utils.GenerateJWT(userId, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken := generateSecureToken()
+	refreshExpiresAt := time.Now().Add(refreshTokenLifetime)
+	token := db.Token{
+		Id:        uuid.New().String(),
+		UserId:    userId,
+		Token:     utils.HashToken(refreshToken),
+		Type:      db.TokenTypeRefresh,
+		FamilyId:  sessionId,
+		ExpiresAt: refreshExpiresAt,
+	}
+	if err := database.Create(&token).Error; err != nil {
+		return nil, err
+	}
+	// The session lives as long as its newest refresh token
+	if err := database.Model(&db.Session{}).Where("id = ?", sessionId).Updates(map[string]any{
+		"expires_at":   refreshExpiresAt,
+		"last_seen_at": time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &sessionTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshResponse struct {
+	Error        string    `json:"error,omitempty"`
+	Token        string    `json:"token,omitempty"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt,omitzero"`
+}
+
+func RefreshToken(c echo.Context) error {
+	req := new(RefreshRequest)
+	if err := c.Bind(req); err != nil || req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, RefreshResponse{
+			Error: "refresh token is required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, RefreshResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var token db.Token
+	if err := database.Where("token = ? AND type = ?", utils.HashToken(req.RefreshToken), db.TokenTypeRefresh).First(&token).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, RefreshResponse{
+			Error: "invalid refresh token",
+		})
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return c.JSON(http.StatusUnauthorized, RefreshResponse{
+			Error: "refresh token has expired",
+		})
+	}
+	var session db.Session
+	if err := database.Where("id = ?", token.FamilyId).First(&session).Error; err != nil || session.RevokedAt != nil {
+		return c.JSON(http.StatusUnauthorized, RefreshResponse{
+			Error: "session has been revoked",
+		})
+	}
+	// Disabling revokes the sessions, but the account is checked as at signin in case one was left
+	var user db.User
+	if err := database.Where("id = ?", token.UserId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, RefreshResponse{
+			Error: "invalid refresh token",
+		})
+	}
+	if user.DisabledAt != nil {
+		return c.JSON(http.StatusForbidden, RefreshResponse{
+			Error: "account has been disabled",
+		})
+	}
+
+	// A refresh token can be exchanged only once. Presenting a used one means it
+	// was copied, so the whole session is revoked and the user must sign in again.
+	result := database.Model(&db.Token{}).
+		Where("id = ? AND used_at IS NULL", token.Id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return c.JSON(http.StatusInternalServerError, RefreshResponse{
+			Error: "failed to rotate refresh token",
+		})
+	}
+	if result.RowsAffected == 0 {
+		revokeSessions(database, token.UserId, token.FamilyId)
+		return c.JSON(http.StatusUnauthorized, RefreshResponse{
+			Error: "refresh token reuse detected",
+		})
+	}
+
+	tokens, err := issueSessionTokens(database, token.UserId, token.FamilyId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, RefreshResponse{
+			Error: "failed to issue tokens",
+		})
+	}
+	return c.JSON(http.StatusOK, RefreshResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+	})
+}
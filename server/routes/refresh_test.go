@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/utils"
+	"gorm.io/gorm"
+)
+
+// newSignedInUser creates a user and starts a session for them
+func newSignedInUser(t *testing.T, database *gorm.DB) (db.User, *sessionTokens) {
+	t.Helper()
+	useTestKeys(t)
+	user := db.User{Id: "user-1", Email: "alice@example.com", IsVerified: true}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user, startTestSession(t, database, user)
+}
+
+// startTestSession signs the user in on another device
+func startTestSession(t *testing.T, database *gorm.DB, user db.User) *sessionTokens {
+	t.Helper()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/auth/signin", nil), httptest.NewRecorder())
+	tokens, err := startSession(c, database, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func refresh(t *testing.T, refreshToken string) (int, RefreshResponse) {
+	t.Helper()
+	c, rec := newJSONContext(t, http.MethodPost, "/auth/refresh", RefreshRequest{RefreshToken: refreshToken})
+	if err := RefreshToken(c); err != nil {
+		t.Fatal(err)
+	}
+	var res RefreshResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, res
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	database := newTestDB(t)
+	user, tokens := newSignedInUser(t, database)
+	_, sessionId, err := utils.ValidateJWT(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, res := refresh(t, tokens.RefreshToken)
+	if status != http.StatusOK || res.Token == "" || res.RefreshToken == "" || res.RefreshToken == tokens.RefreshToken {
+		t.Fatalf("status = %d, response = %+v", status, res)
+	}
+	// The new tokens belong to the same session
+	userId, refreshedSessionId, err := utils.ValidateJWT(res.Token)
+	if err != nil || userId != user.Id || refreshedSessionId != sessionId {
+		t.Errorf("refreshed access token is for %s, %s (%v), want %s, %s", userId, refreshedSessionId, err, user.Id, sessionId)
+	}
+
+	// The rotated token keeps working
+	status, again := refresh(t, res.RefreshToken)
+	if status != http.StatusOK || again.RefreshToken == "" {
+		t.Errorf("second refresh: status = %d, response = %+v", status, again)
+	}
+	var family int64
+	database.Model(&db.Token{}).Where("family_id = ? AND type = ?", sessionId, db.TokenTypeRefresh).Count(&family)
+	if family != 3 {
+		t.Errorf("%d refresh tokens in the family, want 3", family)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	database := newTestDB(t)
+	user, tokens := newSignedInUser(t, database)
+
+	status, rotated := refresh(t, tokens.RefreshToken)
+	if status != http.StatusOK {
+		t.Fatalf("refresh: status = %d, response = %+v", status, rotated)
+	}
+
+	// The used token comes back, so someone copied it
+	if status, res := refresh(t, tokens.RefreshToken); status != http.StatusUnauthorized || res.Error != "refresh token reuse detected" {
+		t.Errorf("reuse: status = %d, response = %+v", status, res)
+	}
+	// Whoever holds the newest token is signed out too
+	if status, _ := refresh(t, rotated.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("newest token after reuse: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	var session db.Session
+	if err := database.Where("user_id = ?", user.Id).First(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	var left int64
+	database.Model(&db.Token{}).Where("family_id = ?", session.Id).Count(&left)
+	if session.RevokedAt == nil || left != 0 {
+		t.Errorf("session revoked at %v with %d refresh tokens left, want it revoked with none", session.RevokedAt, left)
+	}
+}
+
+func TestRefreshTokenReuseKeepsOtherSessions(t *testing.T) {
+	database := newTestDB(t)
+	user, tokens := newSignedInUser(t, database)
+	other := startTestSession(t, database, user)
+
+	refresh(t, tokens.RefreshToken)
+	refresh(t, tokens.RefreshToken)
+	if status, _ := refresh(t, other.RefreshToken); status != http.StatusOK {
+		t.Errorf("another device: status = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestRefreshTokenRejected(t *testing.T) {
+	database := newTestDB(t)
+	user, tokens := newSignedInUser(t, database)
+
+	if status, _ := refresh(t, "unknown"); status != http.StatusUnauthorized {
+		t.Errorf("unknown token: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	database.Model(&db.Token{}).Where("token = ?", utils.HashToken(tokens.RefreshToken)).
+		Update("expires_at", time.Now().Add(-time.Minute))
+	if status, res := refresh(t, tokens.RefreshToken); status != http.StatusUnauthorized || res.Error != "refresh token has expired" {
+		t.Errorf("expired token: status = %d, response = %+v", status, res)
+	}
+
+	tokens = startTestSession(t, database, user)
+	database.Model(&user).Update("disabled_at", time.Now())
+	if status, _ := refresh(t, tokens.RefreshToken); status != http.StatusForbidden {
+		t.Errorf("disabled account: status = %d, want %d", status, http.StatusForbidden)
+	}
+}
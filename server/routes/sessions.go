@@ -0,0 +1,184 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"gorm.io/gorm"
+)
+
+// startSession records the device that just signed in and issues its first tokens.
+// The desktop app names itself with the X-Device-Name header.
+func startSession(c echo.Context, database *gorm.DB, user db.User) (*sessionTokens, error) {
+	session := db.Session{
+		Id:         uuid.New().String(),
+		UserId:     user.Id,
+		DeviceName: c.Request().Header.Get("X-Device-Name"),
+		IpAddress:  c.RealIP(),
+		UserAgent:  c.Request().UserAgent(),
+		LastSeenAt: time.Now(),
+		ExpiresAt:  time.Now().Add(refreshTokenLifetime),
+	}
+	if err := database.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return issueSessionTokens(database, user.Id, session.Id)
+}
+
+// revokeSessions revokes the given sessions of the user, or all of them when no ids are given,
+// and deletes their refresh tokens. Access tokens stop working at the next request.
+func revokeSessions(database *gorm.DB, userId string, sessionIds ...string) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		sessions := tx.Model(&db.Session{}).Where("user_id = ? AND revoked_at IS NULL", userId)
+		tokens := tx.Where("user_id = ? AND type = ?", userId, db.TokenTypeRefresh)
+		if len(sessionIds) > 0 {
+			sessions = sessions.Where("id IN ?", sessionIds)
+			tokens = tokens.Where("family_id IN ?", sessionIds)
+		}
+		if err := sessions.Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tokens.Delete(&db.Token{}).Error
+	})
+}
+
+// signOutEverywhere revokes every session of the user and deletes their personal access
+// tokens, which would otherwise keep working for whoever holds one
+func signOutEverywhere(database *gorm.DB, userId string) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&db.PersonalAccessToken{}).Error; err != nil {
+			return err
+		}
+		return revokeSessions(tx, userId)
+	})
+}
+
+// revokeOtherSessions signs out every device of the user except the one making the request
+func revokeOtherSessions(database *gorm.DB, userId, currentSessionId string) error {
+	var sessionIds []string
+	if err := database.Model(&db.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userId, currentSessionId).
+		Pluck("id", &sessionIds).Error; err != nil {
+		return err
+	}
+	if len(sessionIds) == 0 {
+		return nil
+	}
+	return revokeSessions(database, userId, sessionIds...)
+}
+
+type SessionInfo struct {
+	Id         string    `json:"id"`
+	DeviceName string    `json:"deviceName"`
+	IpAddress  string    `json:"ipAddress"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	// Current is true for the session that made the request
+	Current bool `json:"current"`
+}
+
+type ListSessionsResponse struct {
+	Error    string        `json:"error,omitempty"`
+	Sessions []SessionInfo `json:"sessions,omitempty"`
+}
+
+func ListSessions(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ListSessionsResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	currentSessionId, _ := c.Get("sessionId").(string)
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ListSessionsResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var sessions []db.Session
+	if err := database.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, ListSessionsResponse{
+			Error: "failed to fetch sessions",
+		})
+	}
+	infos := make([]SessionInfo, len(sessions))
+	for i, s := range sessions {
+		infos[i] = SessionInfo{
+			Id:         s.Id,
+			DeviceName: s.DeviceName,
+			IpAddress:  s.IpAddress,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    s.Id == currentSessionId,
+		}
+	}
+	return c.JSON(http.StatusOK, ListSessionsResponse{
+		Sessions: infos,
+	})
+}
+
+type RevokeSessionResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// RevokeSession signs out one device. The id "current" refers to the session making the request.
+func RevokeSession(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, RevokeSessionResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	sessionId := c.Param("id")
+	if sessionId == "current" {
+		sessionId, _ = c.Get("sessionId").(string)
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, RevokeSessionResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var session db.Session
+	if err := database.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionId, userId).First(&session).Error; err != nil {
+		return c.JSON(http.StatusNotFound, RevokeSessionResponse{
+			Error: "session not found",
+		})
+	}
+	if err := revokeSessions(database, userId, session.Id); err != nil {
+		return c.JSON(http.StatusInternalServerError, RevokeSessionResponse{
+			Error: "failed to revoke session",
+		})
+	}
+	return c.JSON(http.StatusOK, RevokeSessionResponse{})
+}
+
+// RevokeAllSessions signs out everywhere, including the device making the request,
+// and deletes the personal access tokens
+func RevokeAllSessions(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, RevokeSessionResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, RevokeSessionResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	if err := signOutEverywhere(database, userId); err != nil {
+		return c.JSON(http.StatusInternalServerError, RevokeSessionResponse{
+			Error: "failed to revoke sessions",
+		})
+	}
+	return c.JSON(http.StatusOK, RevokeSessionResponse{})
+}
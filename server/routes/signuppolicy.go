@@ -0,0 +1,169 @@
+package routes
+
+import (
+	_ "embed"
+	"log"
+	"net/mail"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/db"
+	"gorm.io/gorm"
+)
+
+// SIGNUP_MODE decides who may create an account
+const (
+	SignupModeOpen = "open"
+	// Only addresses with a pending organization invitation
+	SignupModeInviteOnly = "invite_only"
+	// Only addresses in SIGNUP_ALLOWED_DOMAINS, or with a pending invitation
+	SignupModeDomain = "domain"
+)
+
+// Codes in SignupResponse that tell the app why a signup was rejected
+const (
+	SignupCodeInvalidEmail     = "invalid_email"
+	SignupCodeWeakPassword     = "weak_password"
+	SignupCodeEmailTaken       = "email_taken"
+	SignupCodeInviteRequired   = "invite_required"
+	SignupCodeDomainNotAllowed = "domain_not_allowed"
+	SignupCodeDisposableEmail  = "disposable_email"
+)
+
+type signupPolicyError struct {
+	Code    string
+	Message string
+}
+
+func (e *signupPolicyError) Error() string {
+	return e.Message
+}
+
+//go:embed disposable_domains.txt
+var disposableDomainList string
+
+var disposableDomains = sync.OnceValue(func() map[string]bool {
+	domains := make(map[string]bool)
+	lines := strings.Split(disposableDomainList, "\n")
+	lines = append(lines, splitList(os.Getenv("SIGNUP_BLOCKED_DOMAINS"))...)
+	for _, line := range lines {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			domains[line] = true
+		}
+	}
+	return domains
+})
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// whereEmail finds accounts by address regardless of case, as mail servers deliver
+// them, which is also how the unique index on lower(email) compares addresses
+func whereEmail(database *gorm.DB, email string) *gorm.DB {
+	return database.Where("lower(email) = lower(?)", strings.TrimSpace(email))
+}
+
+// normalizeEmail checks the syntax of an address without looking up its domain.
+// It trims the address and lowercases the domain; the local part is kept as typed.
+func normalizeEmail(email string) (string, bool) {
+	email = strings.TrimSpace(email)
+	if len(email) > 254 {
+		return "", false
+	}
+	// ParseAddress also accepts "Name <addr>", which is not an email address here
+	parsed, err := mail.ParseAddress(email)
+	if err != nil || parsed.Address != email {
+		return "", false
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || strings.ContainsAny(domain, "[]") {
+		return "", false
+	}
+	domain = strings.ToLower(domain)
+	if !strings.Contains(domain, ".") {
+		return "", false
+	}
+	return local + "@" + domain, true
+}
+
+func emailDomain(email string) string {
+	return email[strings.LastIndex(email, "@")+1:]
+}
+
+// matchesDomain reports whether domain is one of domains or a subdomain of one
+func matchesDomain(domain string, domains map[string]bool) bool {
+	for d := domain; d != ""; {
+		if domains[d] {
+			return true
+		}
+		_, d, _ = strings.Cut(d, ".")
+	}
+	return false
+}
+
+func signupMode() string {
+	mode := os.Getenv("SIGNUP_MODE")
+	switch mode {
+	case "", SignupModeOpen:
+		return SignupModeOpen
+	case SignupModeInviteOnly, SignupModeDomain:
+		return mode
+	}
+	// An unknown mode is treated as the strictest one rather than letting everyone in
+	log.Printf("Unknown SIGNUP_MODE %q, only invited users can sign up", mode)
+	return SignupModeInviteOnly
+}
+
+// checkEmailDomain applies the rules on the domain of a normalized address. They apply
+// whenever an account gets a new address, including email changes.
+func checkEmailDomain(email string) *signupPolicyError {
+	domain := emailDomain(email)
+	if matchesDomain(domain, disposableDomains()) {
+		return &signupPolicyError{
+			Code:    SignupCodeDisposableEmail,
+			Message: "disposable email addresses cannot be used",
+		}
+	}
+	if signupMode() == SignupModeDomain {
+		allowed := make(map[string]bool)
+		for _, d := range splitList(strings.ToLower(os.Getenv("SIGNUP_ALLOWED_DOMAINS"))) {
+			allowed[d] = true
+		}
+		if !matchesDomain(domain, allowed) {
+			return &signupPolicyError{
+				Code:    SignupCodeDomainNotAllowed,
+				Message: "email addresses of this domain cannot sign up",
+			}
+		}
+	}
+	return nil
+}
+
+// checkSignupPolicy decides whether a new account may be created for a normalized address.
+// A pending organization invitation lets the address in whatever the mode is.
+func checkSignupPolicy(database *gorm.DB, email string) *signupPolicyError {
+	var invitations int64
+	whereEmail(database.Model(&db.Invitation{}), email).
+		Where("expires_at > ?", time.Now()).
+		Count(&invitations)
+	if invitations > 0 {
+		return nil
+	}
+	if signupMode() == SignupModeInviteOnly {
+		return &signupPolicyError{
+			Code:    SignupCodeInviteRequired,
+			Message: "signup requires an invitation",
+		}
+	}
+	return checkEmailDomain(email)
+}
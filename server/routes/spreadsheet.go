@@ -0,0 +1,390 @@
+package routes
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ut-code/Raxcel/server/llm"
+)
+
+// SpreadsheetCell is a cell of the sheet sent with a message
+type SpreadsheetCell struct {
+	// Cell is the A1 reference, such as "B3"
+	Cell string `json:"cell"`
+	// Value is what the cell shows
+	Value string `json:"value"`
+	// Formula is what the value is computed from, starting with "="
+	Formula string `json:"formula,omitempty"`
+}
+
+// SpreadsheetContext is the sheet the user is working on, sent with a message so the
+// model can answer about it. Sheets too large for the prompt are summarized.
+type SpreadsheetContext struct {
+	Sheet string            `json:"sheet,omitempty"`
+	Cells []SpreadsheetCell `json:"cells"`
+	// Selection is the A1 references of the selected cells, whose rows are always shown to the model
+	Selection []string `json:"selection,omitempty"`
+	// HeaderRow is the 1-based row that names the columns, or 0 when there is none
+	HeaderRow int `json:"headerRow,omitempty"`
+}
+
+const (
+	// spreadsheetTokenBudget is about how many tokens of the prompt the sheet may take
+	spreadsheetTokenBudget = 6000
+	maxSpreadsheetCells    = 100000
+	// maxShownCellLength is how many characters of a value are shown before it is cut
+	maxShownCellLength = 200
+	// maxListedSelection is how many selected cells are listed when they are not a range
+	maxListedSelection = 50
+	// columnSamples is how many distinct texts a column summary quotes
+	columnSamples = 3
+)
+
+// sheetGrid is a SpreadsheetContext with its references parsed. Rows and columns are 1-based.
+type sheetGrid struct {
+	name string
+	// cells are the non-empty cells by row, then column
+	cells        map[int]map[int]SpreadsheetCell
+	rows         []int
+	columns      []int
+	selection    []string
+	selectedRows map[int]bool
+	headerRow    int
+}
+
+// parseCellRef returns the 1-based column and row of an A1 reference
+func parseCellRef(ref string) (column, row int, ok bool) {
+	if !cellPattern.MatchString(ref) {
+		return 0, 0, false
+	}
+	digits := strings.IndexFunc(ref, unicode.IsDigit)
+	for _, letter := range ref[:digits] {
+		column = column*26 + int(letter-'A'+1)
+	}
+	row, err := strconv.Atoi(ref[digits:])
+	return column, row, err == nil
+}
+
+// columnName returns the letters of a 1-based column: A to Z, then AA, AB and so on
+func columnName(column int) string {
+	var name []byte
+	for column > 0 {
+		column--
+		name = append([]byte{byte('A' + column%26)}, name...)
+		column /= 26
+	}
+	return string(name)
+}
+
+// newSheetGrid checks the references of the sheet and arranges its cells
+func newSheetGrid(sheet *SpreadsheetContext) (*sheetGrid, error) {
+	if len(sheet.Cells) > maxSpreadsheetCells || len(sheet.Selection) > maxSpreadsheetCells {
+		return nil, fmt.Errorf("the spreadsheet must have at most %d cells", maxSpreadsheetCells)
+	}
+	if sheet.HeaderRow < 0 {
+		return nil, fmt.Errorf("invalid header row %d", sheet.HeaderRow)
+	}
+	grid := &sheetGrid{
+		name:         sheet.Sheet,
+		cells:        map[int]map[int]SpreadsheetCell{},
+		selection:    sheet.Selection,
+		selectedRows: map[int]bool{},
+		headerRow:    sheet.HeaderRow,
+	}
+	columns := map[int]bool{}
+	for _, cell := range sheet.Cells {
+		column, row, ok := parseCellRef(cell.Cell)
+		if !ok {
+			return nil, fmt.Errorf("invalid cell %q", cell.Cell)
+		}
+		if cell.Value == "" && cell.Formula == "" {
+			continue
+		}
+		if grid.cells[row] == nil {
+			grid.cells[row] = map[int]SpreadsheetCell{}
+			grid.rows = append(grid.rows, row)
+		}
+		grid.cells[row][column] = cell
+		columns[column] = true
+	}
+	for _, ref := range sheet.Selection {
+		_, row, ok := parseCellRef(ref)
+		if !ok {
+			return nil, fmt.Errorf("invalid selected cell %q", ref)
+		}
+		grid.selectedRows[row] = true
+	}
+	slices.Sort(grid.rows)
+	grid.columns = slices.Sorted(maps.Keys(columns))
+	return grid, nil
+}
+
+// cutValue shortens long values
+func cutValue(value string) string {
+	if runes := []rune(value); len(runes) > maxShownCellLength {
+		return string(runes[:maxShownCellLength]) + "…"
+	}
+	return value
+}
+
+// showValue shortens long values and keeps each row on one line
+func showValue(value string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ", "\r", " ").Replace(cutValue(value))
+}
+
+// row writes the cells of a row as "A2: Alice | B2: 42 (=SUM(C2:D2))", which
+// only costs tokens for the cells that have something in them
+func (g *sheetGrid) row(row int) string {
+	var cells []string
+	for _, column := range slices.Sorted(maps.Keys(g.cells[row])) {
+		cell := g.cells[row][column]
+		text := columnName(column) + strconv.Itoa(row) + ": " + showValue(cell.Value)
+		if cell.Formula != "" {
+			text += " (" + showValue(cell.Formula) + ")"
+		}
+		cells = append(cells, text)
+	}
+	return strings.Join(cells, " | ")
+}
+
+// describeSelection writes the selection as a range when it is a rectangle, and as a list otherwise
+func (g *sheetGrid) describeSelection() string {
+	cells := map[[2]int]bool{}
+	minColumn, minRow, maxColumn, maxRow := 0, 0, 0, 0
+	for _, ref := range g.selection {
+		column, row, _ := parseCellRef(ref)
+		if len(cells) == 0 {
+			minColumn, minRow, maxColumn, maxRow = column, row, column, row
+		}
+		minColumn, maxColumn = min(minColumn, column), max(maxColumn, column)
+		minRow, maxRow = min(minRow, row), max(maxRow, row)
+		cells[[2]int{column, row}] = true
+	}
+	start := columnName(minColumn) + strconv.Itoa(minRow)
+	if len(cells) == 1 {
+		return start
+	}
+	if len(cells) == (maxColumn-minColumn+1)*(maxRow-minRow+1) {
+		return start + ":" + columnName(maxColumn) + strconv.Itoa(maxRow)
+	}
+	if len(g.selection) > maxListedSelection {
+		return strings.Join(g.selection[:maxListedSelection], ", ") +
+			fmt.Sprintf(" and %d more", len(g.selection)-maxListedSelection)
+	}
+	return strings.Join(g.selection, ", ")
+}
+
+// heading describes the sheet before its rows
+func (g *sheetGrid) heading() []string {
+	var lines []string
+	if g.name != "" {
+		lines = append(lines, "Sheet: "+g.name)
+	}
+	if g.headerRow > 0 {
+		lines = append(lines, fmt.Sprintf("Row %d names the columns.", g.headerRow))
+	}
+	if len(g.selection) > 0 {
+		lines = append(lines, "Selected cells: "+g.describeSelection())
+	}
+	if len(g.rows) == 0 {
+		lines = append(lines, "The sheet is empty.")
+	}
+	return lines
+}
+
+type columnStats struct {
+	values   int
+	numbers  int
+	low      float64
+	high     float64
+	sum      float64
+	texts    []string
+	formulas int
+	formula  string
+}
+
+// columnSummaries describe the values of each column, for sheets whose rows are not all shown
+func (g *sheetGrid) columnSummaries() []string {
+	stats := map[int]*columnStats{}
+	for _, row := range g.rows {
+		if row == g.headerRow {
+			continue
+		}
+		for column, cell := range g.cells[row] {
+			s := stats[column]
+			if s == nil {
+				s = &columnStats{}
+				stats[column] = s
+			}
+			s.values++
+			if cell.Formula != "" {
+				if s.formulas == 0 {
+					s.formula = cell.Formula
+				}
+				s.formulas++
+			}
+			if number, err := strconv.ParseFloat(strings.TrimSpace(cell.Value), 64); err == nil {
+				if s.numbers == 0 {
+					s.low, s.high = number, number
+				}
+				s.low, s.high = min(s.low, number), max(s.high, number)
+				s.sum += number
+				s.numbers++
+			} else if cell.Value != "" && len(s.texts) < columnSamples && !slices.Contains(s.texts, cell.Value) {
+				s.texts = append(s.texts, cell.Value)
+			}
+		}
+	}
+	formatNumber := func(number float64) string {
+		return strconv.FormatFloat(number, 'g', 6, 64)
+	}
+	var lines []string
+	for _, column := range g.columns {
+		line := "- " + columnName(column)
+		if header, ok := g.cells[g.headerRow][column]; ok {
+			line += fmt.Sprintf(" %q", cutValue(header.Value))
+		}
+		s := stats[column]
+		if s == nil {
+			lines = append(lines, line+": no values")
+			continue
+		}
+		line += fmt.Sprintf(": %d values", s.values)
+		if s.numbers > 0 {
+			line += fmt.Sprintf(", %d numbers from %s to %s averaging %s",
+				s.numbers, formatNumber(s.low), formatNumber(s.high), formatNumber(s.sum/float64(s.numbers)))
+		}
+		if len(s.texts) > 0 {
+			var quoted []string
+			for _, text := range s.texts {
+				quoted = append(quoted, fmt.Sprintf("%q", cutValue(text)))
+			}
+			line += ", texts such as " + strings.Join(quoted, ", ")
+		}
+		if s.formulas > 0 {
+			line += fmt.Sprintf(", %d formulas such as %s", s.formulas, showValue(s.formula))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// sampleRows picks n rows spread evenly from first to last
+func sampleRows(rows []int, n int) []int {
+	if n >= len(rows) {
+		return rows
+	}
+	if n == 1 {
+		return rows[:1]
+	}
+	picked := make([]int, 0, n)
+	for i := range n {
+		picked = append(picked, rows[i*(len(rows)-1)/(n-1)])
+	}
+	return picked
+}
+
+// fitRows picks as many rows as fit in about budget tokens, spread evenly from first to last
+func fitRows(rows []int, rowTokens map[int]int, budget int) []int {
+	if budget <= 0 || len(rows) == 0 {
+		return nil
+	}
+	average := 0
+	for _, row := range rows {
+		average += rowTokens[row]
+	}
+	average = max(average/len(rows), 1)
+	// Rows differ in length, so fewer are sampled until they fit
+	for n := min(budget/average, len(rows)); n > 0; n = n * 9 / 10 {
+		sampled := sampleRows(rows, n)
+		tokens := 0
+		for _, row := range sampled {
+			tokens += rowTokens[row]
+		}
+		if tokens <= budget {
+			return sampled
+		}
+	}
+	return nil
+}
+
+// serialize writes the sheet for the prompt in about budget tokens. A sheet that does
+// not fit is summarized by column, with the header row, the rows of the selected
+// cells and as many other rows, sampled evenly, as fit the rest of the budget.
+// The rows of a large selection are sampled too, within half of the budget.
+func (g *sheetGrid) serialize(budget int) string {
+	lines := g.heading()
+	rowTokens := map[int]int{}
+	total := llm.EstimateTokens(strings.Join(lines, "\n"))
+	for _, row := range g.rows {
+		rowTokens[row] = llm.EstimateTokens(g.row(row)) + 1
+		total += rowTokens[row]
+	}
+	if total <= budget {
+		for _, row := range g.rows {
+			lines = append(lines, g.row(row))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	// The column summaries may take up to half of the budget
+	used := llm.EstimateTokens(strings.Join(lines, "\n"))
+	lines = append(lines, "Columns:")
+	summaries := g.columnSummaries()
+	for i, summary := range summaries {
+		tokens := llm.EstimateTokens(summary) + 1
+		if used+tokens > budget/2 {
+			lines = append(lines, fmt.Sprintf("- and %d more columns", len(summaries)-i))
+			break
+		}
+		lines = append(lines, summary)
+		used += tokens
+	}
+
+	kept := map[int]bool{}
+	var selected, others []int
+	selectedTokens := 0
+	for _, row := range g.rows {
+		switch {
+		case row == g.headerRow:
+			kept[row] = true
+			used += rowTokens[row]
+		case g.selectedRows[row]:
+			selected = append(selected, row)
+			selectedTokens += rowTokens[row]
+		default:
+			others = append(others, row)
+		}
+	}
+	// A large selection would leave no room for the rest of the sheet
+	shownSelected := selected
+	if selectedTokens > budget/2 {
+		shownSelected = fitRows(selected, rowTokens, budget/2)
+		selectedTokens = 0
+		for _, row := range shownSelected {
+			selectedTokens += rowTokens[row]
+		}
+	}
+	for _, row := range shownSelected {
+		kept[row] = true
+	}
+	used += selectedTokens
+	for _, row := range fitRows(others, rowTokens, budget-used) {
+		kept[row] = true
+	}
+
+	lines = append(lines, fmt.Sprintf("Rows (%d of the %d rows with values, the others are left out):", len(kept), len(g.rows)))
+	if len(shownSelected) < len(selected) {
+		lines = append(lines, fmt.Sprintf("The selection is too large to show: %d of its %d rows are shown, sampled evenly.", len(shownSelected), len(selected)))
+	}
+	for _, row := range g.rows {
+		if kept[row] {
+			lines = append(lines, g.row(row))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ut-code/Raxcel/server/llm"
+)
+
+// newTestSheet has a header row and rows numbered 2 to rows+1 of a name, an amount and a formula
+func newTestSheet(t *testing.T, rows int, selection []string) *sheetGrid {
+	t.Helper()
+	sheet := &SpreadsheetContext{
+		Sheet:     "Sales",
+		HeaderRow: 1,
+		Selection: selection,
+		Cells: []SpreadsheetCell{
+			{Cell: "A1", Value: "Customer"},
+			{Cell: "B1", Value: "Amount"},
+			{Cell: "C1", Value: "With tax"},
+		},
+	}
+	for row := 2; row <= rows+1; row++ {
+		r := strconv.Itoa(row)
+		sheet.Cells = append(sheet.Cells,
+			SpreadsheetCell{Cell: "A" + r, Value: fmt.Sprintf("Customer number %d", row)},
+			SpreadsheetCell{Cell: "B" + r, Value: strconv.Itoa(row * 10)},
+			SpreadsheetCell{Cell: "C" + r, Value: strconv.Itoa(row * 11), Formula: "=B" + r + "*1.1"},
+		)
+	}
+	grid, err := newSheetGrid(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return grid
+}
+
+// shownRows returns the rows the serialized sheet shows
+func shownRows(serialized string) map[int]bool {
+	rows := map[int]bool{}
+	for _, line := range strings.Split(serialized, "\n") {
+		if _, row, ok := parseCellRef(strings.SplitN(line, ":", 2)[0]); ok && strings.HasPrefix(line, "A") {
+			rows[row] = true
+		}
+	}
+	return rows
+}
+
+func TestSerializeSmallSheet(t *testing.T) {
+	grid := newTestSheet(t, 3, []string{"B3"})
+	want := strings.Join([]string{
+		"Sheet: Sales",
+		"Row 1 names the columns.",
+		"Selected cells: B3",
+		"A1: Customer | B1: Amount | C1: With tax",
+		"A2: Customer number 2 | B2: 20 | C2: 22 (=B2*1.1)",
+		"A3: Customer number 3 | B3: 30 | C3: 33 (=B3*1.1)",
+		"A4: Customer number 4 | B4: 40 | C4: 44 (=B4*1.1)",
+	}, "\n")
+	if got := grid.serialize(spreadsheetTokenBudget); got != want {
+		t.Errorf("serialize =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSerializeStaysWithinBudget(t *testing.T) {
+	for _, budget := range []int{500, 2000, spreadsheetTokenBudget} {
+		grid := newTestSheet(t, 5000, []string{"B2500", "C2500", "A4000"})
+		got := grid.serialize(budget)
+		if tokens := llm.EstimateTokens(got); tokens > budget {
+			t.Errorf("budget %d: serialized in %d tokens", budget, tokens)
+		}
+		if !strings.Contains(got, "Columns:") || !strings.Contains(got, `- B "Amount": 5000 values, 5000 numbers from 20 to 50010`) {
+			t.Errorf("budget %d: no column summary in\n%s", budget, got)
+		}
+		// The header row and the rows of the selected cells are always shown
+		rows := shownRows(got)
+		for _, row := range []int{1, 2500, 4000} {
+			if !rows[row] {
+				t.Errorf("budget %d: row %d is not shown", budget, row)
+			}
+		}
+		if len(rows) < 4 {
+			t.Errorf("budget %d: only %d rows shown", budget, len(rows))
+		}
+	}
+}
+
+func TestSerializeSamplesLargeSelection(t *testing.T) {
+	var selection []string
+	for row := 1001; row <= 4000; row++ {
+		selection = append(selection, "B"+strconv.Itoa(row))
+	}
+	grid := newTestSheet(t, 5000, selection)
+	got := grid.serialize(spreadsheetTokenBudget)
+
+	if tokens := llm.EstimateTokens(got); tokens > spreadsheetTokenBudget {
+		t.Errorf("serialized in %d tokens, over the budget of %d", tokens, spreadsheetTokenBudget)
+	}
+	if !strings.Contains(got, "Selected cells: B1001:B4000") {
+		t.Error("the selection is not described as a range")
+	}
+	rows := shownRows(got)
+	selected, others := 0, 0
+	for row := range rows {
+		if row >= 1001 && row <= 4000 {
+			selected++
+		} else if row != 1 {
+			others++
+		}
+	}
+	// The sample spans the whole selection and leaves room for the rest of the sheet
+	if !rows[1001] || !rows[4000] || selected < 10 || others == 0 {
+		t.Errorf("shown %d selected rows (first %v, last %v) and %d others", selected, rows[1001], rows[4000], others)
+	}
+	note := fmt.Sprintf("The selection is too large to show: %d of its 3000 rows are shown, sampled evenly.", selected)
+	if !strings.Contains(got, note) {
+		t.Errorf("missing %q in\n%s", note, got)
+	}
+}
+
+func TestFitRows(t *testing.T) {
+	rows := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tokens := map[int]int{}
+	for _, row := range rows {
+		tokens[row] = 10
+	}
+	tokens[10] = 50
+	for _, budget := range []int{0, 25, 60, 100, 1000} {
+		fitted := fitRows(rows, tokens, budget)
+		used := 0
+		for _, row := range fitted {
+			used += tokens[row]
+		}
+		if used > budget {
+			t.Errorf("budget %d: fitted rows %v take %d tokens", budget, fitted, used)
+		}
+		if budget >= 140 && len(fitted) != len(rows) {
+			t.Errorf("budget %d: fitted %v, want every row", budget, fitted)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package routes
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	middleware "github.com/ut-code/Raxcel/server/middlewares"
+	"github.com/ut-code/Raxcel/server/utils"
+)
+
+const (
+	maxTokenNameLength = 100
+	// tokenDisplayPrefixLength keeps the rxp_ prefix and a few characters of the secret
+	tokenDisplayPrefixLength = 12
+)
+
+type CreatePersonalAccessTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays is optional; tokens without it never expire
+	ExpiresInDays int `json:"expiresInDays,omitempty"`
+}
+
+type CreatePersonalAccessTokenResponse struct {
+	Error string `json:"error,omitempty"`
+	// Token is only returned here and cannot be shown again
+	Token               string                  `json:"token,omitempty"`
+	PersonalAccessToken *db.PersonalAccessToken `json:"personalAccessToken,omitempty"`
+}
+
+func CreatePersonalAccessToken(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, CreatePersonalAccessTokenResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	req := new(CreatePersonalAccessTokenRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, CreatePersonalAccessTokenResponse{
+			Error: "invalid format",
+		})
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxTokenNameLength {
+		return c.JSON(http.StatusBadRequest, CreatePersonalAccessTokenResponse{
+			Error: "name is required and must be at most 100 characters",
+		})
+	}
+	if len(req.Scopes) == 0 {
+		return c.JSON(http.StatusBadRequest, CreatePersonalAccessTokenResponse{
+			Error: "at least one scope is required",
+		})
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(middleware.Scopes, scope) {
+			return c.JSON(http.StatusBadRequest, CreatePersonalAccessTokenResponse{
+				Error: "unknown scope " + scope,
+			})
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		return c.JSON(http.StatusBadRequest, CreatePersonalAccessTokenResponse{
+			Error: "expiry must not be negative",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, CreatePersonalAccessTokenResponse{
+			Error: "failed to connect to database",
+		})
+	}
+
+	tokenString := middleware.PersonalAccessTokenPrefix + generateSecureToken()
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	token := db.PersonalAccessToken{
+		Id:        uuid.New().String(),
+		UserId:    userId,
+		Name:      name,
+		TokenHash: utils.HashToken(tokenString),
+		Prefix:    tokenString[:tokenDisplayPrefixLength],
+		Scopes:    strings.Join(slices.Compact(scopes), " "),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := database.Create(&token).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, CreatePersonalAccessTokenResponse{
+			Error: "failed to create token",
+		})
+	}
+	return c.JSON(http.StatusCreated, CreatePersonalAccessTokenResponse{
+		Token:               tokenString,
+		PersonalAccessToken: &token,
+	})
+}
+
+type ListPersonalAccessTokensResponse struct {
+	Error                string                   `json:"error,omitempty"`
+	PersonalAccessTokens []db.PersonalAccessToken `json:"personalAccessTokens,omitempty"`
+}
+
+func ListPersonalAccessTokens(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ListPersonalAccessTokensResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ListPersonalAccessTokensResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var tokens []db.PersonalAccessToken
+	if err := database.Where("user_id = ?", userId).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, ListPersonalAccessTokensResponse{
+			Error: "failed to fetch tokens",
+		})
+	}
+	return c.JSON(http.StatusOK, ListPersonalAccessTokensResponse{
+		PersonalAccessTokens: tokens,
+	})
+}
+
+type RevokePersonalAccessTokenResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func RevokePersonalAccessToken(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, RevokePersonalAccessTokenResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, RevokePersonalAccessTokenResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	result := database.Where("id = ? AND user_id = ?", c.Param("id"), userId).Delete(&db.PersonalAccessToken{})
+	if result.Error != nil {
+		return c.JSON(http.StatusInternalServerError, RevokePersonalAccessTokenResponse{
+			Error: "failed to revoke token",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, RevokePersonalAccessTokenResponse{
+			Error: "token not found",
+		})
+	}
+	return c.JSON(http.StatusOK, RevokePersonalAccessTokenResponse{})
+}
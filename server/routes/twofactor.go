@@ -0,0 +1,341 @@
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	middleware "github.com/ut-code/Raxcel/server/middlewares"
+	"github.com/ut-code/Raxcel/server/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorChallengeLifetime = 5 * time.Minute
+	recoveryCodeCount          = 10
+)
+
+// generateRecoveryCodes replaces the user's recovery codes and returns the new ones in plain text
+func generateRecoveryCodes(tx *gorm.DB, userId string) ([]string, error) {
+	if err := tx.Where("user_id = ?", userId).Delete(&db.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		rand.Read(b)
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+		if err := tx.Create(&db.RecoveryCode{
+			Id:       uuid.New().String(),
+			UserId:   userId,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(codes[i])),
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery code.
+// Both are consumed so the same code cannot be replayed.
+func checkSecondFactor(database *gorm.DB, user db.User, code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) == 6 {
+		step, ok := utils.MatchTOTP(user.TotpSecret, code, time.Now())
+		if !ok {
+			return false
+		}
+		result := database.Model(&db.User{}).
+			Where("id = ? AND totp_last_step < ?", user.Id, step).
+			Update("totp_last_step", step)
+		return result.Error == nil && result.RowsAffected == 1
+	}
+	result := database.Model(&db.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.Id, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// issueTwoFactorChallenge is called after a correct password for an account with 2FA enabled
+func issueTwoFactorChallenge(database *gorm.DB, user db.User) (string, error) {
+	challenge := generateSecureToken()
+	err := database.Create(&db.Token{
+		Id:        uuid.New().String(),
+		UserId:    user.Id,
+		Token:     utils.HashToken(challenge),
+		Type:      db.TokenTypeTwoFactorChallenge,
+		ExpiresAt: time.Now().Add(twoFactorChallengeLifetime),
+	}).Error
+	return challenge, err
+}
+
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+	// Code is a 6 digit TOTP code or a recovery code
+	Code string `json:"code"`
+}
+
+func VerifyTwoFactor(c echo.Context) error {
+	req := new(VerifyTwoFactorRequest)
+	if err := c.Bind(req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, SigninResponse{
+			Error: "challenge token and code are required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, SigninResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var challenge db.Token
+	if err := database.Where("token = ? AND type = ?", utils.HashToken(req.ChallengeToken), db.TokenTypeTwoFactorChallenge).First(&challenge).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, SigninResponse{
+			Error: "invalid two-factor challenge, please sign in again",
+		})
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		database.Delete(&challenge)
+		return c.JSON(http.StatusUnauthorized, SigninResponse{
+			Error: "two-factor challenge has expired, please sign in again",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", challenge.UserId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, SigninResponse{
+			Error: "user not found",
+		})
+	}
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return middleware.TooManyRequests(c, time.Until(*user.LockedUntil), "account is temporarily locked after too many failed signins")
+	}
+	if user.DisabledAt != nil {
+		return c.JSON(http.StatusForbidden, SigninResponse{
+			Error: "account has been disabled",
+		})
+	}
+	// Wrong codes count towards the same lockout as wrong passwords
+	if !checkSecondFactor(database, user, req.Code) {
+		recordFailedSignin(database, user)
+		return c.JSON(http.StatusUnauthorized, SigninResponse{
+			Error: "invalid two-factor code",
+		})
+	}
+	if err := database.Delete(&challenge).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, SigninResponse{
+			Error: "failed to complete signin",
+		})
+	}
+	resetFailedSignins(database, user)
+	return respondWithSessionTokens(c, database, user)
+}
+
+type SetupTwoFactorRequest struct {
+	// Password is required for accounts that have one, see confirmIdentity
+	Password string `json:"password"`
+}
+
+type SetupTwoFactorResponse struct {
+	Error      string `json:"error,omitempty"`
+	Secret     string `json:"secret,omitempty"`
+	OtpauthUrl string `json:"otpauthUrl,omitempty"`
+}
+
+func SetupTwoFactor(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, SetupTwoFactorResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	sessionId, _ := c.Get("sessionId").(string)
+	req := new(SetupTwoFactorRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, SetupTwoFactorResponse{
+			Error: "invalid format",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, SetupTwoFactorResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", userId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, SetupTwoFactorResponse{
+			Error: "user not found",
+		})
+	}
+	if user.TotpEnabled {
+		return c.JSON(http.StatusConflict, SetupTwoFactorResponse{
+			Error: "two-factor authentication is already enabled",
+		})
+	}
+	if message := confirmIdentity(database, user, sessionId, req.Password, ""); message != "" {
+		return c.JSON(http.StatusUnauthorized, SetupTwoFactorResponse{
+			Error: message,
+		})
+	}
+	secret := utils.GenerateTOTPSecret()
+	if err := database.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, SetupTwoFactorResponse{
+			Error: "failed to store two-factor secret",
+		})
+	}
+	return c.JSON(http.StatusOK, SetupTwoFactorResponse{
+		Secret:     secret,
+		OtpauthUrl: utils.TOTPURI(secret, user.Email),
+	})
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code"`
+}
+
+type ConfirmTwoFactorResponse struct {
+	Error         string   `json:"error,omitempty"`
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+func ConfirmTwoFactor(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ConfirmTwoFactorResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	req := new(ConfirmTwoFactorRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, ConfirmTwoFactorResponse{
+			Error: "code is required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ConfirmTwoFactorResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", userId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, ConfirmTwoFactorResponse{
+			Error: "user not found",
+		})
+	}
+	if user.TotpEnabled {
+		return c.JSON(http.StatusConflict, ConfirmTwoFactorResponse{
+			Error: "two-factor authentication is already enabled",
+		})
+	}
+	if user.TotpSecret == "" {
+		return c.JSON(http.StatusBadRequest, ConfirmTwoFactorResponse{
+			Error: "two-factor setup has not been started",
+		})
+	}
+	step, ok := utils.MatchTOTP(user.TotpSecret, req.Code, time.Now())
+	if !ok {
+		return c.JSON(http.StatusBadRequest, ConfirmTwoFactorResponse{
+			Error: "invalid two-factor code",
+		})
+	}
+	var codes []string
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]any{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		codes, err = generateRecoveryCodes(tx, user.Id)
+		return err
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ConfirmTwoFactorResponse{
+			Error: "failed to enable two-factor authentication",
+		})
+	}
+	return c.JSON(http.StatusOK, ConfirmTwoFactorResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+type DisableTwoFactorRequest struct {
+	// Password is required for accounts that have one, see confirmIdentity
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type DisableTwoFactorResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func DisableTwoFactor(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, DisableTwoFactorResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	sessionId, _ := c.Get("sessionId").(string)
+	req := new(DisableTwoFactorRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, DisableTwoFactorResponse{
+			Error: "code is required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, DisableTwoFactorResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", userId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, DisableTwoFactorResponse{
+			Error: "user not found",
+		})
+	}
+	if !user.TotpEnabled {
+		return c.JSON(http.StatusBadRequest, DisableTwoFactorResponse{
+			Error: "two-factor authentication is not enabled",
+		})
+	}
+	if message := confirmIdentity(database, user, sessionId, req.Password, req.Code); message != "" {
+		return c.JSON(http.StatusUnauthorized, DisableTwoFactorResponse{
+			Error: message,
+		})
+	}
+	// Accounts without a password have already given the code, which can only be used once
+	if user.PasswordHash != "" && !checkSecondFactor(database, user, req.Code) {
+		return c.JSON(http.StatusUnauthorized, DisableTwoFactorResponse{
+			Error: "invalid two-factor code",
+		})
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]any{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.Id).Delete(&db.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, DisableTwoFactorResponse{
+			Error: "failed to disable two-factor authentication",
+		})
+	}
+	return c.JSON(http.StatusOK, DisableTwoFactorResponse{})
+}
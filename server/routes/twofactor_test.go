@@ -0,0 +1,194 @@
+package routes
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// currentTOTP is the code an authenticator app shows for the secret right now
+func currentTOTP(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+// wrongTOTP changes the last digit of a code
+func wrongTOTP(code string) string {
+	return code[:5] + string('0'+(code[5]-'0'+1)%10)
+}
+
+// newTwoFactorUser creates a user with 2FA enabled, with a password unless it is empty
+func newTwoFactorUser(t *testing.T, database *gorm.DB, password string) db.User {
+	t.Helper()
+	user := db.User{Id: "user-1", Email: "alice@example.com", IsVerified: true, TotpEnabled: true, TotpSecret: utils.GenerateTOTPSecret()}
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.PasswordHash = string(hash)
+	}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestCheckSecondFactorRejectsReplayedCode(t *testing.T) {
+	database := newTestDB(t)
+	user := newTwoFactorUser(t, database, "")
+	code := currentTOTP(t, user.TotpSecret)
+
+	if !checkSecondFactor(database, user, code) {
+		t.Fatal("the current code was rejected")
+	}
+	if checkSecondFactor(database, user, code) {
+		t.Error("the same code was accepted twice")
+	}
+	if checkSecondFactor(database, user, wrongTOTP(code)) {
+		t.Error("a wrong code was accepted")
+	}
+}
+
+func TestCheckSecondFactorConsumesRecoveryCodes(t *testing.T) {
+	database := newTestDB(t)
+	user := newTwoFactorUser(t, database, "")
+	codes, err := generateRecoveryCodes(database, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	// Codes are accepted without the dash and in upper case, but only once
+	typed := " " + strings.ToUpper(strings.ReplaceAll(codes[0], "-", "")) + " "
+	if !checkSecondFactor(database, user, typed) {
+		t.Fatalf("recovery code %q typed as %q was rejected", codes[0], typed)
+	}
+	if checkSecondFactor(database, user, codes[0]) {
+		t.Error("a used recovery code was accepted")
+	}
+	if !checkSecondFactor(database, user, codes[1]) {
+		t.Error("an unused recovery code was rejected")
+	}
+
+	// Generating new codes replaces the old ones
+	if _, err := generateRecoveryCodes(database, user.Id); err != nil {
+		t.Fatal(err)
+	}
+	if checkSecondFactor(database, user, codes[2]) {
+		t.Error("a replaced recovery code was accepted")
+	}
+}
+
+func TestDisableTwoFactorWithoutPassword(t *testing.T) {
+	database := newTestDB(t)
+	user := newTwoFactorUser(t, database, "")
+	if _, err := generateRecoveryCodes(database, user.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	c, rec := newJSONContext(t, http.MethodPost, "/users/me/2fa/disable", DisableTwoFactorRequest{Code: wrongTOTP(currentTOTP(t, user.TotpSecret))})
+	c.Set("userId", user.Id)
+	if err := DisableTwoFactor(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong code: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	c, rec = newJSONContext(t, http.MethodPost, "/users/me/2fa/disable", DisableTwoFactorRequest{Code: currentTOTP(t, user.TotpSecret)})
+	c.Set("userId", user.Id)
+	if err := DisableTwoFactor(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	database.First(&user, "id = ?", user.Id)
+	var codes int64
+	database.Model(&db.RecoveryCode{}).Where("user_id = ?", user.Id).Count(&codes)
+	if user.TotpEnabled || user.TotpSecret != "" || codes != 0 {
+		t.Errorf("after disabling: user = %+v, %d recovery codes", user, codes)
+	}
+}
+
+func TestDisableTwoFactorWithPassword(t *testing.T) {
+	database := newTestDB(t)
+	user := newTwoFactorUser(t, database, "correct horse")
+	code := currentTOTP(t, user.TotpSecret)
+
+	for _, tt := range []struct {
+		name string
+		req  DisableTwoFactorRequest
+		want int
+	}{
+		{"no code", DisableTwoFactorRequest{Password: "correct horse"}, http.StatusBadRequest},
+		{"wrong password", DisableTwoFactorRequest{Password: "wrong", Code: code}, http.StatusUnauthorized},
+		{"both", DisableTwoFactorRequest{Password: "correct horse", Code: code}, http.StatusOK},
+	} {
+		c, rec := newJSONContext(t, http.MethodPost, "/users/me/2fa/disable", tt.req)
+		c.Set("userId", user.Id)
+		if err := DisableTwoFactor(c); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestSetupTwoFactorConfirmsIdentity(t *testing.T) {
+	database := newTestDB(t)
+	user := db.User{Id: "user-1", Email: "alice@example.com", IsVerified: true}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	session := db.Session{Id: "session-1", UserId: user.Id, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := database.Create(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	setup := func(sessionId string) int {
+		c, rec := newJSONContext(t, http.MethodPost, "/users/me/2fa/setup", SetupTwoFactorRequest{})
+		c.Set("userId", user.Id)
+		c.Set("sessionId", sessionId)
+		if err := SetupTwoFactor(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code
+	}
+
+	// An account without a password must have signed in recently on this session
+	if got := setup(session.Id); got != http.StatusOK {
+		t.Errorf("recent session: status = %d, want %d", got, http.StatusOK)
+	}
+	database.Model(&session).Update("created_at", time.Now().Add(-recentSigninWindow-time.Minute))
+	if got := setup(session.Id); got != http.StatusUnauthorized {
+		t.Errorf("old session: status = %d, want %d", got, http.StatusUnauthorized)
+	}
+	if got := setup("other-session"); got != http.StatusUnauthorized {
+		t.Errorf("unknown session: status = %d, want %d", got, http.StatusUnauthorized)
+	}
+}
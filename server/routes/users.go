@@ -1,14 +1,37 @@
 package routes
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/pages"
+	"github.com/ut-code/Raxcel/server/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	emailChangeTokenLifetime = 24 * time.Hour
+	// recentSigninWindow is how long after signing in an account without a password
+	// or 2FA may make sensitive changes
+	recentSigninWindow = 10 * time.Minute
 )
 
 type GetCurrentUserResponse struct {
-	Error  string `json:"error,omitempty"`
-	UserId string `json:"userId,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	UserId      string    `json:"userId,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	IsVerified  bool      `json:"isVerified"`
+	TotpEnabled bool      `json:"totpEnabled"`
+	Role        string    `json:"role,omitempty"`
+	CreatedAt   time.Time `json:"createdAt,omitzero"`
 }
 
 func GetCurrentUser(c echo.Context) error {
@@ -18,7 +41,341 @@ func GetCurrentUser(c echo.Context) error {
 			Error: "Failed to get userId from context",
 		})
 	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, GetCurrentUserResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", userId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, GetCurrentUserResponse{
+			Error: "user not found",
+		})
+	}
 	return c.JSON(http.StatusOK, GetCurrentUserResponse{
-		UserId: userId,
+		UserId:      user.Id,
+		Email:       user.Email,
+		IsVerified:  user.IsVerified,
+		TotpEnabled: user.TotpEnabled,
+		Role:        user.Role,
+		CreatedAt:   user.CreatedAt,
 	})
 }
+
+// checkPassword re-authenticates the user before a sensitive change. Accounts created
+// through an identity provider have no password until they reset it.
+func checkPassword(user db.User, password string) bool {
+	if user.PasswordHash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
+
+// confirmIdentity re-authenticates the user before a sensitive change, and returns the
+// error to answer with when it fails. Accounts with a password give it. Accounts created
+// through an identity provider have none, so they give a second factor when 2FA is
+// enabled, and must otherwise have signed in on this session in the last few minutes.
+func confirmIdentity(database *gorm.DB, user db.User, sessionId, password, code string) string {
+	if user.PasswordHash != "" {
+		if !checkPassword(user, password) {
+			return "invalid password"
+		}
+		return ""
+	}
+	if user.TotpEnabled {
+		if !checkSecondFactor(database, user, code) {
+			return "invalid two-factor code"
+		}
+		return ""
+	}
+	var count int64
+	database.Model(&db.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND created_at > ?", sessionId, user.Id, time.Now().Add(-recentSigninWindow)).
+		Count(&count)
+	if count == 0 {
+		return "sign in again to confirm it is you"
+	}
+	return ""
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type ChangePasswordResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// ChangePassword replaces the password and signs out every other device
+func ChangePassword(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ChangePasswordResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	sessionId, _ := c.Get("sessionId").(string)
+	req := new(ChangePasswordRequest)
+	if err := c.Bind(req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.JSON(http.StatusBadRequest, ChangePasswordResponse{
+			Error: "current password and new password are required",
+		})
+	}
+	if len(req.NewPassword) < 8 {
+		return c.JSON(http.StatusBadRequest, ChangePasswordResponse{
+			Error: "password must be at least 8 characters",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ChangePasswordResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", userId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, ChangePasswordResponse{
+			Error: "user not found",
+		})
+	}
+	if !checkPassword(user, req.CurrentPassword) {
+		return c.JSON(http.StatusUnauthorized, ChangePasswordResponse{
+			Error: "invalid password",
+		})
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ChangePasswordResponse{
+			Error: "failed to hash password",
+		})
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password_hash", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+		return revokeOtherSessions(tx, user.Id, sessionId)
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ChangePasswordResponse{
+			Error: "failed to change password",
+		})
+	}
+	return c.JSON(http.StatusOK, ChangePasswordResponse{})
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"newEmail"`
+	// Password is required when the account has one
+	Password string `json:"password"`
+	// Code is required when the account has no password and two-factor authentication is enabled
+	Code string `json:"code"`
+}
+
+type ChangeEmailResponse struct {
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ChangeEmail sends a confirmation link to the new address. The email is only
+// changed once the link is opened, so a typo cannot lock the user out.
+func ChangeEmail(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ChangeEmailResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	sessionId, _ := c.Get("sessionId").(string)
+	req := new(ChangeEmailRequest)
+	if err := c.Bind(req); err != nil || req.NewEmail == "" {
+		return c.JSON(http.StatusBadRequest, ChangeEmailResponse{
+			Error: "new email is required",
+		})
+	}
+	newEmail, ok := normalizeEmail(req.NewEmail)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, ChangeEmailResponse{
+			Error: "invalid email address",
+		})
+	}
+	if policyErr := checkEmailDomain(newEmail); policyErr != nil {
+		return c.JSON(http.StatusForbidden, ChangeEmailResponse{
+			Error: policyErr.Message,
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ChangeEmailResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", userId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, ChangeEmailResponse{
+			Error: "user not found",
+		})
+	}
+	if message := confirmIdentity(database, user, sessionId, req.Password, req.Code); message != "" {
+		return c.JSON(http.StatusUnauthorized, ChangeEmailResponse{
+			Error: message,
+		})
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return c.JSON(http.StatusBadRequest, ChangeEmailResponse{
+			Error: "the new email is the same as the current one",
+		})
+	}
+	var count int64
+	whereEmail(database.Model(&db.User{}), newEmail).Count(&count)
+	if count > 0 {
+		return c.JSON(http.StatusConflict, ChangeEmailResponse{
+			Error: "the email is already used",
+		})
+	}
+
+	tokenString := generateSecureToken()
+	err = database.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent request stays valid
+		if err := tx.Where("user_id = ? AND type = ?", user.Id, db.TokenTypeEmailChange).Delete(&db.Token{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&db.Token{
+			Id:        uuid.New().String(),
+			UserId:    user.Id,
+			Token:     utils.HashToken(tokenString),
+			Type:      db.TokenTypeEmailChange,
+			Data:      newEmail,
+			ExpiresAt: time.Now().Add(emailChangeTokenLifetime),
+		}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ChangeEmailResponse{
+			Error: "failed to create email change token",
+		})
+	}
+	if err := sendEmailChangeEmail(c, newEmail, tokenString); err != nil {
+		log.Printf("Failed to send email change email: %v", err)
+		return c.JSON(http.StatusInternalServerError, ChangeEmailResponse{
+			Error: "failed to send confirmation email",
+		})
+	}
+	return c.JSON(http.StatusOK, ChangeEmailResponse{
+		Message: "a confirmation email has been sent to the new address",
+	})
+}
+
+// ConfirmEmailChange is opened in the browser from the link sent to the new address,
+// so it answers with a page
+func ConfirmEmailChange(c echo.Context) error {
+	reqToken := c.QueryParam("token")
+	database, err := db.ConnectDB()
+	if err != nil {
+		return renderPage(c, http.StatusInternalServerError, pages.PageError, nil)
+	}
+	var token db.Token
+	if err := database.Where("token = ? AND type = ?", utils.HashToken(reqToken), db.TokenTypeEmailChange).First(&token).Error; err != nil {
+		return renderPage(c, http.StatusNotFound, pages.PageEmailChangeInvalid, nil)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		database.Delete(&token)
+		return renderPage(c, http.StatusBadRequest, pages.PageEmailChangeInvalid, nil)
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&token)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// Opening the link proves ownership of the new address
+		return tx.Model(&db.User{}).Where("id = ?", token.UserId).Updates(map[string]any{
+			"email":       token.Data,
+			"is_verified": true,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return renderPage(c, http.StatusConflict, pages.PageEmailChangeConflict, nil)
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return renderPage(c, http.StatusNotFound, pages.PageEmailChangeInvalid, nil)
+		}
+		return renderPage(c, http.StatusInternalServerError, pages.PageError, nil)
+	}
+	return renderPage(c, http.StatusOK, pages.PageEmailChanged, map[string]any{
+		"Email": token.Data,
+	})
+}
+
+type DeleteAccountRequest struct {
+	// Password is required when the account has one
+	Password string `json:"password"`
+	// Code is required when two-factor authentication is enabled
+	Code string `json:"code"`
+}
+
+type DeleteAccountResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// DeleteAccount removes the user together with their messages, tokens and sessions
+func DeleteAccount(c echo.Context) error {
+	userId, ok := c.Get("userId").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, DeleteAccountResponse{
+			Error: "Failed to get userId from context",
+		})
+	}
+	sessionId, _ := c.Get("sessionId").(string)
+	req := new(DeleteAccountRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, DeleteAccountResponse{
+			Error: "invalid format",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, DeleteAccountResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	var user db.User
+	if err := database.Where("id = ?", userId).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, DeleteAccountResponse{
+			Error: "user not found",
+		})
+	}
+	if message := confirmIdentity(database, user, sessionId, req.Password, req.Code); message != "" {
+		return c.JSON(http.StatusUnauthorized, DeleteAccountResponse{
+			Error: message,
+		})
+	}
+	// Accounts without a password have already given the code, which can only be used once
+	if user.TotpEnabled && user.PasswordHash != "" && !checkSecondFactor(database, user, req.Code) {
+		return c.JSON(http.StatusUnauthorized, DeleteAccountResponse{
+			Error: "invalid two-factor code",
+		})
+	}
+	// Associations are deleted explicitly as well, in case the database was
+	// created without the ON DELETE CASCADE constraints
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := deleteUserMemberships(tx, user.Id); err != nil {
+			return err
+		}
+		return tx.Select(clause.Associations).Delete(&user).Error
+	})
+	if errors.Is(err, errLastOwner) {
+		return c.JSON(http.StatusConflict, DeleteAccountResponse{
+			Error: "you are the last owner of an organization, make someone else an owner or delete it first",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, DeleteAccountResponse{
+			Error: "failed to delete account",
+		})
+	}
+	return c.JSON(http.StatusOK, DeleteAccountResponse{})
+}
@@ -0,0 +1,110 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"github.com/ut-code/Raxcel/server/utils"
+	"gorm.io/gorm"
+)
+
+func newEmailChangeTest(t *testing.T) *gorm.DB {
+	t.Helper()
+	database := newTestDB(t)
+	for _, user := range []db.User{
+		{Id: "user-1", Email: "alice@example.com", IsVerified: true},
+		{Id: "user-2", Email: "carol@example.com", IsVerified: true},
+	} {
+		if err := database.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database
+}
+
+func createEmailChangeToken(t *testing.T, database *gorm.DB, token, email string, expiresAt time.Time) {
+	t.Helper()
+	err := database.Create(&db.Token{
+		Id:        token,
+		UserId:    "user-1",
+		Token:     utils.HashToken(token),
+		Type:      db.TokenTypeEmailChange,
+		Data:      email,
+		ExpiresAt: expiresAt,
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func confirmEmailChange(t *testing.T, token, language string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/auth/confirm-email-change?token="+token, nil)
+	req.Header.Set("Accept-Language", language)
+	rec := httptest.NewRecorder()
+	if err := ConfirmEmailChange(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	database := newEmailChangeTest(t)
+	createEmailChangeToken(t, database, "change-token", "alice@example.org", time.Now().Add(time.Hour))
+
+	rec := confirmEmailChange(t, "change-token", "en")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Email address changed") {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), echo.MIMETextHTML) {
+		t.Errorf("content type = %q, want a page", rec.Header().Get(echo.HeaderContentType))
+	}
+	var user db.User
+	database.Where("id = ?", "user-1").First(&user)
+	if user.Email != "alice@example.org" {
+		t.Errorf("email = %q, want alice@example.org", user.Email)
+	}
+
+	// The link works once
+	if rec := confirmEmailChange(t, "change-token", "en"); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Invalid link") {
+		t.Errorf("second use: status = %d, body = %s", rec.Code, rec.Body)
+	}
+}
+
+func TestConfirmEmailChangePages(t *testing.T) {
+	database := newEmailChangeTest(t)
+	createEmailChangeToken(t, database, "expired-token", "alice@example.org", time.Now().Add(-time.Minute))
+	createEmailChangeToken(t, database, "taken-token", "carol@example.com", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name     string
+		token    string
+		language string
+		status   int
+		title    string
+	}{
+		{"unknown", "missing", "en", http.StatusNotFound, "Invalid link"},
+		{"expired", "expired-token", "en", http.StatusBadRequest, "Invalid link"},
+		{"taken", "taken-token", "en", http.StatusConflict, "Email address already in use"},
+		{"japanese", "missing", "ja", http.StatusNotFound, "無効なリンクです"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := confirmEmailChange(t, tt.token, tt.language)
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.title) {
+				t.Errorf("status = %d, body = %s", rec.Code, rec.Body)
+			}
+		})
+	}
+
+	var user db.User
+	database.Where("id = ?", "user-1").First(&user)
+	if user.Email != "alice@example.com" {
+		t.Errorf("email = %q, want it unchanged", user.Email)
+	}
+}
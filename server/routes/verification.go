@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/ut-code/Raxcel/server/db"
+	"gorm.io/gorm"
+)
+
+const (
+	verificationTokenLifetime  = 24 * time.Hour
+	verificationResendCooldown = time.Minute
+)
+
+// issueVerificationEmail replaces the user's verification tokens with a new one and emails it
+func issueVerificationEmail(c echo.Context, database *gorm.DB, user db.User) error {
+	tokenString := generateSecureToken()
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND type = ?", user.Id, db.TokenTypeVerification).Delete(&db.Token{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&db.Token{
+			Id:        uuid.New().String(),
+			UserId:    user.Id,
+			Token:     tokenString,
+			Type:      db.TokenTypeVerification,
+			ExpiresAt: time.Now().Add(verificationTokenLifetime),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return sendVerificationEmail(c, user.Email, tokenString)
+}
+
+// canResendVerification reports whether enough time has passed since the last verification email
+func canResendVerification(database *gorm.DB, userId string) bool {
+	var count int64
+	database.Model(&db.Token{}).
+		Where("user_id = ? AND type = ? AND created_at > ?", userId, db.TokenTypeVerification, time.Now().Add(-verificationResendCooldown)).
+		Count(&count)
+	return count == 0
+}
+
+// signupExistingEmail answers a signup for an email that is already registered.
+// An unverified account gets a fresh verification email instead of being stuck forever.
+// Its password is kept: whoever verifies the email would otherwise sign in with a
+// password someone else chose.
+func signupExistingEmail(c echo.Context, database *gorm.DB, email string) error {
+	var user db.User
+	if err := whereEmail(database, email).First(&user).Error; err != nil || user.IsVerified {
+		return c.JSON(http.StatusConflict, SignupResponse{
+			Error: "the email is already used",
+			Code:  SignupCodeEmailTaken,
+		})
+	}
+	// The password of the first signup is kept, so the message says which one to sign in with
+	message := "the email is already registered but not verified, and a verification email was sent less than a minute ago"
+	if canResendVerification(database, user.Id) {
+		if err := issueVerificationEmail(c, database, user); err != nil {
+			log.Printf("Failed to resend verification email: %v", err)
+			message = "the email is already registered but not verified, and no verification email could be sent, try again later"
+		} else {
+			message = "the email is already registered but not verified, a new verification email has been sent"
+		}
+	}
+	return c.JSON(http.StatusConflict, SignupResponse{
+		Error: message + ". Sign in with the password you first signed up with",
+		Code:  SignupCodeEmailTaken,
+	})
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+type ResendVerificationResponse struct {
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func ResendVerification(c echo.Context) error {
+	req := new(ResendVerificationRequest)
+	if err := c.Bind(req); err != nil || req.Email == "" {
+		return c.JSON(http.StatusBadRequest, ResendVerificationResponse{
+			Error: "email is required",
+		})
+	}
+	database, err := db.ConnectDB()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ResendVerificationResponse{
+			Error: "failed to connect to database",
+		})
+	}
+	// Unknown, already verified and rate limited emails all get the same
+	// answer, so this endpoint cannot be used to find registered emails
+	sent := ResendVerificationResponse{
+		Message: "if the email is registered and not yet verified, a verification email has been sent",
+	}
+	var user db.User
+	if err := whereEmail(database, req.Email).First(&user).Error; err != nil {
+		return c.JSON(http.StatusOK, sent)
+	}
+	if user.IsVerified || !canResendVerification(database, user.Id) {
+		return c.JSON(http.StatusOK, sent)
+	}
+	if err := issueVerificationEmail(c, database, user); err != nil {
+		log.Printf("Failed to resend verification email: %v", err)
+	}
+	return c.JSON(http.StatusOK, sent)
+}
@@ -6,6 +6,7 @@ import (
 )
 
 type AuthMiddlewareReturn = middleware.AuthMiddlewareReturn
+type RateLimitReturn = middleware.RateLimitReturn
 
 // Auth requests and responses
 type SignupRequest = routes.SignupRequest
@@ -14,6 +15,27 @@ type SignupResponse = routes.SignupResponse
 type SigninRequest = routes.SigninRequest
 type SigninResponse = routes.SigninResponse
 
+type VerifyTwoFactorRequest = routes.VerifyTwoFactorRequest
+
+type StartOIDCRequest = routes.StartOIDCRequest
+type StartOIDCResponse = routes.StartOIDCResponse
+type OIDCCallbackRequest = routes.OIDCCallbackRequest
+type RequestMagicLinkRequest = routes.RequestMagicLinkRequest
+type RequestMagicLinkResponse = routes.RequestMagicLinkResponse
+type CompleteMagicLinkRequest = routes.CompleteMagicLinkRequest
+
+type RefreshRequest = routes.RefreshRequest
+type RefreshResponse = routes.RefreshResponse
+
+type ResendVerificationRequest = routes.ResendVerificationRequest
+type ResendVerificationResponse = routes.ResendVerificationResponse
+
+type ForgotPasswordRequest = routes.ForgotPasswordRequest
+type ForgotPasswordResponse = routes.ForgotPasswordResponse
+
+type ResetPasswordRequest = routes.ResetPasswordRequest
+type ResetPasswordResponse = routes.ResetPasswordResponse
+
 // Message requests and responses
 type ChatWithAIRequest = routes.ChatWithAIRequest
 
@@ -27,8 +49,195 @@ type LoadChatHistoryResponse struct {
 	*AuthMiddlewareReturn
 }
 
+// The spreadsheet sent with a message
+type SpreadsheetContext = routes.SpreadsheetContext
+type SpreadsheetCell = routes.SpreadsheetCell
+
+// Spreadsheet edits proposed by the model
+type SpreadsheetEdit = routes.SpreadsheetEdit
+
+const (
+	EditSetValue    = routes.EditSetValue
+	EditSetFormula  = routes.EditSetFormula
+	EditInsertRows  = routes.EditInsertRows
+	EditCreateChart = routes.EditCreateChart
+)
+
+// Conversation requests and responses
+type CreateConversationRequest = routes.CreateConversationRequest
+type UpdateConversationRequest = routes.UpdateConversationRequest
+
+type ConversationResponse struct {
+	routes.ConversationResponse
+	*AuthMiddlewareReturn
+}
+
+type ListConversationsResponse struct {
+	routes.ListConversationsResponse
+	*AuthMiddlewareReturn
+}
+
+type DeleteConversationResponse struct {
+	routes.DeleteConversationResponse
+	*AuthMiddlewareReturn
+}
+
+// Events of the streaming chat endpoint
+const (
+	ChatEventChunk = routes.ChatEventChunk
+	ChatEventDone  = routes.ChatEventDone
+	ChatEventError = routes.ChatEventError
+)
+
+const ConversationIdHeader = routes.ConversationIdHeader
+
+type ChatChunkEvent = routes.ChatChunkEvent
+type ChatDoneEvent = routes.ChatDoneEvent
+type ChatErrorEvent = routes.ChatErrorEvent
+
 // User responses
 type GetCurrentUserResponse struct {
 	routes.GetCurrentUserResponse
 	*AuthMiddlewareReturn
 }
+
+type ChangePasswordRequest = routes.ChangePasswordRequest
+
+type ChangePasswordResponse struct {
+	routes.ChangePasswordResponse
+	*AuthMiddlewareReturn
+}
+
+type ChangeEmailRequest = routes.ChangeEmailRequest
+
+type ChangeEmailResponse struct {
+	routes.ChangeEmailResponse
+	*AuthMiddlewareReturn
+}
+
+type DeleteAccountRequest = routes.DeleteAccountRequest
+
+type DeleteAccountResponse struct {
+	routes.DeleteAccountResponse
+	*AuthMiddlewareReturn
+}
+
+type SetupTwoFactorRequest = routes.SetupTwoFactorRequest
+
+type SetupTwoFactorResponse struct {
+	routes.SetupTwoFactorResponse
+	*AuthMiddlewareReturn
+}
+
+type ConfirmTwoFactorRequest = routes.ConfirmTwoFactorRequest
+
+type ConfirmTwoFactorResponse struct {
+	routes.ConfirmTwoFactorResponse
+	*AuthMiddlewareReturn
+}
+
+type DisableTwoFactorRequest = routes.DisableTwoFactorRequest
+
+type DisableTwoFactorResponse struct {
+	routes.DisableTwoFactorResponse
+	*AuthMiddlewareReturn
+}
+
+type SessionInfo = routes.SessionInfo
+
+type ListSessionsResponse struct {
+	routes.ListSessionsResponse
+	*AuthMiddlewareReturn
+}
+
+type RevokeSessionResponse struct {
+	routes.RevokeSessionResponse
+	*AuthMiddlewareReturn
+}
+
+type CreatePersonalAccessTokenRequest = routes.CreatePersonalAccessTokenRequest
+
+type CreatePersonalAccessTokenResponse struct {
+	routes.CreatePersonalAccessTokenResponse
+	*AuthMiddlewareReturn
+}
+
+type ListPersonalAccessTokensResponse struct {
+	routes.ListPersonalAccessTokensResponse
+	*AuthMiddlewareReturn
+}
+
+type RevokePersonalAccessTokenResponse struct {
+	routes.RevokePersonalAccessTokenResponse
+	*AuthMiddlewareReturn
+}
+
+// Organization requests and responses
+type OrganizationSummary = routes.OrganizationSummary
+type OrganizationMember = routes.OrganizationMember
+
+type CreateOrganizationRequest = routes.CreateOrganizationRequest
+type UpdateOrganizationRequest = routes.UpdateOrganizationRequest
+type UpdateMemberRequest = routes.UpdateMemberRequest
+type CreateInvitationRequest = routes.CreateInvitationRequest
+type RespondToInvitationRequest = routes.RespondToInvitationRequest
+
+type OrganizationResponse struct {
+	routes.OrganizationResponse
+	*AuthMiddlewareReturn
+}
+
+type ListOrganizationsResponse struct {
+	routes.ListOrganizationsResponse
+	*AuthMiddlewareReturn
+}
+
+type OrganizationActionResponse struct {
+	routes.OrganizationActionResponse
+	*AuthMiddlewareReturn
+}
+
+type OrganizationUsageResponse struct {
+	routes.OrganizationUsageResponse
+	*AuthMiddlewareReturn
+}
+
+type InvitationResponse struct {
+	routes.InvitationResponse
+	*AuthMiddlewareReturn
+}
+
+type ListInvitationsResponse struct {
+	routes.ListInvitationsResponse
+	*AuthMiddlewareReturn
+}
+
+type AcceptInvitationResponse struct {
+	routes.AcceptInvitationResponse
+	*AuthMiddlewareReturn
+}
+
+// Admin responses
+type AdminUser = routes.AdminUser
+type UserUsage = routes.UserUsage
+type AdminSetRoleRequest = routes.AdminSetRoleRequest
+
+type AdminListUsersResponse struct {
+	routes.AdminListUsersResponse
+	*AuthMiddlewareReturn
+}
+
+type AdminUserResponse struct {
+	routes.AdminUserResponse
+	*AuthMiddlewareReturn
+}
+
+type AdminDeleteUserResponse struct {
+	routes.AdminDeleteUserResponse
+	*AuthMiddlewareReturn
+}
+
+type AdminUserUsageResponse struct {
+	routes.AdminUserUsageResponse
+	*AuthMiddlewareReturn
+}
@@ -1,23 +1,85 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
-func ValidateJWT(tokenString string) (string, error) {
-	secretKey := os.Getenv("SECRET_KEY")
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		//TODO: check the algorithm
-		return []byte(secretKey), nil
+const AccessTokenLifetime = 15 * time.Minute
+
+const (
+	// TokenIssuer and TokenAudience are the iss and aud claims of access tokens
+	TokenIssuer   = "raxcel"
+	TokenAudience = "raxcel-api"
+)
+
+// GenerateJWT signs an access token for the session with the current signing key.
+// The user id is the sub claim and the session id is the jti claim.
+func GenerateJWT(userId, sessionId string) (string, time.Time, error) {
+	keys, err := LoadSigningKeys()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	key := keys[0]
+	now := time.Now()
+	expiresAt := now.Add(AccessTokenLifetime)
+	token := jwt.NewWithClaims(key.Method, jwt.StandardClaims{
+		Subject:   userId,
+		Issuer:    TokenIssuer,
+		Audience:  TokenAudience,
+		Id:        sessionId,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	token.Header["kid"] = key.Id
+	signedToken, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signedToken, expiresAt, nil
+}
+
+// ValidateJWT returns the user id and session id of a valid access token. The key is
+// chosen by the kid header and the token must use that key's algorithm.
+func ValidateJWT(tokenString string) (string, string, error) {
+	keys, err := LoadSigningKeys()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	parser := &jwt.Parser{
+		ValidMethods: []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodEdDSA.Alg()},
+	}
+	token, err := parser.ParseWithClaims(tokenString, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		for _, key := range keys {
+			if key.Id != kid {
+				continue
+			}
+			if token.Method.Alg() != key.Method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+			}
+			return key.verifyKey, nil
+		}
+		return nil, fmt.Errorf("unknown key %q", kid)
 	})
 	if err != nil {
-		return "", fmt.Errorf("unauthenticated")
+		return "", "", fmt.Errorf("unauthenticated")
 	}
 
 	claims := token.Claims.(*jwt.StandardClaims)
-	id := claims.Issuer
-	return id, nil
+	if !claims.VerifyIssuer(TokenIssuer, true) || !claims.VerifyAudience(TokenAudience, true) ||
+		claims.Subject == "" || claims.Id == "" {
+		return "", "", fmt.Errorf("unauthenticated")
+	}
+	return claims.Subject, claims.Id, nil
+}
+
+// HashToken returns the hex-encoded SHA-256 digest used to store opaque tokens
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
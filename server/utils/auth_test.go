@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// signTestToken signs valid claims for user-1 and session-1 with any method and key
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.StandardClaims{
+		Subject:   "user-1",
+		Issuer:    TokenIssuer,
+		Audience:  TokenAudience,
+		Id:        "session-1",
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: time.Now().Add(time.Minute).Unix(),
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestGenerateAndValidateJWT(t *testing.T) {
+	for _, config := range []string{testEd25519Key, testHMACKey} {
+		t.Setenv("JWT_KEYS", config)
+		token, expiresAt, err := GenerateJWT("user-1", "session-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Until(expiresAt) > AccessTokenLifetime {
+			t.Errorf("expires at %v, after the lifetime", expiresAt)
+		}
+		userId, sessionId, err := ValidateJWT(token)
+		if err != nil || userId != "user-1" || sessionId != "session-1" {
+			t.Errorf("JWT_KEYS=%s: ValidateJWT = %q, %q, %v", config, userId, sessionId, err)
+		}
+	}
+}
+
+func TestValidateJWTAcceptsRotatedKey(t *testing.T) {
+	// Tokens signed before the rotation verify while the old key stays in the set
+	t.Setenv("JWT_KEYS", testHMACKey)
+	token, _, err := GenerateJWT("user-1", "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JWT_KEYS", testEd25519Key+","+testHMACKey)
+	if _, _, err := ValidateJWT(token); err != nil {
+		t.Errorf("token of the rotated-out key: %v", err)
+	}
+	newToken, _, err := GenerateJWT("user-1", "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed, _ := jwt.Parse(newToken, nil); parsed == nil || parsed.Header["kid"] != "ed-1" {
+		t.Error("new tokens are not signed with the first key")
+	}
+
+	// Once the old key is removed, its tokens stop working
+	t.Setenv("JWT_KEYS", testEd25519Key)
+	if _, _, err := ValidateJWT(token); err == nil {
+		t.Error("token of a removed key was accepted")
+	}
+}
+
+func TestValidateJWTRejects(t *testing.T) {
+	t.Setenv("JWT_KEYS", testEd25519Key+","+testHMACKey)
+	private := ed25519.NewKeyFromSeed(testEd25519Seed)
+	public := private.Public().(ed25519.PublicKey)
+	otherPrivate := ed25519.NewKeyFromSeed([]byte("another seed of thirty-two bytes"))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"alg none", signTestToken(t, jwt.SigningMethodNone, "ed-1", jwt.UnsafeAllowNoneSignatureType)},
+		// The public key is known to anyone, so it must not be accepted as an HMAC secret
+		{"HS256 with the public key of an EdDSA kid", signTestToken(t, jwt.SigningMethodHS256, "ed-1", []byte(public))},
+		{"EdDSA with an HS256 kid", signTestToken(t, jwt.SigningMethodEdDSA, "hs-1", private)},
+		{"unknown kid", signTestToken(t, jwt.SigningMethodHS256, "hs-2", testHMACSecret)},
+		{"no kid", signTestToken(t, jwt.SigningMethodHS256, "", testHMACSecret)},
+		{"wrong key", signTestToken(t, jwt.SigningMethodEdDSA, "ed-1", otherPrivate)},
+		{"HS384", signTestToken(t, jwt.SigningMethodHS384, "hs-1", testHMACSecret)},
+		{"garbage", "not.a.token"},
+	}
+	for _, tt := range tests {
+		if _, _, err := ValidateJWT(tt.token); err == nil {
+			t.Errorf("%s: token was accepted", tt.name)
+		}
+	}
+
+	// The same claims signed properly are accepted, so the cases above fail for their signature
+	if _, _, err := ValidateJWT(signTestToken(t, jwt.SigningMethodEdDSA, "ed-1", private)); err != nil {
+		t.Errorf("valid token: %v", err)
+	}
+}
+
+func TestValidateJWTChecksClaims(t *testing.T) {
+	t.Setenv("JWT_KEYS", testHMACKey)
+	tests := []struct {
+		name   string
+		claims jwt.StandardClaims
+	}{
+		{"expired", jwt.StandardClaims{Subject: "user-1", Id: "session-1", Issuer: TokenIssuer, Audience: TokenAudience, ExpiresAt: time.Now().Add(-time.Minute).Unix()}},
+		{"other issuer", jwt.StandardClaims{Subject: "user-1", Id: "session-1", Issuer: "other", Audience: TokenAudience}},
+		{"other audience", jwt.StandardClaims{Subject: "user-1", Id: "session-1", Issuer: TokenIssuer, Audience: "other"}},
+		{"no subject", jwt.StandardClaims{Id: "session-1", Issuer: TokenIssuer, Audience: TokenAudience}},
+		{"no session", jwt.StandardClaims{Subject: "user-1", Issuer: TokenIssuer, Audience: TokenAudience}},
+	}
+	for _, tt := range tests {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, tt.claims)
+		token.Header["kid"] = "hs-1"
+		signed, err := token.SignedString(testHMACSecret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ValidateJWT(signed); err == nil {
+			t.Errorf("%s: token was accepted", tt.name)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// legacyKeyId identifies the HS256 key derived from SECRET_KEY when JWT_KEYS is not set
+const legacyKeyId = "default"
+
+// minHMACKeySize is the smallest HS256 secret accepted in JWT_KEYS (RFC 7518, section 3.2)
+const minHMACKeySize = 32
+
+// SigningKey is one entry of the key set. The algorithm belongs to the key, so a
+// token is only ever verified with the algorithm its key was configured with.
+type SigningKey struct {
+	Id         string
+	Method     jwt.SigningMethod
+	signKey    any
+	verifyKey  any
+	ed25519Pub ed25519.PublicKey
+}
+
+// LoadSigningKeys reads JWT_KEYS, a comma separated list of kid:alg:key entries where
+// alg is HS256 or EdDSA and key is the base64 encoded HMAC secret or Ed25519 seed.
+// The first key signs new tokens and the rest only verify, so a key is rotated by
+// prepending the new one and removing the old one after the access token lifetime.
+// Without JWT_KEYS, SECRET_KEY is used as a single HS256 key.
+func LoadSigningKeys() ([]SigningKey, error) {
+	config := strings.TrimSpace(os.Getenv("JWT_KEYS"))
+	if config == "" {
+		secretKey := os.Getenv("SECRET_KEY")
+		if secretKey == "" {
+			return nil, errors.New("neither JWT_KEYS nor SECRET_KEY is set")
+		}
+		return []SigningKey{{
+			Id:        legacyKeyId,
+			Method:    jwt.SigningMethodHS256,
+			signKey:   []byte(secretKey),
+			verifyKey: []byte(secretKey),
+		}}, nil
+	}
+
+	var keys []SigningKey
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(config, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("JWT_KEYS entry %q is not kid:alg:key", entry)
+		}
+		kid, alg := parts[0], parts[1]
+		if seen[kid] {
+			return nil, fmt.Errorf("JWT_KEYS has duplicate kid %q", kid)
+		}
+		seen[kid] = true
+		material, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("JWT_KEYS key %q is not valid base64: %w", kid, err)
+		}
+		switch alg {
+		case jwt.SigningMethodHS256.Alg():
+			if len(material) < minHMACKeySize {
+				return nil, fmt.Errorf("JWT_KEYS key %q must be at least %d bytes", kid, minHMACKeySize)
+			}
+			keys = append(keys, SigningKey{
+				Id:        kid,
+				Method:    jwt.SigningMethodHS256,
+				signKey:   material,
+				verifyKey: material,
+			})
+		case jwt.SigningMethodEdDSA.Alg():
+			if len(material) != ed25519.SeedSize {
+				return nil, fmt.Errorf("JWT_KEYS key %q must be a %d byte Ed25519 seed", kid, ed25519.SeedSize)
+			}
+			private := ed25519.NewKeyFromSeed(material)
+			public := private.Public().(ed25519.PublicKey)
+			keys = append(keys, SigningKey{
+				Id:         kid,
+				Method:     jwt.SigningMethodEdDSA,
+				signKey:    private,
+				verifyKey:  public,
+				ed25519Pub: public,
+			})
+		default:
+			return nil, fmt.Errorf("JWT_KEYS key %q has unsupported algorithm %q", kid, alg)
+		}
+	}
+	return keys, nil
+}
+
+// JWK is a public key in the JSON Web Key format (RFC 8037 for Ed25519)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// PublicJWKs returns the asymmetric keys of the key set. HMAC secrets are never published.
+func PublicJWKs() ([]JWK, error) {
+	keys, err := LoadSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+	jwks := []JWK{}
+	for _, key := range keys {
+		if key.ed25519Pub == nil {
+			continue
+		}
+		jwks = append(jwks, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key.ed25519Pub),
+			KeyId:     key.Id,
+			Algorithm: key.Method.Alg(),
+			Use:       "sig",
+		})
+	}
+	return jwks, nil
+}
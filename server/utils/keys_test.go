@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// Keys of the tests, as they appear in JWT_KEYS
+var (
+	testHMACSecret  = []byte("0123456789abcdef0123456789abcdef")
+	testEd25519Seed = []byte("abcdefghijklmnopqrstuvwxyz012345")
+	testHMACKey     = "hs-1:HS256:" + base64.StdEncoding.EncodeToString(testHMACSecret)
+	testEd25519Key  = "ed-1:EdDSA:" + base64.StdEncoding.EncodeToString(testEd25519Seed)
+)
+
+func TestLoadSigningKeys(t *testing.T) {
+	t.Setenv("JWT_KEYS", testEd25519Key+", "+testHMACKey)
+	keys, err := LoadSigningKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].Id != "ed-1" || keys[0].Method.Alg() != "EdDSA" || keys[1].Id != "hs-1" || keys[1].Method.Alg() != "HS256" {
+		t.Errorf("keys = %+v", keys)
+	}
+}
+
+func TestLoadSigningKeysLegacySecret(t *testing.T) {
+	t.Setenv("JWT_KEYS", "")
+	t.Setenv("SECRET_KEY", "legacy")
+	keys, err := LoadSigningKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Id != legacyKeyId || keys[0].Method.Alg() != "HS256" {
+		t.Errorf("keys = %+v", keys)
+	}
+
+	t.Setenv("SECRET_KEY", "")
+	if _, err := LoadSigningKeys(); err == nil {
+		t.Error("LoadSigningKeys succeeded without any key")
+	}
+}
+
+func TestLoadSigningKeysErrors(t *testing.T) {
+	short := base64.StdEncoding.EncodeToString([]byte("short"))
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{"hs-1:HS256", "is not kid:alg:key"},
+		{":HS256:" + short, "is not kid:alg:key"},
+		{testHMACKey + "," + testHMACKey, "duplicate kid"},
+		{"hs-1:HS256:not base64!", "not valid base64"},
+		{"hs-1:HS256:" + short, "at least 32 bytes"},
+		{"ed-1:EdDSA:" + short, "32 byte Ed25519 seed"},
+		{"rs-1:RS256:" + short, "unsupported algorithm"},
+		{"none-1:none:" + short, "unsupported algorithm"},
+	}
+	for _, tt := range tests {
+		t.Setenv("JWT_KEYS", tt.config)
+		if _, err := LoadSigningKeys(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("JWT_KEYS=%q: err = %v, want %q", tt.config, err, tt.want)
+		}
+	}
+}
+
+func TestPublicJWKs(t *testing.T) {
+	t.Setenv("JWT_KEYS", testHMACKey+","+testEd25519Key)
+	jwks, err := PublicJWKs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The HMAC secret is never published
+	if len(jwks) != 1 {
+		t.Fatalf("jwks = %+v, want only the Ed25519 key", jwks)
+	}
+	public := ed25519.NewKeyFromSeed(testEd25519Seed).Public().(ed25519.PublicKey)
+	want := JWK{KeyType: "OKP", Curve: "Ed25519", X: base64.RawURLEncoding.EncodeToString(public), KeyId: "ed-1", Algorithm: "EdDSA", Use: "sig"}
+	if jwks[0] != want {
+		t.Errorf("jwk = %+v, want %+v", jwks[0], want)
+	}
+}
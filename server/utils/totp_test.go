@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the test vectors in RFC 6238 Appendix B
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestMatchTOTPRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes, of which 6 digit codes are the last 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		step, ok := MatchTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("MatchTOTP(%s) at %d = %d, %v, want %d, true", tt.code, tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestMatchTOTPDriftWindow(t *testing.T) {
+	secret := GenerateTOTPSecret()
+	key, _ := totpEncoding.DecodeString(secret)
+	now := time.Unix(1700000000, 0)
+	current := now.Unix() / totpPeriod
+
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		step, ok := MatchTOTP(secret, totpCode(key, current+offset), now)
+		if !ok || step != current+offset {
+			t.Errorf("code %+d steps away = %d, %v, want it accepted", offset, step, ok)
+		}
+	}
+	for _, offset := range []int64{-totpSkew - 1, totpSkew + 1} {
+		if _, ok := MatchTOTP(secret, totpCode(key, current+offset), now); ok {
+			t.Errorf("code %+d steps away was accepted", offset)
+		}
+	}
+}
+
+func TestMatchTOTPInput(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfc6238Secret)
+	now := time.Unix(59, 0)
+	code := totpCode(key, 1)
+	// Secrets are case insensitive and codes may be pasted with spaces around them
+	if _, ok := MatchTOTP(strings.ToLower(rfc6238Secret), " "+code+"\n", now); !ok {
+		t.Error("a code with surrounding spaces was rejected")
+	}
+	for _, tt := range []struct{ secret, code string }{
+		{rfc6238Secret, ""},
+		{rfc6238Secret, "12345"},
+		{rfc6238Secret, "not a code"},
+		{"not base32!", code},
+	} {
+		if _, ok := MatchTOTP(tt.secret, tt.code, now); ok {
+			t.Errorf("MatchTOTP(%q, %q) accepted", tt.secret, tt.code)
+		}
+	}
+}